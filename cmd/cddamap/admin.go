@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ralreegorganon/cddamap/internal/server"
+)
+
+type tokenCommand struct {
+	Name   string `long:"name" required:"true" description:"Who or what the token is for"`
+	Admin  bool   `long:"admin" description:"Allow the token to see every world"`
+	Worlds []int  `long:"world" description:"World the token may see, repeat for more"`
+}
+
+type visibilityCommand struct {
+	WorldID int `long:"world" required:"true" description:"World to change"`
+	Args    struct {
+		Visibility string `positional-arg-name:"public|unlisted|private"`
+	} `positional-args:"true" required:"true"`
+}
+
+func init() {
+	parser.AddCommand("token", "Create an API token", "Creates an API token and prints its secret, which is not stored and can't be shown again.", &tokenCommand{})
+	parser.AddCommand("visibility", "Set a world's visibility", "Makes a world public, unlisted or private.", &visibilityCommand{})
+}
+
+func openDB() (*server.DB, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var db server.DB
+	if err := db.Open(cfg.Database.ConnectionString); err != nil {
+		return nil, err
+	}
+	return &db, nil
+}
+
+func (c *tokenCommand) Execute(args []string) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	token, err := server.NewToken()
+	if err != nil {
+		return err
+	}
+
+	id, err := db.CreateToken(c.Name, c.Admin, server.HashToken(token))
+	if err != nil {
+		return err
+	}
+
+	for _, worldID := range c.Worlds {
+		if err := db.GrantWorldAccess(worldID, id); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(token)
+	return nil
+}
+
+func (c *visibilityCommand) Execute(args []string) error {
+	visibility := strings.ToLower(c.Args.Visibility)
+	switch visibility {
+	case server.VisibilityPublic, server.VisibilityUnlisted, server.VisibilityPrivate:
+	default:
+		return fmt.Errorf("visibility: expected public, unlisted or private, got %q", c.Args.Visibility)
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.SetWorldVisibility(c.WorldID, visibility)
+}
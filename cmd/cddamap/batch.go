@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+	log "github.com/sirupsen/logrus"
+)
+
+// discoverSaves returns every world folder under the game's save folder,
+// recognised by the mods.json the game writes into each.
+func discoverSaves(gameRoot string) ([]string, error) {
+	root := filepath.Join(gameRoot, "save")
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	saves := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "mods.json")); err == nil {
+			saves = append(saves, dir)
+		}
+	}
+	return saves, nil
+}
+
+// metadataCache builds metadata once per distinct set of mods, however many
+// saves share it, since building it means reading all of the game's data.
+type metadataCache struct {
+	gameRoot string
+	mu       sync.Mutex
+	builds   map[string]*metadataBuild
+}
+
+type metadataBuild struct {
+	once sync.Once
+	m    metadata.Overmap
+	err  error
+}
+
+func (mc *metadataCache) get(s save.Save) (metadata.Overmap, error) {
+	mods := append([]string{}, s.Mods...)
+	sort.Strings(mods)
+	key := strings.Join(mods, "\n")
+
+	mc.mu.Lock()
+	b, ok := mc.builds[key]
+	if !ok {
+		b = &metadataBuild{}
+		mc.builds[key] = b
+	}
+	mc.mu.Unlock()
+
+	b.once.Do(func() {
+		log.WithField("mods", s.Mods).Info("building metadata")
+		b.m, b.err = metadata.Build(s, mc.gameRoot)
+	})
+	return b.m, b.err
+}
+
+type batchResult struct {
+	name    string
+	result  genResult
+	elapsed time.Duration
+	err     error
+}
+
+// batch runs gen over every save in the game's save folder, --jobs at a
+// time, then prints a summary of what it did for each.
+func (c *genCommand) batch(connectionString string) error {
+	saves, err := discoverSaves(c.GameRoot)
+	if err != nil {
+		return err
+	}
+	if len(saves) == 0 {
+		return fmt.Errorf("gen: no saves found in %v", filepath.Join(c.GameRoot, "save"))
+	}
+
+	jobs := c.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	log.WithField("saves", len(saves)).WithField("jobs", jobs).Info("processing every save")
+
+	mc := &metadataCache{gameRoot: c.GameRoot, builds: make(map[string]*metadataBuild)}
+	results := make([]batchResult, len(saves))
+	work := make(chan int)
+
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				sc := *c
+				sc.Save = saves[i]
+				if c.OutputDir != "" {
+					sc.OutputDir = filepath.Join(c.OutputDir, filepath.Base(saves[i]))
+				}
+
+				start := time.Now()
+				result, err := sc.run(connectionString, mc.get)
+				results[i] = batchResult{name: filepath.Base(saves[i]), result: result, elapsed: time.Since(start), err: err}
+				if err != nil {
+					log.WithField("save", saves[i]).WithField("err", err).Error("save failed")
+				}
+			}
+		}()
+	}
+	for i := range saves {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	failed := printBatchSummary(results)
+	if failed > 0 {
+		return fmt.Errorf("gen: %v of %v saves failed", failed, len(saves))
+	}
+	return nil
+}
+
+func printBatchSummary(results []batchResult) int {
+	failed := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SAVE\tRESULT\tCHUNKS\tCHANGED\tTIME")
+	for _, r := range results {
+		status := "updated"
+		switch {
+		case r.err != nil:
+			status = "failed: " + r.err.Error()
+			failed++
+		case r.result.Full:
+			status = "generated"
+		case r.result.Changed == 0:
+			status = "unchanged"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", r.name, status, r.result.Chunks, r.result.Changed, r.elapsed.Round(time.Millisecond))
+	}
+	fmt.Fprintf(tw, "\n%v saves, %v failed\n", len(results), failed)
+	tw.Flush()
+	return failed
+}
@@ -16,13 +16,13 @@ var gm metadata.Overmap
 func BenchmarkSaveBuild(b *testing.B) {
 	var s save.Save
 	for n := 0; n < b.N; n++ {
-		s, _ = save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard")
+		s, _ = save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard", "")
 	}
 	gs = s
 }
 
 func BenchmarkMetadatadBuild(b *testing.B) {
-	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard")
+	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard", "")
 	b.ResetTimer()
 
 	var m metadata.Overmap
@@ -33,61 +33,61 @@ func BenchmarkMetadatadBuild(b *testing.B) {
 }
 
 func BenchmarkWorldBuild(b *testing.B) {
-	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard")
+	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard", "")
 	m, _ := metadata.Build(s, "/Users/jj/code/Cataclysm-DDA")
 	b.ResetTimer()
 
 	var w world.World
 	for n := 0; n < b.N; n++ {
-		w, _ = world.Build(m, s)
+		w, _ = world.Build(m, s, false)
 	}
 	gw = w
 }
 
 func BenchmarkRenderTerrainToImages(b *testing.B) {
-	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard")
+	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard", "")
 	m, _ := metadata.Build(s, "/Users/jj/code/Cataclysm-DDA")
-	w, _ := world.Build(m, s)
+	w, _ := world.Build(m, s, false)
 	l := []int{10}
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		render.Image(w, "/Users/jj/Desktop/GoTest", l, true, false, false, true)
+		render.Image(w, render.Options{OutputRoot: "/Users/jj/Desktop/GoTest", Layers: l, Terrain: true, SkipEmpty: true})
 	}
 }
 
 func BenchmarkRenderSeenToImages(b *testing.B) {
-	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard")
+	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard", "")
 	m, _ := metadata.Build(s, "/Users/jj/code/Cataclysm-DDA")
-	w, _ := world.Build(m, s)
+	w, _ := world.Build(m, s, false)
 	l := []int{10}
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		render.Image(w, "/Users/jj/Desktop/GoTest", l, false, true, false, true)
+		render.Image(w, render.Options{OutputRoot: "/Users/jj/Desktop/GoTest", Layers: l, Seen: true, SkipEmpty: true})
 	}
 }
 
 func BenchmarkRenderSeenSolidToImages(b *testing.B) {
-	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard")
+	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard", "")
 	m, _ := metadata.Build(s, "/Users/jj/code/Cataclysm-DDA")
-	w, _ := world.Build(m, s)
+	w, _ := world.Build(m, s, false)
 	l := []int{10}
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		render.Image(w, "/Users/jj/Desktop/GoTest", l, false, false, true, true)
+		render.Image(w, render.Options{OutputRoot: "/Users/jj/Desktop/GoTest", Layers: l, SeenSolid: true, SkipEmpty: true})
 	}
 }
 
 func BenchmarkRenderAllToImages(b *testing.B) {
-	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard")
+	s, _ := save.Build("/Users/jj/code/Cataclysm-DDA/save/Spenard", "")
 	m, _ := metadata.Build(s, "/Users/jj/code/Cataclysm-DDA")
-	w, _ := world.Build(m, s)
+	w, _ := world.Build(m, s, false)
 	l := []int{10}
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		render.Image(w, "/Users/jj/Desktop/GoTest", l, true, true, true, true)
+		render.Image(w, render.Options{OutputRoot: "/Users/jj/Desktop/GoTest", Layers: l, Terrain: true, Seen: true, SeenSolid: true, SkipEmpty: true})
 	}
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/render"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	log "github.com/sirupsen/logrus"
+)
+
+// cacheFile is where gen remembers the chunks its output was made from.
+const cacheFile = ".cddamap-cache"
+
+func (c *genCommand) cacheFile() string {
+	return filepath.Join(c.OutputDir, cacheFile)
+}
+
+// loadCache returns the chunk cache from the output folder, or nil when
+// there's no output folder to keep it in. With --force, or if the cache
+// can't be read, it starts over with an empty one.
+func (c *genCommand) loadCache() *save.Cache {
+	if c.OutputDir == "" {
+		return nil
+	}
+
+	if !c.Force {
+		cache, err := save.LoadCache(c.cacheFile())
+		if err == nil {
+			return cache
+		}
+		log.WithField("err", err).Warn("ignoring unreadable chunk cache")
+	}
+	return save.NewCache()
+}
+
+// cacheKey identifies everything besides the chunks themselves that the
+// output depends on. When it changes, everything is generated again.
+func (c *genCommand) cacheKey(m metadata.Overmap, e world.Extent, annotations []render.Annotation, connectionString string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%v\n", m.Hash())
+	fmt.Fprintf(h, "%+v\n", e)
+	fmt.Fprintf(h, "%v %v %v %v %v %v %v %q\n", c.Layers, c.Terrain, c.Seen, c.SeenSolid, c.Cities, c.SkipEmpty, c.LandUseCode, c.Overmap)
+	fmt.Fprintf(h, "%v %v %v %v %v %q\n", c.Text, c.Images, c.Tile, c.Static, c.Import, connectionString)
+	fmt.Fprintf(h, "%v %v %v %v\n", c.Grid, c.GridSubdivisions, c.Legend, c.Render)
+	fmt.Fprintf(h, "%+v\n", annotations)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func worldChunks(refs []save.ChunkRef) []world.Chunk {
+	chunks := make([]world.Chunk, len(refs))
+	for i, r := range refs {
+		chunks[i] = world.Chunk{Character: r.Character, X: r.X, Y: r.Y}
+	}
+	return chunks
+}
+
+// regenerate brings the output up to date with the changed chunks of the
+// world. Tiles are redrawn and cells replaced only where chunks changed;
+// renderers that can't work chunk by chunk, such as text, images without
+// tiles, legends and static sites, are run again in full.
+func (c *genCommand) regenerate(w world.World, annotations []render.Annotation, connectionString string, chunks []world.Chunk) error {
+	return render.RunChunks(w, c.renderOptions(annotations, connectionString), c.renderers(), chunks)
+}
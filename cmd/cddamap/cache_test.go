@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+)
+
+func TestLoadCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cddamap-gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if cache := (&genCommand{}).loadCache(); cache != nil {
+		t.Errorf("expected no cache without an output folder, got %+v", cache)
+	}
+
+	c := &genCommand{OutputDir: dir}
+	written := save.NewCache()
+	written.Key = "key"
+	if err := written.Write(c.cacheFile()); err != nil {
+		t.Fatal(err)
+	}
+	if cache := c.loadCache(); cache == nil || cache.Key != "key" {
+		t.Errorf("expected the written cache, got %+v", cache)
+	}
+
+	c.Force = true
+	if cache := c.loadCache(); cache == nil || cache.Key != "" {
+		t.Errorf("expected --force to start over, got %+v", cache)
+	}
+	c.Force = false
+
+	if err := ioutil.WriteFile(filepath.Join(dir, cacheFile), []byte("not a cache"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := c.loadCache()
+	if cache == nil || cache.Key != "" || cache.Len() != 0 {
+		t.Errorf("expected an unreadable cache to start over, got %+v", cache)
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/render"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/server"
+	"github.com/ralreegorganon/cddamap/internal/tile"
+	log "github.com/sirupsen/logrus"
+)
+
+// worldOptions are the options shared by the commands that build a world
+// from a save.
+type worldOptions struct {
+	GameRoot    string `short:"g" long:"game" required:"true" description:"Cataclysm: DDA game root directory"`
+	Save        string `short:"s" long:"save" description:"Game save directory to process"`
+	Layers      []int  `short:"l" long:"layer" description:"Layer to render, 0-20. Repeat flag for multiple layers or omit for all."`
+	Terrain     bool   `short:"r" long:"terrain" description:"Render terrain"`
+	Seen        bool   `short:"e" long:"seen" description:"Render seen"`
+	SeenSolid   bool   `short:"d" long:"seensolid" description:"Render seen as a solid overlay"`
+	Cities      bool   `short:"C" long:"cities" description:"Render city names"`
+	SkipEmpty   bool   `short:"k" long:"skipempty" description:"Skip rendering empty layers"`
+	Overmap     string `short:"O" long:"overmap" description:"Overmap filter to limit included overmaps"`
+	LandUseCode bool   `short:"U" long:"landusecode" description:"Symbolize by land use code"`
+	Theme       string `long:"theme" default:"game" description:"Color theme: game, classic, colorblind, colorblind-bright or the path of a theme file"`
+}
+
+func (o *worldOptions) load() (save.Save, metadata.Overmap, error) {
+	o.Layers = allLayers(o.Layers)
+
+	s, err := save.Build(o.Save, o.Overmap)
+	if err != nil {
+		return s, metadata.Overmap{}, err
+	}
+
+	m, err := metadata.Build(s, o.GameRoot)
+	if err != nil {
+		return s, m, err
+	}
+
+	m, err = o.theme(m)
+	return s, m, err
+}
+
+// theme recolors the metadata with the chosen theme.
+func (o *worldOptions) theme(m metadata.Overmap) (metadata.Overmap, error) {
+	t, err := metadata.LoadTheme(o.Theme, o.GameRoot)
+	if err != nil {
+		return m, err
+	}
+	return m.WithTheme(t)
+}
+
+func (o *worldOptions) build() (world.World, error) {
+	s, m, err := o.load()
+	if err != nil {
+		return world.World{}, err
+	}
+
+	return world.Build(m, s, o.LandUseCode)
+}
+
+// renderOptions are the options every renderer shares, as given.
+func (o *worldOptions) renderOptions() render.Options {
+	return render.Options{
+		OvermapFilter: o.Overmap,
+		Layers:        o.Layers,
+		Terrain:       o.Terrain,
+		Seen:          o.Seen,
+		SeenSolid:     o.SeenSolid,
+		Cities:        o.Cities,
+		SkipEmpty:     o.SkipEmpty,
+		LandUseCode:   o.LandUseCode,
+	}
+}
+
+func (o *worldOptions) gis(w world.World, connectionString string) error {
+	ro := o.renderOptions()
+	ro.ConnectionString = connectionString
+	return render.GIS(w, ro)
+}
+
+type genCommand struct {
+	worldOptions
+	OutputDir          string        `short:"o" long:"output" description:"Output folder"`
+	Text               bool          `short:"t" long:"text" description:"Render to text files"`
+	Images             bool          `short:"i" long:"images" description:"Render to images"`
+	Tile               bool          `short:"T" long:"tile" description:"Tile the rendered images, implies --images"`
+	Import             bool          `short:"I" long:"import" description:"Import into the PostGIS database"`
+	DBConnectionString string        `short:"c" long:"connectionString" description:"PostGIS database connection string, overrides the configured one"`
+	Annotations        bool          `short:"A" long:"annotations" description:"Bake annotations from the PostGIS database into terrain images"`
+	Static             bool          `short:"S" long:"static" description:"Export a static site of tiles, JSON and the viewer to the output folder"`
+	Grid               bool          `short:"G" long:"grid" description:"Render an overmap coordinate grid overlay as an image and GeoJSON"`
+	GridSubdivisions   int           `long:"gridSubdivisions" description:"Also draw grid lines this many times across each overmap, must divide 180"`
+	Legend             bool          `short:"L" long:"legend" description:"Render a legend of the terrain on the rendered layers as PNG, HTML and JSON"`
+	Render             []string      `short:"R" long:"render" description:"Also run a registered renderer by name. Repeat flag for multiple renderers."`
+	Watch              bool          `short:"w" long:"watch" description:"Keep watching the save and update the tiles and database as the game saves, needs --tile or --import"`
+	WatchInterval      time.Duration `long:"watchInterval" default:"5s" description:"How often to check the save for changes"`
+	Force              bool          `short:"F" long:"force" description:"Ignore the chunk cache in the output folder and regenerate everything"`
+	All                bool          `short:"a" long:"all" description:"Process every save in the game's save folder, each into a folder of the output folder named for it"`
+	Jobs               int           `short:"j" long:"jobs" default:"2" description:"How many saves to process at once with --all"`
+}
+
+func init() {
+	render.Register("static", render.Serial(render.RendererFunc(exportStatic)))
+	parser.AddCommand("gen", "Generate maps from a save", "Builds the world from a game save and renders it as text, images, tiles, a static site or into the PostGIS database.", &genCommand{})
+}
+
+func (c *genCommand) Execute(args []string) error {
+	if c.Tile {
+		c.Images = true
+	}
+	if c.Save == "" && !c.All {
+		return fmt.Errorf("gen: --save or --all is required")
+	}
+	if (c.Text || c.Images || c.Static || c.Grid || c.Legend || len(c.Render) > 0) && c.OutputDir == "" {
+		return fmt.Errorf("gen: --output is required to render text, images, a grid, a legend, a static site or other renderers")
+	}
+	for _, name := range c.Render {
+		if _, err := render.Lookup(name); err != nil {
+			return fmt.Errorf("gen: %v", err)
+		}
+	}
+	if c.GridSubdivisions > 1 && 180%c.GridSubdivisions != 0 {
+		return fmt.Errorf("gen: --gridSubdivisions must divide an overmap's 180 terrains evenly")
+	}
+
+	if c.Watch && !c.Tile && !c.Import {
+		return fmt.Errorf("gen: --watch keeps tiles and the database current, use it with --tile or --import")
+	}
+	if c.Watch && (c.Static || c.All) {
+		return fmt.Errorf("gen: --watch only keeps a single save's tiles and database current")
+	}
+
+	connectionString, err := c.connectionString()
+	if err != nil {
+		return err
+	}
+	c.Layers = allLayers(c.Layers)
+
+	if c.All {
+		return c.batch(connectionString)
+	}
+
+	_, err = c.run(connectionString, func(s save.Save) (metadata.Overmap, error) {
+		return metadata.Build(s, c.GameRoot)
+	})
+	return err
+}
+
+// genResult describes what a run of gen did for one save.
+type genResult struct {
+	Chunks  int
+	Changed int
+	Full    bool
+}
+
+// run generates the output for one save, using the chunk cache to do as
+// little as it can, then watches the save if asked to.
+func (c *genCommand) run(connectionString string, buildMetadata func(save.Save) (metadata.Overmap, error)) (genResult, error) {
+	var result genResult
+
+	cache := c.loadCache()
+
+	s, changes, err := save.BuildCached(c.Save, c.Overmap, cache)
+	if err != nil {
+		return result, err
+	}
+	result.Chunks = len(s.Overmap.Chunks)
+	for _, seen := range s.Seen {
+		result.Chunks += len(seen.Chunks)
+	}
+	result.Changed = len(changes)
+
+	m, err := buildMetadata(s)
+	if err != nil {
+		return result, err
+	}
+	m, err = c.theme(m)
+	if err != nil {
+		return result, err
+	}
+
+	var annotations []render.Annotation
+	if c.Annotations {
+		annotations, err = render.LoadAnnotations(connectionString, s.Name)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	key := c.cacheKey(m, world.ExtentOf(s), annotations, connectionString)
+	result.Full = cache == nil || cache.Key != key
+	if !result.Full && len(changes) == 0 && !c.Watch {
+		log.WithField("save", c.Save).Info("nothing changed since the last run")
+		return result, nil
+	}
+
+	w, err := world.Build(m, s, c.LandUseCode)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Full {
+		err = c.generate(w, annotations, connectionString)
+	} else if len(changes) > 0 {
+		log.WithField("save", c.Save).WithField("changed", len(changes)).WithField("chunks", cache.Len()).Info("regenerating changed chunks")
+		err = c.regenerate(w, annotations, connectionString, worldChunks(changes))
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if cache != nil {
+		cache.Key = key
+		if err := cache.Write(c.cacheFile()); err != nil {
+			return result, err
+		}
+	}
+
+	if c.Watch {
+		return result, c.watch(s, m, w, annotations, connectionString)
+	}
+	return result, nil
+}
+
+// renderers lists the renderers asked for, in the order the flags list them.
+func (c *genCommand) renderers() []string {
+	names := []string{}
+	for _, r := range []struct {
+		name string
+		on   bool
+	}{
+		{"text", c.Text},
+		{"image", c.Images},
+		{"legend", c.Legend},
+		{"grid", c.Grid},
+		{"static", c.Static},
+		{"gis", c.Import},
+	} {
+		if r.on {
+			names = append(names, r.name)
+		}
+	}
+
+	for _, name := range c.Render {
+		if indexOf(names, name) == -1 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// renderOptions are the options given to every renderer gen runs.
+func (c *genCommand) renderOptions(annotations []render.Annotation, connectionString string) render.Options {
+	o := c.worldOptions.renderOptions()
+	o.OutputRoot = c.OutputDir
+	o.Tile = c.Tile
+	o.Annotations = annotations
+	o.ConnectionString = connectionString
+	o.GridSubdivisions = c.GridSubdivisions
+	return o
+}
+
+// generate renders, tiles, exports and imports the whole world, as asked,
+// with every renderer at once.
+func (c *genCommand) generate(w world.World, annotations []render.Annotation, connectionString string) error {
+	return render.Run(w, c.renderOptions(annotations, connectionString), c.renderers())
+}
+
+// connectionString returns the database to import into or read annotations
+// from, if either was asked for. Giving one on the command line implies
+// --import, as it always has.
+func (c *genCommand) connectionString() (string, error) {
+	if c.DBConnectionString != "" {
+		c.Import = true
+		return c.DBConnectionString, nil
+	}
+	if !c.Import && !c.Annotations {
+		return "", nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if cfg.Database.ConnectionString == "" {
+		return "", fmt.Errorf("gen: importing and annotations need a database, set a connection string")
+	}
+	return cfg.Database.ConnectionString, nil
+}
+
+// exportStatic renders and tiles the world under <output>/images, laid out
+// like the server's tile root, then writes a static site to <output>/site.
+func exportStatic(w world.World, o render.Options) error {
+	if o.OvermapFilter != "" {
+		log.Warn("static export expects the whole world, tiles rendered with an overmap filter won't be found")
+	}
+
+	imageRoot := filepath.Join(o.OutputRoot, "images")
+
+	images := o
+	images.OutputRoot = filepath.Join(imageRoot, w.Name)
+	images.Tile = true
+	if err := render.Image(w, images); err != nil {
+		return err
+	}
+
+	m := server.NewMemStore()
+	err := m.Load(w, o.Layers, o.Terrain, o.Seen, o.SeenSolid, o.SkipEmpty, o.Cities)
+	if err != nil {
+		return err
+	}
+
+	site := filepath.Join(o.OutputRoot, "site")
+	if err := server.ExportStatic(m, []string{imageRoot}, site); err != nil {
+		return err
+	}
+	log.WithField("site", site).Info("static site exported")
+	return nil
+}
+
+// tileDirectory tiles every image in dir.
+func tileDirectory(dir string, resume bool) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err := tile.ChopChop(f, resume); err != nil {
+			return err
+		}
+	}
+	return nil
+}
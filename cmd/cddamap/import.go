@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+)
+
+type importCommand struct {
+	worldOptions
+	DBConnectionString string `short:"c" long:"connectionString" description:"PostGIS database connection string, overrides the configured one"`
+}
+
+func init() {
+	parser.AddCommand("import", "Import a save into the database", "Builds the world from a game save and imports it into the PostGIS database for the server.", &importCommand{})
+}
+
+func (c *importCommand) Execute(args []string) error {
+	if c.Save == "" {
+		return fmt.Errorf("import: --save is required")
+	}
+
+	connectionString := c.DBConnectionString
+	if connectionString == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		connectionString = cfg.Database.ConnectionString
+	}
+	if connectionString == "" {
+		return fmt.Errorf("import: set a connection string")
+	}
+
+	w, err := c.build()
+	if err != nil {
+		return err
+	}
+	return c.gis(w, connectionString)
+}
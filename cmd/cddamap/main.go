@@ -1,25 +1,22 @@
 package main
 
 import (
-	"flag"
-	"fmt"
 	"net/http"
+	_ "net/http/pprof"
 	"os"
-	"os/signal"
-	"path/filepath"
-	"time"
 
-	"github.com/ralreegorganon/cddamap/internal/server"
+	flags "github.com/jessevdk/go-flags"
+	"github.com/ralreegorganon/cddamap/internal/config"
 	log "github.com/sirupsen/logrus"
-
-	"github.com/mattes/migrate"
-
-	_ "github.com/mattes/migrate/database/postgres"
-	_ "github.com/mattes/migrate/source/file"
 )
 
-var version = flag.Bool("version", false, "Print version")
-var tileRoot = flag.String("tileRoot", "./tiles", "Root directory for tiles")
+var opts struct {
+	Config   string `long:"config" env:"CDDAMAP_CONFIG" description:"TOML config file, settings in the environment take precedence"`
+	LogLevel string `long:"logLevel" env:"CDDAMAP_LOG_LEVEL" default:"info" description:"Log level: debug, info, warn or error"`
+	Pprof    string `long:"pprof" description:"Serve pprof profiles on this address, e.g. :8080"`
+}
+
+var parser = flags.NewParser(&opts, flags.Default)
 
 func init() {
 	f := &log.TextFormatter{
@@ -29,53 +26,49 @@ func init() {
 }
 
 func main() {
-	flag.Parse()
-
-	if *version {
-		fmt.Printf("Version: %s - Commit: %s - Date: %s\n", Version, GitCommit, BuildDate)
-		return
-	}
-
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
+	parser.CommandHandler = func(command flags.Commander, args []string) error {
+		level, err := log.ParseLevel(opts.LogLevel)
+		if err != nil {
+			return err
+		}
+		log.SetLevel(level)
 
-	connectionString := os.Getenv("CDDAMAP_CONNECTION_STRING")
-	var db server.DB
-	if err := db.Open(connectionString); err != nil {
-		log.Fatal(err)
-	}
+		if opts.Pprof != "" {
+			go func() {
+				log.WithField("address", opts.Pprof).Info("serving pprof")
+				http.ListenAndServe(opts.Pprof, nil)
+			}()
+		}
 
-	migrationsPath := os.Getenv("CDDAMAP_MIGRATIONS_PATH")
-	g, err := migrate.New(migrationsPath, connectionString)
-	if err != nil {
-		time.Sleep(30 * time.Second)
-		log.Fatal("Couldn't create migrator: ", err)
+		if command == nil {
+			return nil
+		}
+		return command.Execute(args)
 	}
 
-	if err = g.Up(); err != nil {
-		if err != migrate.ErrNoChange {
-			log.Fatal(err)
-		} else {
-			log.Info("Migrations up to date")
+	if _, err := parser.Parse(); err != nil {
+		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
+			return
+		}
+		if _, ok := err.(*flags.Error); !ok {
+			log.Error(err)
 		}
+		os.Exit(1)
 	}
+}
 
-	absTileRoot, err := filepath.Abs(*tileRoot)
-	if err != nil {
-		log.Fatal(err)
-	}
+// loadConfig reads the settings shared by every command, see config.Load.
+func loadConfig() (config.Config, error) {
+	return config.Load(opts.Config)
+}
 
-	s := server.NewHTTPServer(&db, absTileRoot)
-	router, err := server.CreateRouter(s)
-	if err != nil {
-		log.Fatal(err)
+// allLayers returns every layer, 0 to 20, for commands given none.
+func allLayers(layers []int) []int {
+	if len(layers) > 0 {
+		return layers
 	}
-	http.Handle("/", router)
-
-	u := "0.0.0.0:8989"
-	go http.ListenAndServe(u, nil)
-	log.WithField("address", u).Info("cddamap web server started")
-	log.WithField("tileRoot", absTileRoot).Info("serving tiles")
-
-	<-interrupt
+	for i := 0; i < 21; i++ {
+		layers = append(layers, i)
+	}
+	return layers
 }
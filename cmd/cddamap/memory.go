@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/server"
+)
+
+func loadMemStore(gameRoot, savePath string) (*server.MemStore, error) {
+	s, err := save.Build(savePath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := metadata.Build(s, gameRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := world.Build(o, s, false)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make([]int, 21)
+	for i := range layers {
+		layers[i] = i
+	}
+
+	m := server.NewMemStore()
+	err = m.Load(w, layers, true, true, true, true, true)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mattes/migrate"
+	"github.com/mattes/migrate/source"
+	"github.com/ralreegorganon/cddamap/internal/config"
+	log "github.com/sirupsen/logrus"
+
+	_ "github.com/mattes/migrate/database/postgres"
+	_ "github.com/mattes/migrate/source/file"
+)
+
+type migrateCommand struct{}
+
+type migrateUpCommand struct{}
+
+type migrateDownCommand struct {
+	Steps int  `short:"n" long:"steps" default:"1" description:"Number of migrations to roll back"`
+	All   bool `long:"all" description:"Roll back every migration"`
+}
+
+type migrateStatusCommand struct{}
+
+func init() {
+	c, _ := parser.AddCommand("migrate", "Manage database migrations", "Applies, rolls back or reports the database migrations from the configured migrations path.", &migrateCommand{})
+	c.SubcommandsOptional = false
+	c.AddCommand("up", "Apply all pending migrations", "", &migrateUpCommand{})
+	c.AddCommand("down", "Roll back migrations", "", &migrateDownCommand{})
+	c.AddCommand("status", "Print the current migration", "", &migrateStatusCommand{})
+}
+
+func newMigrator(cfg config.Config) (*migrate.Migrate, error) {
+	g, err := migrate.New(cfg.Database.MigrationsPath, cfg.Database.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create migrator: %v", err)
+	}
+	return g, nil
+}
+
+// latestMigrationIn returns the newest migration in the configured
+// migrations path, which the database must reach before serving is ready.
+// Without a migrations path there's nothing to wait for.
+func latestMigrationIn(cfg config.Config) (uint, error) {
+	if cfg.Database.MigrationsPath == "" {
+		return 0, nil
+	}
+
+	src, err := source.Open(cfg.Database.MigrationsPath)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't open migrations: %v", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("couldn't find migrations: %v", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if os.IsNotExist(err) {
+			return version, nil
+		} else if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+func loadMigrator() (*migrate.Migrate, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(cfg)
+}
+
+func migrateUp(g *migrate.Migrate) error {
+	if err := g.Up(); err != nil {
+		if err != migrate.ErrNoChange {
+			return err
+		}
+		log.Info("Migrations up to date")
+	}
+	return nil
+}
+
+func (c *migrateUpCommand) Execute(args []string) error {
+	g, err := loadMigrator()
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+	return migrateUp(g)
+}
+
+func (c *migrateDownCommand) Execute(args []string) error {
+	g, err := loadMigrator()
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	if c.All {
+		err = g.Down()
+	} else {
+		err = g.Steps(-c.Steps)
+	}
+	if err == migrate.ErrNoChange {
+		log.Info("No migrations to roll back")
+		return nil
+	}
+	return err
+}
+
+func (c *migrateStatusCommand) Execute(args []string) error {
+	g, err := loadMigrator()
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	version, dirty, err := g.Version()
+	if err == migrate.ErrNilVersion {
+		fmt.Println("no migrations applied")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("%v (dirty)\n", version)
+	} else {
+		fmt.Println(version)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/ralreegorganon/cddamap/internal/server"
+	log "github.com/sirupsen/logrus"
+)
+
+type serveCommand struct {
+	TileRoot          string        `long:"tileRoot" description:"Root directory for tiles, overrides the configured tile roots"`
+	GameRoot          string        `long:"game" description:"Cataclysm: DDA game root directory, used with --save"`
+	Save              string        `long:"save" description:"Serve this game save from memory instead of PostGIS"`
+	ShutdownTimeout   time.Duration `long:"shutdownTimeout" default:"30s" description:"How long to wait for in-flight requests on shutdown"`
+	TileWatchInterval time.Duration `long:"tileWatchInterval" description:"How often to check tiles for changes, overrides the configured interval"`
+}
+
+func init() {
+	parser.AddCommand("serve", "Run the map server", "Serves the viewer, API and tiles, migrating the database first unless read-only.", &serveCommand{})
+}
+
+func (c *serveCommand) Execute(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if c.TileRoot != "" {
+		cfg.Tiles.Roots = []string{c.TileRoot}
+	}
+	if parser.Find("serve").FindOptionByLongName("tileWatchInterval").IsSet() {
+		cfg.Tiles.WatchInterval.Duration = c.TileWatchInterval
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	var store server.Store
+	var latestMigration uint
+	if c.Save != "" {
+		m, err := loadMemStore(c.GameRoot, c.Save)
+		if err != nil {
+			return err
+		}
+		store = m
+		log.WithField("save", c.Save).Info("serving save from memory")
+	} else {
+		var db server.DB
+		if err := db.Open(cfg.Database.ConnectionString); err != nil {
+			return err
+		}
+		db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+		db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime.Duration)
+
+		latestMigration, err = latestMigrationIn(cfg)
+		if err != nil {
+			return err
+		}
+
+		if cfg.ReadOnly {
+			log.Info("read-only, skipping migrations")
+		} else {
+			g, err := newMigrator(cfg)
+			if err != nil {
+				time.Sleep(30 * time.Second)
+				return err
+			}
+			if err := migrateUp(g); err != nil {
+				return err
+			}
+		}
+		store = &db
+	}
+
+	tileRoots := make([]string, len(cfg.Tiles.Roots))
+	for i, t := range cfg.Tiles.Roots {
+		if tileRoots[i], err = filepath.Abs(t); err != nil {
+			return err
+		}
+	}
+
+	s := server.NewHTTPServer(store, tileRoots)
+	s.AllowedOrigins = cfg.CORS.AllowedOrigins
+	s.ReadOnly = cfg.ReadOnly
+	s.LatestMigration = latestMigration
+	s.PublicMetrics = cfg.PublicMetrics
+	router, err := server.CreateRouter(s)
+	if err != nil {
+		return err
+	}
+
+	if c.Save == "" {
+		listener, err := server.ListenForWorldUpdates(cfg.Database.ConnectionString, s.Updates)
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+	}
+
+	if cfg.Tiles.WatchInterval.Duration > 0 {
+		go server.WatchTiles(store, tileRoots, cfg.Tiles.WatchInterval.Duration, s.Updates)
+	}
+
+	srv := &http.Server{
+		Addr:    cfg.Listen,
+		Handler: router,
+	}
+	srv.RegisterOnShutdown(s.Updates.Close)
+
+	go func() {
+		var err error
+		if cfg.TLS.Cert != "" {
+			err = srv.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+	log.WithField("address", srv.Addr).WithField("tls", cfg.TLS.Cert != "").WithField("readOnly", cfg.ReadOnly).Info("cddamap web server started")
+	log.WithField("tileRoots", tileRoots).Info("serving tiles")
+
+	<-interrupt
+	log.Info("shutting down, draining requests")
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.WithField("err", err).Error("graceful shutdown failed")
+	}
+	return nil
+}
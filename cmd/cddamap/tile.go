@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/ralreegorganon/cddamap/internal/tile"
+)
+
+type tileCommand struct {
+	ImageDirectory string   `short:"I" long:"imageDirectory" description:"Image directory to tile"`
+	ImageFiles     []string `short:"i" long:"images" description:"Images to tile"`
+	Resume         bool     `short:"z" long:"resume" description:"Resume tile building, instead of overwriting"`
+}
+
+func init() {
+	parser.AddCommand("tile", "Tile rendered images", "Cuts rendered images into a pyramid of 256px tiles next to each image.", &tileCommand{})
+}
+
+func (c *tileCommand) Execute(args []string) error {
+	if c.ImageDirectory != "" {
+		if err := tileDirectory(c.ImageDirectory, c.Resume); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range append(c.ImageFiles, args...) {
+		if err := tile.ChopChop(f, c.Resume); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
 package main
 
+import "fmt"
+
 var (
 	// BuildDate is the date when the binary was built.
 	BuildDate string
@@ -8,3 +10,14 @@ var (
 	// Version is the version of the binary.
 	Version string
 )
+
+type versionCommand struct{}
+
+func init() {
+	parser.AddCommand("version", "Print version", "", &versionCommand{})
+}
+
+func (c *versionCommand) Execute(args []string) error {
+	fmt.Printf("Version: %s - Commit: %s - Date: %s\n", Version, GitCommit, BuildDate)
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/render"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	log "github.com/sirupsen/logrus"
+)
+
+// watch polls the save for overmap and seen chunk files the game has
+// rewritten, and updates the world, tiles and database for just those
+// chunks. Overmap chunks outside the world grow it, which takes a full
+// rebuild. Changes that fail to apply are tried again at the next check.
+// An interrupt or SIGTERM stops it once any update under way is done, so
+// tiles and the database aren't left half written.
+func (c *genCommand) watch(s save.Save, m metadata.Overmap, w world.World, annotations []render.Annotation, connectionString string) error {
+	times, err := save.ChunkTimes(c.Save, c.Overmap)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("save", c.Save).WithField("interval", c.WatchInterval).Info("watching save for changes")
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(c.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case sig := <-interrupt:
+			log.WithField("signal", sig).Info("stopped watching save")
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := save.ChunkTimes(c.Save, c.Overmap)
+		if err != nil {
+			log.WithField("err", err).Warn("couldn't check save for changes")
+			continue
+		}
+
+		changed := []string{}
+		for f, t := range current {
+			if prev, ok := times[f]; !ok || !prev.Equal(t) {
+				changed = append(changed, f)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		sort.Strings(changed)
+
+		// The game may still be writing, so a chunk that doesn't parse is
+		// left for the next check rather than marked as seen.
+		overmaps, seen, err := readChangedChunks(changed)
+		if err != nil {
+			log.WithField("err", err).Debug("changed chunks not readable yet")
+			continue
+		}
+
+		start := time.Now()
+		chunks, seen, grown := applyChunks(&s, overmaps, seen, w.Extent)
+		if grown {
+			log.Info("save grew beyond the world, rebuilding it")
+			var rebuilt world.World
+			rebuilt, err = world.Build(m, s, c.LandUseCode)
+			if err == nil {
+				err = c.generate(rebuilt, annotations, connectionString)
+			}
+			if err == nil {
+				w = rebuilt
+			}
+		} else {
+			err = c.update(m, &w, overmaps, seen, annotations, connectionString, chunks)
+		}
+		if err != nil {
+			log.WithField("err", err).Error("couldn't update map")
+			continue
+		}
+
+		for _, f := range changed {
+			times[f] = current[f]
+		}
+		log.WithField("chunks", len(chunks)).WithField("elapsed", time.Since(start)).Info("map updated")
+	}
+}
+
+type seenChunk struct {
+	name  string
+	chunk save.SeenChunk
+}
+
+func readChangedChunks(files []string) ([]save.OvermapChunk, []seenChunk, error) {
+	overmaps := []save.OvermapChunk{}
+	seen := []seenChunk{}
+	for _, f := range files {
+		if save.IsOvermapChunkFile(f) {
+			chunk, err := save.ReadOvermapChunk(f)
+			if err != nil {
+				return nil, nil, err
+			}
+			overmaps = append(overmaps, chunk)
+		} else if save.IsSeenChunkFile(f) {
+			name, chunk, err := save.ReadSeenChunk(f)
+			if err != nil {
+				return nil, nil, err
+			}
+			seen = append(seen, seenChunk{name: name, chunk: chunk})
+		}
+	}
+	return overmaps, seen, nil
+}
+
+// applyChunks puts the changed chunks into the save and lists them,
+// reporting whether any overmap chunks lie outside the world's extent. The
+// extent comes from overmaps alone, so seen chunks outside it are kept in
+// the save for when the world grows but otherwise left out.
+func applyChunks(s *save.Save, overmaps []save.OvermapChunk, seen []seenChunk, e world.Extent) ([]world.Chunk, []seenChunk, bool) {
+	chunks := []world.Chunk{}
+	grown := false
+	for _, o := range overmaps {
+		s.SetOvermapChunk(o)
+		chunks = append(chunks, world.Chunk{X: o.X, Y: o.Y})
+		grown = grown || !e.Contains(o.X, o.Y)
+	}
+
+	inside := []seenChunk{}
+	for _, sc := range seen {
+		s.SetSeenChunk(sc.name, sc.chunk)
+		if !e.Contains(sc.chunk.X, sc.chunk.Y) {
+			continue
+		}
+		chunks = append(chunks, world.Chunk{Character: sc.name, X: sc.chunk.X, Y: sc.chunk.Y})
+		inside = append(inside, sc)
+	}
+	return chunks, inside, grown
+}
+
+// update rebuilds the changed chunks of the world, then brings the output
+// up to date with them.
+func (c *genCommand) update(m metadata.Overmap, w *world.World, overmaps []save.OvermapChunk, seen []seenChunk, annotations []render.Annotation, connectionString string, chunks []world.Chunk) error {
+	for _, o := range overmaps {
+		if err := w.UpdateChunk(m, o, c.LandUseCode); err != nil {
+			return err
+		}
+	}
+	for _, sc := range seen {
+		if err := w.UpdateSeenChunk(sc.name, sc.chunk); err != nil {
+			return err
+		}
+	}
+
+	return c.regenerate(*w, annotations, connectionString, chunks)
+}
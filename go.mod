@@ -3,6 +3,7 @@ module github.com/ralreegorganon/cddamap
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/disintegration/imaging v1.5.0
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/gorilla/context v1.1.1
@@ -0,0 +1,166 @@
+// Package config loads the cddamap server configuration from an optional
+// TOML file, with CDDAMAP_* environment variables taking precedence.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+type Config struct {
+	Listen        string   `toml:"listen"`
+	ReadOnly      bool     `toml:"read_only"`
+	PublicMetrics bool     `toml:"public_metrics"`
+	TLS           TLS      `toml:"tls"`
+	CORS          CORS     `toml:"cors"`
+	Database      Database `toml:"database"`
+	Tiles         Tiles    `toml:"tiles"`
+}
+
+// TLS serves HTTPS when both a certificate and key are given.
+type TLS struct {
+	Cert string `toml:"cert"`
+	Key  string `toml:"key"`
+}
+
+type CORS struct {
+	AllowedOrigins []string `toml:"allowed_origins"`
+}
+
+// Database pool limits follow database/sql, where zero means unlimited.
+type Database struct {
+	ConnectionString string   `toml:"connection_string"`
+	MigrationsPath   string   `toml:"migrations_path"`
+	MaxOpenConns     int      `toml:"max_open_conns"`
+	MaxIdleConns     int      `toml:"max_idle_conns"`
+	ConnMaxLifetime  Duration `toml:"conn_max_lifetime"`
+}
+
+// Tiles are looked up in each root in turn, so tiles can be spread over
+// several disks or an old and a new render directory.
+type Tiles struct {
+	Roots         []string `toml:"roots"`
+	WatchInterval Duration `toml:"watch_interval"`
+}
+
+// Duration reads a time.Duration from strings like "10s".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func Default() Config {
+	return Config{
+		Listen: "0.0.0.0:8989",
+		CORS: CORS{
+			AllowedOrigins: []string{"*"},
+		},
+		Tiles: Tiles{
+			Roots:         []string{"./tiles"},
+			WatchInterval: Duration{10 * time.Second},
+		},
+	}
+}
+
+// Load reads the config file at path, if any, over the defaults and then
+// applies environment overrides.
+func Load(path string) (Config, error) {
+	c := Default()
+
+	if path != "" {
+		if _, err := toml.DecodeFile(path, &c); err != nil {
+			return c, fmt.Errorf("reading config %v: %v", path, err)
+		}
+	}
+
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return c, err
+	}
+
+	return c, c.Validate()
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	str := func(name string, dst *string) {
+		if v, ok := lookup(name); ok {
+			*dst = v
+		}
+	}
+	list := func(name string, sep string, dst *[]string) {
+		if v, ok := lookup(name); ok {
+			*dst = split(v, sep)
+		}
+	}
+	var err error
+	num := func(name string, dst *int) {
+		if v, ok := lookup(name); ok && err == nil {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				err = fmt.Errorf("%v: %v", name, err)
+			}
+		}
+	}
+	boolean := func(name string, dst *bool) {
+		if v, ok := lookup(name); ok && err == nil {
+			if *dst, err = strconv.ParseBool(v); err != nil {
+				err = fmt.Errorf("%v: %v", name, err)
+			}
+		}
+	}
+	duration := func(name string, dst *Duration) {
+		if v, ok := lookup(name); ok && err == nil {
+			if err = dst.UnmarshalText([]byte(v)); err != nil {
+				err = fmt.Errorf("%v: %v", name, err)
+			}
+		}
+	}
+
+	str("CDDAMAP_LISTEN", &c.Listen)
+	boolean("CDDAMAP_READ_ONLY", &c.ReadOnly)
+	boolean("CDDAMAP_PUBLIC_METRICS", &c.PublicMetrics)
+	str("CDDAMAP_TLS_CERT", &c.TLS.Cert)
+	str("CDDAMAP_TLS_KEY", &c.TLS.Key)
+	list("CDDAMAP_CORS_ALLOWED_ORIGINS", ",", &c.CORS.AllowedOrigins)
+	str("CDDAMAP_CONNECTION_STRING", &c.Database.ConnectionString)
+	str("CDDAMAP_MIGRATIONS_PATH", &c.Database.MigrationsPath)
+	num("CDDAMAP_DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	num("CDDAMAP_DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	duration("CDDAMAP_DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime)
+	list("CDDAMAP_TILE_ROOTS", string(filepath.ListSeparator), &c.Tiles.Roots)
+	duration("CDDAMAP_TILE_WATCH_INTERVAL", &c.Tiles.WatchInterval)
+
+	return err
+}
+
+func (c Config) Validate() error {
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		return fmt.Errorf("tls needs both a cert and a key")
+	}
+	if len(c.Tiles.Roots) == 0 {
+		return fmt.Errorf("at least one tile root is required")
+	}
+	return nil
+}
+
+func split(s, sep string) []string {
+	var parts []string
+	for _, p := range strings.Split(s, sep) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
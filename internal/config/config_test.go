@@ -0,0 +1,76 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestLoad(t *testing.T) {
+	f, err := ioutil.TempFile("", "cddamap.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString(`
+listen = "127.0.0.1:9000"
+read_only = true
+
+[cors]
+allowed_origins = ["https://map.example.com"]
+
+[database]
+max_open_conns = 20
+conn_max_lifetime = "5m"
+
+[tiles]
+roots = ["/srv/tiles", "/mnt/tiles"]
+`)
+	f.Close()
+
+	c := Default()
+	env := map[string]string{
+		"CDDAMAP_LISTEN":            ":8080",
+		"CDDAMAP_DB_MAX_IDLE_CONNS": "4",
+		"CDDAMAP_PUBLIC_METRICS":    "true",
+	}
+	if _, err := toml.DecodeFile(f.Name(), &c); err != nil {
+		t.Fatal(err)
+	}
+	err = c.applyEnv(func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Listen != ":8080" {
+		t.Errorf("expected the environment to override the file, got %q", c.Listen)
+	}
+	if !c.ReadOnly || !c.PublicMetrics || c.Database.MaxOpenConns != 20 || c.Database.MaxIdleConns != 4 {
+		t.Errorf("unexpected config: %+v", c)
+	}
+	if c.Database.ConnMaxLifetime.Duration != 5*time.Minute {
+		t.Errorf("unexpected lifetime: %v", c.Database.ConnMaxLifetime)
+	}
+	if !reflect.DeepEqual(c.Tiles.Roots, []string{"/srv/tiles", "/mnt/tiles"}) {
+		t.Errorf("unexpected tile roots: %v", c.Tiles.Roots)
+	}
+	if c.Tiles.WatchInterval.Duration != 10*time.Second {
+		t.Errorf("expected defaults to survive, got %v", c.Tiles.WatchInterval)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := Default()
+	c.TLS.Cert = "cert.pem"
+	if err := c.Validate(); err == nil {
+		t.Errorf("expected a cert without a key to be rejected")
+	}
+}
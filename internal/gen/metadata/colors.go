@@ -0,0 +1,228 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type ColorPair struct {
+	FG color.RGBA
+	BG color.RGBA
+}
+
+// baseColorNames are the game's 16 base colors, named as in its colors.json.
+var baseColorNames = []string{
+	"BLACK",
+	"RED",
+	"GREEN",
+	"BROWN",
+	"BLUE",
+	"MAGENTA",
+	"CYAN",
+	"GRAY",
+	"DGRAY",
+	"LRED",
+	"LGREEN",
+	"YELLOW",
+	"LBLUE",
+	"LMAGENTA",
+	"LCYAN",
+	"WHITE",
+}
+
+// classicColors are the base colors maps were drawn with before they were
+// read from the game. They match the game's own but for WHITE, which was
+// drawn as light gray. They're also used when the game has no colors.json.
+var classicColors = map[string]color.RGBA{
+	"BLACK":    {0, 0, 0, 255},
+	"RED":      {255, 0, 0, 255},
+	"GREEN":    {0, 110, 0, 255},
+	"BROWN":    {92, 51, 23, 255},
+	"BLUE":     {0, 0, 200, 255},
+	"MAGENTA":  {139, 58, 98, 255},
+	"CYAN":     {0, 150, 180, 255},
+	"GRAY":     {150, 150, 150, 255},
+	"DGRAY":    {99, 99, 99, 255},
+	"LRED":     {255, 150, 150, 255},
+	"LGREEN":   {0, 255, 0, 255},
+	"YELLOW":   {255, 255, 0, 255},
+	"LBLUE":    {100, 100, 255, 255},
+	"LMAGENTA": {254, 0, 254, 255},
+	"LCYAN":    {0, 240, 255, 255},
+	"WHITE":    {150, 150, 150, 255},
+}
+
+// colorNames maps the names the game gives colors in its data to the base
+// colors they're drawn with.
+var colorNames = map[string]string{
+	"black":       "BLACK",
+	"white":       "WHITE",
+	"light_gray":  "GRAY",
+	"dark_gray":   "DGRAY",
+	"red":         "RED",
+	"green":       "GREEN",
+	"blue":        "BLUE",
+	"cyan":        "CYAN",
+	"magenta":     "MAGENTA",
+	"brown":       "BROWN",
+	"light_red":   "LRED",
+	"light_green": "LGREEN",
+	"light_blue":  "LBLUE",
+	"light_cyan":  "LCYAN",
+	"pink":        "LMAGENTA",
+	"yellow":      "YELLOW",
+}
+
+// resolveColor returns the base colors of the foreground and background of
+// a color name, read the way the game reads one: a name without a prefix is
+// taken as c_, a leading lt or dk abbreviates light_ or dark_, c_X is X on
+// black and c_X_Y is X on Y, i_X is inverted to black on X, and h_X is
+// highlighted as X on blue.
+func resolveColor(name string) (string, string, bool) {
+	if name == "" || name == "unset" || name == "c_unset" {
+		return "GRAY", "BLACK", true
+	}
+
+	if len(name) < 2 || name[1] != '_' {
+		name = "c_" + name
+	}
+	prefix, rest := name[:2], expandAbbreviation(name[2:])
+	switch prefix {
+	case "c_":
+		if fg, ok := colorNames[rest]; ok {
+			return fg, "BLACK", true
+		}
+		for n, fg := range colorNames {
+			if !strings.HasPrefix(rest, n+"_") {
+				continue
+			}
+			if bg, ok := colorNames[strings.TrimPrefix(rest, n+"_")]; ok {
+				return fg, bg, true
+			}
+		}
+	case "i_":
+		if bg, ok := colorNames[rest]; ok {
+			if bg == "BLACK" {
+				return "BLACK", "WHITE", true
+			}
+			return "BLACK", bg, true
+		}
+	case "h_":
+		if fg, ok := colorNames[rest]; ok {
+			return fg, "BLUE", true
+		}
+	}
+	return "", "", false
+}
+
+// expandAbbreviation spells out the lt or dk the game's older data starts
+// color names with, so ltred is light_red and dkgray_red is dark_gray_red.
+func expandAbbreviation(name string) string {
+	switch {
+	case strings.HasPrefix(name, "lt"):
+		return "light_" + strings.TrimPrefix(name[2:], "_")
+	case strings.HasPrefix(name, "dk"):
+		return "dark_" + strings.TrimPrefix(name[2:], "_")
+	}
+	return name
+}
+
+// colorPair returns the colors of a color name from the metadata's base
+// colors.
+func (o Overmap) colorPair(name string) (ColorPair, bool) {
+	fg, bg, ok := resolveColor(name)
+	if !ok {
+		return ColorPair{}, false
+	}
+	return ColorPair{FG: o.baseColors[fg], BG: o.baseColors[bg]}, true
+}
+
+// loadBaseColors reads the game's base colors from data/raw/colors.json,
+// then any the player has changed in config/base_colors.json. A game too
+// old to have a colors.json is drawn with the classic colors.
+func loadBaseColors(gameRoot string) (map[string]color.RGBA, error) {
+	base := copyBaseColors(classicColors)
+
+	for _, f := range []string{
+		filepath.Join(gameRoot, "data", "raw", "colors.json"),
+		filepath.Join(gameRoot, "config", "base_colors.json"),
+	} {
+		defs, err := readColorDefs(f)
+		if os.IsNotExist(err) {
+			log.WithField("file", f).Debug("no color definitions")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for name, rgb := range defs {
+			c, err := rgbColor(name, rgb)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", f, err)
+			}
+			base[name] = c
+		}
+	}
+	return base, nil
+}
+
+// readColorDefs reads base colors from a file in the format of the game's
+// colors.json, a list holding a colordef object of names and [r, g, b]s.
+func readColorDefs(file string) (map[string][3]int, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &defs); err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+
+	colors := make(map[string][3]int)
+	for _, d := range defs {
+		var typ string
+		if err := json.Unmarshal(d["type"], &typ); err != nil || typ != "colordef" {
+			continue
+		}
+		for _, name := range baseColorNames {
+			raw, ok := d[name]
+			if !ok {
+				continue
+			}
+			var rgb [3]int
+			if err := json.Unmarshal(raw, &rgb); err != nil {
+				return nil, fmt.Errorf("%v: %v: %v", file, name, err)
+			}
+			colors[name] = rgb
+		}
+	}
+	return colors, nil
+}
+
+func rgbColor(name string, rgb [3]int) (color.RGBA, error) {
+	if _, ok := classicColors[name]; !ok {
+		return color.RGBA{}, fmt.Errorf("%v isn't a base color, use one of %v", name, strings.Join(baseColorNames, ", "))
+	}
+	for _, v := range rgb {
+		if v < 0 || v > 255 {
+			return color.RGBA{}, fmt.Errorf("%v is out of range: %v", name, rgb)
+		}
+	}
+	return color.RGBA{uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]), 255}, nil
+}
+
+func copyBaseColors(base map[string]color.RGBA) map[string]color.RGBA {
+	c := make(map[string]color.RGBA, len(base))
+	for k, v := range base {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,46 @@
+package metadata
+
+import "testing"
+
+func TestResolveColor(t *testing.T) {
+	tests := []struct {
+		name   string
+		fg, bg string
+		ok     bool
+	}{
+		{"", "GRAY", "BLACK", true},
+		{"unset", "GRAY", "BLACK", true},
+		{"c_unset", "GRAY", "BLACK", true},
+		{"red", "RED", "BLACK", true},
+		{"light_green", "LGREEN", "BLACK", true},
+		{"c_light_green", "LGREEN", "BLACK", true},
+		{"c_pink", "LMAGENTA", "BLACK", true},
+		{"c_white_red", "WHITE", "RED", true},
+		{"light_green_yellow", "LGREEN", "YELLOW", true},
+		{"c_light_gray_light_red", "GRAY", "LRED", true},
+		{"c_dark_gray_cyan", "DGRAY", "CYAN", true},
+		{"i_red", "BLACK", "RED", true},
+		{"i_light_blue", "BLACK", "LBLUE", true},
+		{"i_black", "BLACK", "WHITE", true},
+		{"h_yellow", "YELLOW", "BLUE", true},
+		{"h_dark_gray", "DGRAY", "BLUE", true},
+		{"ltred", "LRED", "BLACK", true},
+		{"c_ltblue", "LBLUE", "BLACK", true},
+		{"i_ltgreen", "BLACK", "LGREEN", true},
+		{"h_dkgray", "DGRAY", "BLUE", true},
+		{"c_dkgray_red", "DGRAY", "RED", true},
+		{"c_salt", "", "", false},
+		{"c_chartreuse", "", "", false},
+		{"c_red_chartreuse", "", "", false},
+		{"i_red_blue", "", "", false},
+		{"h_red_blue", "", "", false},
+		{"x_red", "", "", false},
+	}
+
+	for _, tt := range tests {
+		fg, bg, ok := resolveColor(tt.name)
+		if fg != tt.fg || bg != tt.bg || ok != tt.ok {
+			t.Errorf("%q: expected %v on %v (%v), got %v on %v (%v)", tt.name, tt.fg, tt.bg, tt.ok, fg, bg, ok)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	"image/color"
@@ -10,27 +11,50 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/imdario/mergo"
 	"github.com/ralreegorganon/cddamap/internal/gen/save"
+	"github.com/ralreegorganon/cddamap/internal/terrain"
 	log "github.com/sirupsen/logrus"
 )
 
 type overmapTerrain struct {
-	internalID  uint32
-	ID          string   `json:"id"`
-	Type        string   `json:"type"`
-	Abstract    string   `json:"abstract"`
-	Sym         string   `json:"sym"`
-	Color       string   `json:"color"`
-	LandUseCode string   `json:"land_use_code"`
-	CopyFrom    string   `json:"copy-from"`
-	SeeCost     int      `json:"see_cost"`
-	Extras      string   `json:"extras"`
-	MonDensity  int      `json:"mondensity"`
-	Flags       []string `json:"flags"`
-	Spawns      spawns   `json:"spawns"`
-	Delete      deleteit `json:"delete"`
+	ID          string           `json:"id"`
+	Type        string           `json:"type"`
+	Abstract    string           `json:"abstract"`
+	Name        translatedString `json:"name"`
+	Sym         string           `json:"sym"`
+	Color       string           `json:"color"`
+	LandUseCode string           `json:"land_use_code"`
+	CopyFrom    string           `json:"copy-from"`
+	SeeCost     int              `json:"see_cost"`
+	Extras      string           `json:"extras"`
+	MonDensity  int              `json:"mondensity"`
+	Flags       []string         `json:"flags"`
+	Spawns      spawns           `json:"spawns"`
+	Delete      deleteit         `json:"delete"`
+}
+
+// translatedString accepts both a plain string and the {"str": "..."} form
+// newer game versions use for translatable names.
+type translatedString string
+
+func (t *translatedString) UnmarshalJSON(bs []byte) error {
+	var s string
+	if err := json.Unmarshal(bs, &s); err == nil {
+		*t = translatedString(s)
+		return nil
+	}
+
+	var o struct {
+		Str string `json:"str"`
+	}
+	if err := json.Unmarshal(bs, &o); err != nil {
+		return err
+	}
+	*t = translatedString(o.Str)
+	return nil
 }
 
 type deleteit struct {
@@ -86,24 +110,6 @@ func indexOf(slice []string, item string) int {
 	return -1
 }
 
-var linearSuffixes = []string{
-	"_isolated",
-	"_end_south",
-	"_end_west",
-	"_ne",
-	"_end_north",
-	"_ns",
-	"_es",
-	"_nes",
-	"_end_east",
-	"_wn",
-	"_ew",
-	"_new",
-	"_sw",
-	"_nsw",
-	"_esw",
-	"_nesw"}
-
 var linearSuffixSymbols = map[string]string{
 	"_isolated":  "",
 	"_end_south": "\u2502",
@@ -123,116 +129,59 @@ var linearSuffixSymbols = map[string]string{
 	"_nesw":      "\u253c",
 }
 
-var rotationSuffixes = []string{
-	"_north",
-	"_east",
-	"_south",
-	"_west"}
-
 var rotations [][]string
 
-type ColorPair struct {
-	FG color.RGBA
-	BG color.RGBA
-}
-
-var colors map[string]ColorPair
-
 func init() {
 	rotations = make([][]string, 0)
 	rotations = append(rotations, []string{"<", "^", ">", "v"})
 	rotations = append(rotations, []string{"\u2518", "\u2514", "\u250c", "\u2510"})
 	rotations = append(rotations, []string{"\u2500", "\u2502", "\u2500", "\u2502"})
 	rotations = append(rotations, []string{"\u251c", "\u252c", "\u2524", "\u2534"})
-
-	white := color.RGBA{150, 150, 150, 255}
-	black := color.RGBA{0, 0, 0, 255}
-	red := color.RGBA{255, 0, 0, 255}
-	green := color.RGBA{0, 110, 0, 255}
-	brown := color.RGBA{92, 51, 23, 255}
-	blue := color.RGBA{0, 0, 200, 255}
-	magenta := color.RGBA{139, 58, 98, 255}
-	cyan := color.RGBA{0, 150, 180, 255}
-	gray := color.RGBA{150, 150, 150, 255}
-	darkGray := color.RGBA{99, 99, 99, 255}
-	lightRed := color.RGBA{255, 150, 150, 255}
-	lightGreen := color.RGBA{0, 255, 0, 255}
-	yellow := color.RGBA{255, 255, 0, 255}
-	lightBlue := color.RGBA{100, 100, 255, 255}
-	lightMagenta := color.RGBA{254, 0, 254, 255}
-	lightCyan := color.RGBA{0, 240, 255, 255}
-
-	colors = make(map[string]ColorPair)
-
-	colors["black_yellow"] = ColorPair{FG: black, BG: yellow}
-	colors["blue_white"] = ColorPair{FG: blue, BG: white}
-	colors["blue"] = ColorPair{FG: blue, BG: black}
-	colors["brown_green"] = ColorPair{FG: brown, BG: green}
-	colors["brown"] = ColorPair{FG: brown, BG: black}
-	colors["c_blue_white"] = ColorPair{FG: blue, BG: white}
-	colors["c_light_cyan_magenta"] = ColorPair{FG: lightCyan, BG: magenta}
-	colors["c_red_white"] = ColorPair{FG: red, BG: white}
-	colors["c_yellow_green"] = ColorPair{FG: yellow, BG: green}
-	colors["c_yellow_white"] = ColorPair{FG: yellow, BG: white}
-	colors["cyan"] = ColorPair{FG: cyan, BG: black}
-	colors["dark_gray_magenta"] = ColorPair{FG: darkGray, BG: magenta}
-	colors["dark_gray"] = ColorPair{FG: darkGray, BG: black}
-	colors["green_cyan"] = ColorPair{FG: green, BG: cyan}
-	colors["green_yellow"] = ColorPair{FG: green, BG: yellow}
-	colors["green"] = ColorPair{FG: green, BG: black}
-	colors["h_dark_gray"] = ColorPair{FG: darkGray, BG: black}
-	colors["h_yellow"] = ColorPair{FG: yellow, BG: black}
-	colors["i_black"] = ColorPair{FG: black, BG: white}
-	colors["i_blue"] = ColorPair{FG: black, BG: blue}
-	colors["i_brown"] = ColorPair{FG: black, BG: brown}
-	colors["i_cyan"] = ColorPair{FG: black, BG: cyan}
-	colors["i_green"] = ColorPair{FG: black, BG: green}
-	colors["i_light_blue"] = ColorPair{FG: black, BG: lightBlue}
-	colors["i_light_cyan"] = ColorPair{FG: black, BG: lightCyan}
-	colors["i_light_gray"] = ColorPair{FG: black, BG: gray}
-	colors["i_light_green"] = ColorPair{FG: black, BG: lightGreen}
-	colors["i_light_red"] = ColorPair{FG: black, BG: lightRed}
-	colors["i_magenta"] = ColorPair{FG: black, BG: magenta}
-	colors["i_pink"] = ColorPair{FG: black, BG: lightMagenta}
-	colors["i_red"] = ColorPair{FG: black, BG: red}
-	colors["i_white"] = ColorPair{FG: white, BG: black}
-	colors["i_yellow"] = ColorPair{FG: black, BG: yellow}
-	colors["light_blue"] = ColorPair{FG: lightBlue, BG: black}
-	colors["light_cyan_magenta"] = ColorPair{FG: lightCyan, BG: magenta}
-	colors["light_cyan"] = ColorPair{FG: lightCyan, BG: black}
-	colors["light_gray"] = ColorPair{FG: gray, BG: black}
-	colors["light_green_cyan"] = ColorPair{FG: lightGreen, BG: cyan}
-	colors["light_green_green"] = ColorPair{FG: lightGreen, BG: green}
-	colors["light_green_red"] = ColorPair{FG: lightGreen, BG: red}
-	colors["light_green_yellow"] = ColorPair{FG: lightGreen, BG: yellow}
-	colors["light_green"] = ColorPair{FG: lightGreen, BG: black}
-	colors["light_red"] = ColorPair{FG: lightRed, BG: black}
-	colors["magenta"] = ColorPair{FG: magenta, BG: black}
-	colors["pink_magenta"] = ColorPair{FG: lightMagenta, BG: magenta}
-	colors["pink"] = ColorPair{FG: lightMagenta, BG: black}
-	colors["red_white"] = ColorPair{FG: red, BG: white}
-	colors["red"] = ColorPair{FG: red, BG: black}
-	colors["unset"] = ColorPair{FG: white, BG: black}
-	colors["white_magenta"] = ColorPair{FG: white, BG: magenta}
-	colors["white_white"] = ColorPair{FG: white, BG: white}
-	colors["white"] = ColorPair{FG: white, BG: black}
-	colors["yellow_cyan"] = ColorPair{FG: yellow, BG: cyan}
-	colors["yellow_green"] = ColorPair{FG: yellow, BG: green}
-	colors["yellow_magenta"] = ColorPair{FG: yellow, BG: magenta}
-	colors["yellow_white"] = ColorPair{FG: yellow, BG: white}
-	colors["yellow"] = ColorPair{FG: yellow, BG: black}
 }
 
 type Overmap struct {
-	built        map[string]overmapTerrain
-	landusecodes map[string]overmapLandUseCode
+	built             map[string]overmapTerrain
+	landusecodes      map[string]overmapLandUseCode
+	baseColors        map[string]color.RGBA
+	terrainColors     map[string]string
+	landUseCodeColors map[string]string
+	hash              string
 }
 
-func (o Overmap) UID(id string) uint32 {
-	if t, tok := o.built[id]; tok {
-		return t.internalID
+// Hash identifies the symbology the metadata gives terrain, so output drawn
+// with it can tell when the game data or mods have changed underneath it.
+func (o Overmap) Hash() string {
+	return o.hash
+}
+
+func hashSymbology(built map[string]overmapTerrain, landusecodes map[string]overmapLandUseCode, baseColors map[string]color.RGBA) string {
+	h := sha1.New()
+
+	for _, name := range baseColorNames {
+		fmt.Fprintf(h, "%v %v\n", name, baseColors[name])
+	}
+
+	ids := make([]string, 0, len(built))
+	for id := range built {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		t := built[id]
+		fmt.Fprintf(h, "%q %q %q %q %q\n", id, t.Name, t.Sym, t.Color, t.LandUseCode)
 	}
-	return 0
+
+	codes := make([]string, 0, len(landusecodes))
+	for id := range landusecodes {
+		codes = append(codes, id)
+	}
+	sort.Strings(codes)
+	for _, id := range codes {
+		l := landusecodes[id]
+		fmt.Fprintf(h, "%q %q %q\n", id, l.Sym, l.Color)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
 func (o Overmap) Exists(id string) bool {
@@ -240,6 +189,21 @@ func (o Overmap) Exists(id string) bool {
 	return ok
 }
 
+func (o Overmap) Name(id string) string {
+	if t, tok := o.built[id]; tok {
+		return string(t.Name)
+	}
+	return ""
+}
+
+// LandUseCode returns the land use code of an overmap terrain, if it has one.
+func (o Overmap) LandUseCode(id string) string {
+	if t, tok := o.built[id]; tok {
+		return t.LandUseCode
+	}
+	return ""
+}
+
 func (o Overmap) Symbol(id string, landUseCode bool) string {
 	if t, tok := o.built[id]; tok {
 		if !landUseCode {
@@ -252,26 +216,52 @@ func (o Overmap) Symbol(id string, landUseCode bool) string {
 	return "?"
 }
 
+// Color returns the colors of an overmap terrain, or of its land use code,
+// with any the theme gives it in place of the game data's.
 func (o Overmap) Color(id string, landUseCode bool) (color.RGBA, color.RGBA) {
 	if c, tok := o.built[id]; tok {
 		if !landUseCode {
-			if cp, ok := colors[c.Color]; ok {
+			name := c.Color
+			if n, ok := o.terrainColors[id]; ok {
+				name = n
+			} else if n, ok := o.terrainColors[terrain.BaseID(id)]; ok {
+				name = n
+			}
+			if cp, ok := o.colorPair(name); ok {
 				return cp.FG, cp.BG
 			}
-			fmt.Printf("missing terrain color for: %#v from %v\n", c.Color, id)
+			warnMissingColor("terrain", id, name)
 		}
 		if luc, lucok := o.landusecodes[c.LandUseCode]; lucok {
-			if cp, ok := colors[luc.Color]; ok {
+			name := luc.Color
+			if n, ok := o.landUseCodeColors[c.LandUseCode]; ok {
+				name = n
+			}
+			if cp, ok := o.colorPair(name); ok {
 				return cp.FG, cp.BG
 			}
-			fmt.Printf("missing landusecode color for: %#v\n", luc.Color)
+			warnMissingColor("land use code", c.LandUseCode, name)
 		}
 	}
 
-	unset := colors["unset"]
+	unset, _ := o.colorPair("unset")
 	return unset.FG, unset.BG
 }
 
+// missingColors holds the terrain and land use codes already warned about,
+// as Color is called for every cell drawn.
+var missingColors sync.Map
+
+func warnMissingColor(kind, id, name string) {
+	if _, seen := missingColors.LoadOrStore(kind+" "+id, true); seen {
+		return
+	}
+	log.WithFields(log.Fields{
+		kind:    id,
+		"color": name,
+	}).Warn("missing color, drawing it unset")
+}
+
 func Build(save save.Save, gameRoot string) (Overmap, error) {
 	o := Overmap{}
 
@@ -296,9 +286,16 @@ func Build(save save.Save, gameRoot string) (Overmap, error) {
 		return o, err
 	}
 
+	baseColors, err := loadBaseColors(gameRoot)
+	if err != nil {
+		return o, err
+	}
+
 	o = Overmap{
 		built:        built,
 		landusecodes: landusecodes,
+		baseColors:   baseColors,
+		hash:         hashSymbology(built, landusecodes, baseColors),
 	}
 
 	return o, nil
@@ -495,7 +492,6 @@ func buildTemplates(templates map[string]overmapTerrain) (map[string]overmapTerr
 		bt = append(bt, t)
 		for t.CopyFrom != "" {
 			t = templates[t.CopyFrom]
-			t.internalID = save.HashTerrainID(t.ID)
 			bt = append(bt, t)
 		}
 
@@ -509,7 +505,6 @@ func buildTemplates(templates map[string]overmapTerrain) (map[string]overmapTerr
 		if ot.Abstract == "" {
 			b.Abstract = ""
 			b.CopyFrom = ""
-			b.internalID = save.HashTerrainID(b.ID)
 			built[b.ID] = b
 
 			rotate := true
@@ -531,27 +526,25 @@ func buildTemplates(templates map[string]overmapTerrain) (map[string]overmapTerr
 				}
 
 				if _, ok := flagsMap["LINEAR"]; ok {
-					for _, suffix := range linearSuffixes {
+					for _, suffix := range terrain.LinearSuffixes {
 						bs := overmapTerrain{}
 						if err := mergo.Merge(&bs, b, mergo.WithOverride); err != nil {
 							return built, err
 						}
 						bs.ID = b.ID + suffix
 						bs.Sym = linearSuffixSymbols[suffix]
-						b.internalID = save.HashTerrainID(b.ID)
 						built[bs.ID] = bs
 					}
 				}
 			}
 
 			if rotate {
-				for i, suffix := range rotationSuffixes {
+				for i, suffix := range terrain.RotationSuffixes {
 					bs := overmapTerrain{}
 					if err := mergo.Merge(&bs, b, mergo.WithOverride); err != nil {
 						log.Fatal(err)
 					}
 					bs.ID = b.ID + suffix
-					b.internalID = save.HashTerrainID(b.ID)
 
 					for _, r := range rotations {
 						index := indexOf(r, b.Sym)
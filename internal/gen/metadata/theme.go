@@ -0,0 +1,169 @@
+package metadata
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Theme recolors a map. Colors replaces any of the 16 base colors, by the
+// names the game's colors.json gives them, with an [r, g, b]. Terrain and
+// LandUseCodes give specific terrain ids, or the base id of rotated and
+// linear terrain, and land use codes a color name such as "light_green" or
+// "i_yellow" in place of the one in the game data.
+type Theme struct {
+	Colors       map[string][3]int `json:"colors"`
+	Terrain      map[string]string `json:"terrain"`
+	LandUseCodes map[string]string `json:"land_use_codes"`
+}
+
+// builtinThemes can be chosen by name. Classic puts back the base colors
+// maps have always been drawn with, and the colorblind themes use the
+// Okabe-Ito palette and Paul Tol's bright and light palettes, which stay
+// distinguishable under the common forms of color blindness.
+var builtinThemes = map[string]Theme{
+	"classic": {
+		Colors: themeColors(classicColors),
+	},
+	"colorblind": {
+		Colors: map[string][3]int{
+			"BLACK":    {0, 0, 0},
+			"RED":      {213, 94, 0},
+			"GREEN":    {0, 158, 115},
+			"BROWN":    {160, 100, 0},
+			"BLUE":     {0, 114, 178},
+			"MAGENTA":  {204, 121, 167},
+			"CYAN":     {86, 180, 233},
+			"GRAY":     {150, 150, 150},
+			"DGRAY":    {99, 99, 99},
+			"LRED":     {230, 159, 0},
+			"LGREEN":   {102, 204, 170},
+			"YELLOW":   {240, 228, 66},
+			"LBLUE":    {140, 200, 240},
+			"LMAGENTA": {230, 170, 205},
+			"LCYAN":    {170, 220, 245},
+			"WHITE":    {255, 255, 255},
+		},
+	},
+	"colorblind-bright": {
+		Colors: map[string][3]int{
+			"BLACK":    {0, 0, 0},
+			"RED":      {238, 102, 119},
+			"GREEN":    {34, 136, 51},
+			"BROWN":    {170, 170, 0},
+			"BLUE":     {68, 119, 170},
+			"MAGENTA":  {170, 51, 119},
+			"CYAN":     {102, 204, 238},
+			"GRAY":     {187, 187, 187},
+			"DGRAY":    {102, 102, 102},
+			"LRED":     {255, 170, 187},
+			"LGREEN":   {187, 204, 51},
+			"YELLOW":   {238, 221, 136},
+			"LBLUE":    {119, 170, 221},
+			"LMAGENTA": {238, 136, 102},
+			"LCYAN":    {153, 221, 255},
+			"WHITE":    {221, 221, 221},
+		},
+	},
+}
+
+// ThemeNames lists the built-in themes, along with "game", which takes the
+// base colors from the game's own data/raw/colors.json and the player's
+// config/base_colors.json.
+func ThemeNames() []string {
+	names := []string{"game"}
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTheme returns the named built-in theme, or reads a theme file from
+// the path given.
+func LoadTheme(name, gameRoot string) (Theme, error) {
+	if t, ok := builtinThemes[name]; ok {
+		return t, nil
+	}
+	if name == "game" {
+		return loadGameColors(gameRoot)
+	}
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme %v is neither one of %v nor a readable file: %v", name, strings.Join(ThemeNames(), ", "), err)
+	}
+
+	var t Theme
+	if err := json.Unmarshal(b, &t); err != nil {
+		return Theme{}, fmt.Errorf("theme %v: %v", name, err)
+	}
+	return t, nil
+}
+
+// loadGameColors makes a theme of the base colors the game has, so a map
+// drawn with it looks as the game does.
+func loadGameColors(gameRoot string) (Theme, error) {
+	base, err := loadBaseColors(gameRoot)
+	if err != nil {
+		return Theme{}, err
+	}
+	return Theme{Colors: themeColors(base)}, nil
+}
+
+// WithTheme returns the metadata recolored by t, on top of any theme it
+// already has.
+func (o Overmap) WithTheme(t Theme) (Overmap, error) {
+	base := copyBaseColors(o.baseColors)
+	for name, rgb := range t.Colors {
+		c, err := rgbColor(name, rgb)
+		if err != nil {
+			return o, fmt.Errorf("theme: %v", err)
+		}
+		base[name] = c
+	}
+
+	terrain, err := mergeThemeColors(o.terrainColors, t.Terrain)
+	if err != nil {
+		return o, err
+	}
+	landUseCodes, err := mergeThemeColors(o.landUseCodeColors, t.LandUseCodes)
+	if err != nil {
+		return o, err
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%v\n%v\n%v\n%v\n", o.hash, base, terrain, landUseCodes)
+
+	o.baseColors = base
+	o.terrainColors = terrain
+	o.landUseCodeColors = landUseCodes
+	o.hash = fmt.Sprintf("%x", h.Sum(nil))
+	return o, nil
+}
+
+func mergeThemeColors(current, overrides map[string]string) (map[string]string, error) {
+	merged := make(map[string]string, len(current)+len(overrides))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		if _, _, ok := resolveColor(v); !ok {
+			return nil, fmt.Errorf("theme: %v has unknown color %v", k, v)
+		}
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+func themeColors(base map[string]color.RGBA) map[string][3]int {
+	c := make(map[string][3]int, len(base))
+	for name, rgb := range base {
+		c[name] = [3]int{int(rgb.R), int(rgb.G), int(rgb.B)}
+	}
+	return c
+}
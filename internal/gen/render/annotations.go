@@ -0,0 +1,95 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/golang/freetype"
+	"github.com/jmoiron/sqlx"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+)
+
+// Annotation is a player placed marker, positioned by game coordinates
+// with z relative to ground level.
+type Annotation struct {
+	OMX  int    `db:"om_x"`
+	OMY  int    `db:"om_y"`
+	X    int    `db:"x"`
+	Y    int    `db:"y"`
+	Z    int    `db:"z"`
+	Text string `db:"text"`
+}
+
+// LoadAnnotations reads the annotations players have placed on the named
+// world from the map database.
+func LoadAnnotations(connectionString, worldName string) ([]Annotation, error) {
+	db, err := sqlx.Open("postgres", connectionString)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	annotations := []Annotation{}
+	err = db.Select(&annotations, `
+		select
+			a.om_x,
+			a.om_y,
+			a.x,
+			a.y,
+			a.z,
+			a.text
+		from
+			annotation a
+			inner join world w
+				on a.world_id = w.world_id
+		where
+			w.name = $1
+		order by
+			a.annotation_id
+	`, worldName)
+	if err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+// annotationsToImage outlines each annotated cell on the layer and labels
+// it with the annotation's text.
+func annotationsToImage(fullImage *image.RGBA, c *freetype.Context, w world.World, layerID int, annotations []Annotation) {
+	marker := image.NewUniform(color.RGBA{255, 0, 255, 255})
+	bg := image.NewUniform(color.RGBA{0, 0, 0, 255})
+	fg := image.NewUniform(color.RGBA{255, 255, 255, 255})
+
+	rows := w.Rows()
+	columns := w.Columns()
+
+	for _, a := range annotations {
+		if a.Z+10 != layerID {
+			continue
+		}
+
+		column := (a.OMX-w.Extent.XMin)*180 + a.X
+		row := (a.OMY-w.Extent.YMin)*180 + a.Y
+		if column < 0 || column >= columns || row < 0 || row >= rows {
+			continue
+		}
+
+		x0 := column * cellOverprintWidth
+		y0 := row * cellHeight
+		cell := image.Rect(x0, y0, x0+cellOverprintWidth, y0+cellHeight)
+		for _, edge := range []image.Rectangle{
+			image.Rect(cell.Min.X, cell.Min.Y, cell.Max.X, cell.Min.Y+2),
+			image.Rect(cell.Min.X, cell.Max.Y-2, cell.Max.X, cell.Max.Y),
+			image.Rect(cell.Min.X, cell.Min.Y, cell.Min.X+2, cell.Max.Y),
+			image.Rect(cell.Max.X-2, cell.Min.Y, cell.Max.X, cell.Max.Y),
+		} {
+			draw.Draw(fullImage, edge, marker, image.ZP, draw.Src)
+		}
+
+		label := image.Rect(cell.Max.X, y0, cell.Max.X+len(a.Text)*cellOverprintWidth, y0+cellHeight)
+		draw.Draw(fullImage, label, bg, image.ZP, draw.Src)
+		c.SetSrc(fg)
+		drawLabel(c, a.Text, freetype.Pt(label.Min.X, label.Max.Y))
+	}
+}
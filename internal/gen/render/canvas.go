@@ -0,0 +1,140 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/freetype"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/tile"
+)
+
+// stripRows is how many rows of cells a world sized image is drawn in at a
+// time when it's written whole.
+const stripRows = 16
+
+// drawFunc draws the part of an image inside cells, a rectangle of columns
+// and rows, at its place in img.
+type drawFunc func(img *image.RGBA, c *freetype.Context, cells image.Rectangle)
+
+// canvas is a buffer that pieces of a world's images are drawn in one after
+// another, so drawing a world never needs more than a piece's worth of
+// memory however spread out its overmaps are.
+type canvas struct {
+	pix []uint8
+	c   *freetype.Context
+}
+
+// draw draws cells with f and returns their pixels. A cell's symbol can spill
+// into the cells beside it, so a margin of one cell around them is drawn too,
+// in the same order as drawing the whole world would, which keeps the pixels
+// of cells the same as if it had been. The image is only good until the next
+// call.
+func (cv *canvas) draw(w world.World, cells image.Rectangle, f drawFunc) *image.RGBA {
+	margin := cells.Inset(-1).Intersect(worldCells(w))
+	r := cellPixels(margin)
+
+	n := 4 * r.Dx() * r.Dy()
+	if cap(cv.pix) < n {
+		cv.pix = make([]uint8, n)
+	}
+	img := &image.RGBA{Pix: cv.pix[:n], Stride: 4 * r.Dx(), Rect: r}
+	draw.Draw(img, r, image.Transparent, image.ZP, draw.Src)
+
+	if cv.c == nil {
+		cv.c = newContext(img)
+	} else {
+		cv.c.SetDst(img)
+		cv.c.SetClip(r)
+	}
+
+	f(img, cv.c, margin)
+	return img.SubImage(cellPixels(cells)).(*image.RGBA)
+}
+
+// stripImage is a world sized image that's drawn a strip of rows at a time
+// as it's read from the top down, the way the PNG encoder reads, so writing
+// it only holds one strip in memory.
+type stripImage struct {
+	w     world.World
+	cv    *canvas
+	f     drawFunc
+	strip *image.RGBA
+}
+
+func newStripImage(w world.World, cv *canvas, f drawFunc) *stripImage {
+	return &stripImage{w: w, cv: cv, f: f}
+}
+
+func (s *stripImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (s *stripImage) Bounds() image.Rectangle {
+	return cellPixels(worldCells(s.w))
+}
+
+// Opaque keeps the PNG encoder from reading the whole image to find out.
+func (s *stripImage) Opaque() bool {
+	return false
+}
+
+func (s *stripImage) At(x, y int) color.Color {
+	p := image.Pt(x, y)
+	if !p.In(s.Bounds()) {
+		return color.RGBA{}
+	}
+	if s.strip == nil || !p.In(s.strip.Rect) {
+		row := y / cellHeight / stripRows * stripRows
+		cells := image.Rect(0, row, s.w.Columns(), row+stripRows).Intersect(worldCells(s.w))
+		s.strip = s.cv.draw(s.w, cells, s.f)
+	}
+	return s.strip.RGBAAt(x, y)
+}
+
+// tileOvermaps draws the overmaps at points one at a time straight into a
+// new tile pyramid for filename, replacing any there was. Nothing is drawn
+// where there are no overmaps, so no tiles are cut there.
+func tileOvermaps(filename string, w world.World, points []world.Point, cv *canvas, f drawFunc) error {
+	if err := os.RemoveAll(strings.TrimSuffix(filename, filepath.Ext(filename)) + "_tiles"); err != nil {
+		return err
+	}
+	return patchOvermaps(tile.NewPyramid(filename, cellPixels(worldCells(w)).Size()), w, points, cv, f)
+}
+
+// patchOvermaps draws the overmaps at points one at a time into pyramid p.
+func patchOvermaps(p *tile.Pyramid, w world.World, points []world.Point, cv *canvas, f drawFunc) error {
+	for _, pt := range points {
+		if err := p.Draw(cv.draw(w, w.Extent.Rect(pt), f)); err != nil {
+			return err
+		}
+	}
+	return p.Close()
+}
+
+// presentOvermaps returns the overmaps the world has, a row at a time from
+// the top left as drawing the whole world visits them. They're the only
+// ones tiled, as everything else is blank.
+func presentOvermaps(w world.World) []world.Point {
+	points := make([]world.Point, 0, len(w.TerrainLayers.Chunks))
+	for p := range w.TerrainLayers.Chunks {
+		points = append(points, p)
+	}
+	return sortedPoints(points)
+}
+
+// sortedPoints sorts overmaps a row at a time from the top left.
+func sortedPoints(points []world.Point) []world.Point {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Y != points[j].Y {
+			return points[i].Y < points[j].Y
+		}
+		return points[i].X < points[j].X
+	})
+	return points
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/freetype"
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/pixel"
+)
+
+// testWorld is two overmaps side by side, with city labels crossing the
+// boundary between them and the boundary between the first two strips.
+func testWorld(t *testing.T) world.World {
+	chunk := func(x int, city save.City) save.OvermapChunk {
+		c := save.OvermapChunk{X: x, Y: 0, Cities: []save.City{city}}
+		for i := 0; i < 21; i++ {
+			c.Layers = append(c.Layers, []save.TerrainGroup{{OvermapTerrainID: "open_air", Count: 32400}})
+		}
+		return c
+	}
+
+	w, err := world.Build(metadata.Overmap{}, save.Save{
+		Name: "Spenard",
+		Overmap: save.Overmap{Chunks: []save.OvermapChunk{
+			chunk(0, save.City{Name: "Anchorage", X: 178, Y: stripRows - 1, Size: 3}),
+			chunk(1, save.City{Name: "Muldoon", X: 2, Y: stripRows, Size: 2}),
+		}},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func testDrawFuncs(w world.World) map[string]drawFunc {
+	lines := gridLines(w, 4)
+	return map[string]drawFunc{
+		"cities": func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+			drawCities(img, c, w, cells)
+		},
+		"grid": func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+			drawGrid(img, c, w, lines)
+		},
+	}
+}
+
+func TestStripImage(t *testing.T) {
+	w := testWorld(t)
+	full := image.NewRGBA(cellPixels(worldCells(w)))
+
+	for name, f := range testDrawFuncs(w) {
+		draw.Draw(full, full.Rect, image.Transparent, image.ZP, draw.Src)
+		f(full, newContext(full), worldCells(w))
+
+		s := newStripImage(w, &canvas{}, f)
+		if s.Bounds() != full.Bounds() {
+			t.Fatalf("%v: expected bounds %v, got %v", name, full.Bounds(), s.Bounds())
+		}
+		diff := 0
+		for y := full.Rect.Min.Y; y < full.Rect.Max.Y; y++ {
+			for x := full.Rect.Min.X; x < full.Rect.Max.X; x++ {
+				if s.At(x, y) != full.RGBAAt(x, y) {
+					diff++
+				}
+			}
+		}
+		if diff > 0 {
+			t.Errorf("%v: %v pixels differ from drawing the whole world at once", name, diff)
+		}
+	}
+}
+
+func TestTileOvermaps(t *testing.T) {
+	w := testWorld(t)
+	full := image.NewRGBA(cellPixels(worldCells(w)))
+	f := testDrawFuncs(w)["cities"]
+	f(full, newContext(full), worldCells(w))
+
+	dir, err := ioutil.TempDir("", "cddamap-render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, citiesImageName(""))
+	stale := filepath.Join(dir, "cities_tiles", "9", "0", "0.png")
+	if err := os.MkdirAll(filepath.Dir(stale), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(stale, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tileOvermaps(filename, w, presentOvermaps(w), &canvas{}, f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("expected the old pyramid to be replaced")
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Error("expected no full size image")
+	}
+
+	z := pixel.MaxZoom(full.Rect.Dx(), full.Rect.Dy())
+	for x := 0; x*pixel.TileSize < full.Rect.Dx(); x++ {
+		for y := 0; y*pixel.TileSize < full.Rect.Dy(); y++ {
+			tile := readPNG(t, filepath.Join(dir, "cities_tiles", fmt.Sprint(z), fmt.Sprint(x), fmt.Sprintf("%v.png", y)))
+			origin := image.Pt(x*pixel.TileSize, y*pixel.TileSize)
+			for ty := 0; ty < pixel.TileSize; ty++ {
+				for tx := 0; tx < pixel.TileSize; tx++ {
+					want := full.RGBAAt(origin.X+tx, origin.Y+ty)
+					if r, g, b, a := tile.At(tx, ty).RGBA(); r>>8 != uint32(want.R) || g>>8 != uint32(want.G) || b>>8 != uint32(want.B) || a>>8 != uint32(want.A) {
+						t.Fatalf("tile %v,%v: pixel %v,%v differs from drawing the whole world at once", x, y, tx, ty)
+					}
+				}
+			}
+		}
+	}
+	readPNG(t, filepath.Join(dir, "cities_tiles", "0", "0", "0.png"))
+}
+
+func readPNG(t *testing.T, filename string) image.Image {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/freetype"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/tile"
+)
+
+// ImageChunks redraws the changed chunks of each layer straight into the
+// tile pyramids that Image wrote under the output root, so only the tiles
+// those chunks cover are rendered again. Layers without a pyramid yet, such
+// as ones skipped while empty, are rendered and tiled in full. Untiled
+// images are rendered again in full.
+func ImageChunks(w world.World, o Options, chunks []world.Chunk) error {
+	if !o.Tile {
+		return Image(w, o)
+	}
+	if len(o.Layers) == 0 || len(chunks) == 0 {
+		return nil
+	}
+
+	terrain := []world.Point{}
+	seen := make(map[string][]world.Point)
+	for _, ch := range chunks {
+		p := world.Point{X: ch.X, Y: ch.Y}
+		if ch.Character == "" {
+			terrain = append(terrain, p)
+		} else if _, ok := w.SeenLayers[ch.Character]; ok {
+			seen[ch.Character] = append(seen[ch.Character], p)
+		}
+	}
+	sortedPoints(terrain)
+	for _, points := range seen {
+		sortedPoints(points)
+	}
+
+	imageSize := cellPixels(worldCells(w)).Size()
+	cv := &canvas{}
+	missing := make(map[int]bool)
+	missingCities := false
+
+	patch := func(name string, points []world.Point, f drawFunc) (bool, error) {
+		if len(points) == 0 {
+			return true, nil
+		}
+		filename := filepath.Join(o.OutputRoot, name)
+		if _, err := os.Stat(strings.TrimSuffix(filename, ".png") + "_tiles"); os.IsNotExist(err) {
+			return false, nil
+		}
+		return true, patchOvermaps(tile.NewPyramid(filename, imageSize), w, points, cv, f)
+	}
+
+	for _, layerID := range o.Layers {
+		layerID := layerID
+		if o.Terrain && !(w.TerrainLayers.Empty[layerID] && o.SkipEmpty) {
+			ok, err := patch(terrainImageName(o.OvermapFilter, layerID), terrain, func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+				drawTerrain(img, c, w, layerID, cells, o.Annotations)
+			})
+			if err != nil {
+				return err
+			}
+			missing[layerID] = missing[layerID] || !ok
+		}
+
+		for name, points := range seen {
+			if w.SeenLayers[name].Empty[layerID] && o.SkipEmpty {
+				continue
+			}
+			for _, solid := range []bool{false, true} {
+				if (solid && !o.SeenSolid) || (!solid && !o.Seen) {
+					continue
+				}
+				name, solid := name, solid
+				ok, err := patch(seenImageName(name, o.OvermapFilter, layerID, solid), points, func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+					drawSeen(img, c, w, name, layerID, cells, solid)
+				})
+				if err != nil {
+					return err
+				}
+				missing[layerID] = missing[layerID] || !ok
+			}
+		}
+	}
+
+	if o.Cities {
+		ok, err := patch(citiesImageName(o.OvermapFilter), terrain, func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+			drawCities(img, c, w, cells)
+		})
+		if err != nil {
+			return err
+		}
+		missingCities = !ok
+	}
+
+	return renderMissing(w, o, missing, missingCities)
+}
+
+// renderMissing renders and tiles the layers ImageChunks found no pyramid
+// for.
+func renderMissing(w world.World, o Options, missing map[int]bool, missingCities bool) error {
+	layers := []int{}
+	for layerID, m := range missing {
+		if m {
+			layers = append(layers, layerID)
+		}
+	}
+	if len(layers) == 0 && !missingCities {
+		return nil
+	}
+	sort.Ints(layers)
+	return images(w, o, layers, missingCities)
+}
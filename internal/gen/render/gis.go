@@ -2,105 +2,65 @@ package render
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"math"
+	"image"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
-	"github.com/ralreegorganon/cddamap/internal/gen/save"
 	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/notify"
+	"github.com/ralreegorganon/cddamap/internal/pixel"
 )
 
-func GIS(w world.World, connectionString string, includeLayers []int, terrain, seen, seenSolid, skipEmpty, cities bool) error {
-	tl := w.TerrainLayers[includeLayers[0]]
-	width := int(cellWidth * float64(len(tl.TerrainRows[0].TerrainCellKeys)))
-	height := cellHeight * len(tl.TerrainRows)
-
-	tileXCount := int(math.Ceil(float64(width) / float64(tileSize)))
-	tileYCount := int(math.Ceil(float64(height) / float64(tileSize)))
+func init() {
+	Register("gis", chunkRenderer{render: GIS, renderChunks: GISChunks})
+}
 
-	maxz := nativeZoom(tileXCount, tileYCount)
+func GIS(w world.World, o Options) error {
+	maxz := pixel.WorldMaxZoom(w.Columns(), w.Rows())
 
-	db, err := sqlx.Open("postgres", connectionString)
+	db, err := sqlx.Open("postgres", o.ConnectionString)
 	if err != nil {
 		return err
 	}
 
 	var worldID int
-	err = db.QueryRow("insert into world (name, maxz) values ($1, $2) on conflict(name) do update set maxz = EXCLUDED.maxz returning world_id", w.Name, maxz).Scan(&worldID)
+	err = db.QueryRow(`
+		insert into world (name, maxz, om_xmin, om_ymin, om_xsize, om_ysize) values ($1, $2, $3, $4, $5, $6)
+		on conflict(name) do update set maxz = EXCLUDED.maxz, om_xmin = EXCLUDED.om_xmin, om_ymin = EXCLUDED.om_ymin, om_xsize = EXCLUDED.om_xsize, om_ysize = EXCLUDED.om_ysize
+		returning world_id`, w.Name, maxz, w.Extent.XMin, w.Extent.YMin, w.Extent.XSize, w.Extent.YSize).Scan(&worldID)
 	if err != nil {
 		return err
 	}
 
-	emptyRockHash := save.HashTerrainID("empty_rock")
-	openAirHash := save.HashTerrainID("open_air")
-	blankHash := save.HashTerrainID("")
-
-	for _, i := range includeLayers {
-		if seen || seenSolid {
-			for name, layers := range w.SeenLayers {
-				l := layers[i]
+	updatedLayerIDs := make([]int, 0)
 
-				if l.Empty && skipEmpty {
+	for _, i := range o.Layers {
+		if o.Seen || o.SeenSolid {
+			for _, name := range w.Characters() {
+				if w.SeenLayers[name].Empty[i] && o.SkipEmpty {
 					continue
 				}
 
-				var characterID int
-				err = db.QueryRow("select character_id from character where world_id = $1 and namehash = $2", worldID, name).Scan(&characterID)
-				if err == sql.ErrNoRows {
-					err = db.QueryRow("insert into character (world_id, namehash, name) values ($1, $2, $2) returning character_id", worldID, name).Scan(&characterID)
-					if err != nil {
-						return err
-					}
-				} else if err != nil {
+				ids, err := seenLayerIDs(db, worldID, i, name, o.Seen, o.SeenSolid)
+				if err != nil {
 					return err
 				}
-
-				if seen {
-					var layerID int
-					err = db.QueryRow("select layer_id from layer where world_id = $1 and z = $2 and character_id = $3 and type = 'seen'", worldID, i, characterID).Scan(&layerID)
-					if err == sql.ErrNoRows {
-						err = db.QueryRow("insert into layer (world_id, z, character_id, type) values ($1, $2, $3, 'seen') returning layer_id", worldID, i, characterID).Scan(&layerID)
-						if err != nil {
-							return err
-						}
-					} else if err != nil {
-						return err
-					}
-				}
-				if seenSolid {
-					var layerID int
-					err = db.QueryRow("select layer_id from layer where world_id = $1 and z = $2 and character_id = $3 and type = 'seen_solid'", worldID, i, characterID).Scan(&layerID)
-					if err == sql.ErrNoRows {
-						err = db.QueryRow("insert into layer (world_id, z, character_id, type) values ($1, $2, $3, 'seen_solid') returning layer_id", worldID, i, characterID).Scan(&layerID)
-						if err != nil {
-							return err
-						}
-					} else if err != nil {
-						return err
-					}
-				}
-
+				updatedLayerIDs = append(updatedLayerIDs, ids...)
 			}
 		}
 
-		if terrain {
-			l := w.TerrainLayers[i]
-
-			if l.Empty && skipEmpty {
+		if o.Terrain {
+			if w.TerrainLayers.Empty[i] && o.SkipEmpty {
 				continue
 			}
 
-			var layerID int
-			err = db.QueryRow("select layer_id from layer where world_id = $1 and z = $2 and type = 'overmap'", worldID, i).Scan(&layerID)
-			if err == sql.ErrNoRows {
-				err = db.QueryRow("insert into layer (world_id, z, type) values ($1, $2, 'overmap') returning layer_id", worldID, i).Scan(&layerID)
-				if err != nil {
-					return err
-				}
-			} else if err != nil {
+			layerID, err := layerIDFor(db, worldID, i, "overmap")
+			if err != nil {
 				return err
 			}
+			updatedLayerIDs = append(updatedLayerIDs, layerID)
 
 			txn, err := db.Begin()
 			if err != nil {
@@ -117,111 +77,285 @@ func GIS(w world.World, connectionString string, includeLayers []int, terrain, s
 				return err
 			}
 
-			stmt, err := txn.Prepare(pq.CopyIn("cell", "layer_id", "id", "name", "the_geom"))
+			err = copyCells(txn, w, layerID, i, image.Rect(0, 0, w.Columns(), w.Rows()))
+			if err != nil {
+				return err
+			}
+
+			err = txn.Commit()
 			if err != nil {
 				return err
 			}
+		}
+	}
+
+	if o.Cities {
+		layerID, err := copyCities(db, w, worldID)
+		if err != nil {
+			return err
+		}
+		updatedLayerIDs = append(updatedLayerIDs, layerID)
+	}
+
+	return notifyWorldUpdated(db, worldID, updatedLayerIDs)
+}
+
+// GISChunks updates a world GIS has already imported with the chunks that
+// changed since: the cells of each changed overmap are replaced, and the
+// cities too if any terrain changed. Listeners are notified of every layer
+// touched, including the seen layers of characters whose chunks changed.
+func GISChunks(w world.World, o Options, chunks []world.Chunk) error {
+	db, err := sqlx.Open("postgres", o.ConnectionString)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var worldID int
+	err = db.QueryRow("select world_id from world where name = $1", w.Name).Scan(&worldID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("world %v hasn't been imported", w.Name)
+	} else if err != nil {
+		return err
+	}
 
-			for ri, r := range l.TerrainRows {
-				for ci, k := range r.TerrainCellKeys {
-					if k == emptyRockHash || k == openAirHash || k == blankHash {
-						continue
-					}
+	overmaps := []world.Chunk{}
+	characters := make(map[string]bool)
+	for _, ch := range chunks {
+		if ch.Character == "" {
+			overmaps = append(overmaps, ch)
+		} else {
+			characters[ch.Character] = true
+		}
+	}
 
-					x := float64(ci) * cellWidth
-					y := float64(ri) * float64(cellHeight)
-					x2 := x + cellWidth
-					y2 := y + float64(cellHeight)
+	updatedLayerIDs := make([]int, 0)
 
-					c := w.TerrainCellLookup[k]
+	for _, i := range o.Layers {
+		if o.Seen || o.SeenSolid {
+			for name := range characters {
+				layers, ok := w.SeenLayers[name]
+				if !ok || (layers.Empty[i] && o.SkipEmpty) {
+					continue
+				}
 
-					geom := fmt.Sprintf("POLYGON((%[1]f %[2]f, %[3]f %[4]f, %[5]f %[6]f, %[7]f %[8]f, %[1]f %[2]f))", x, y, x2, y, x2, y2, x, y2)
-					_, err = stmt.Exec(layerID, c.ID, c.Name, geom)
-					if err != nil {
-						return err
-					}
+				ids, err := seenLayerIDs(db, worldID, i, name, o.Seen, o.SeenSolid)
+				if err != nil {
+					return err
 				}
+				updatedLayerIDs = append(updatedLayerIDs, ids...)
 			}
-			_, err = stmt.Exec()
+		}
+
+		if o.Terrain && len(overmaps) > 0 {
+			if w.TerrainLayers.Empty[i] && o.SkipEmpty {
+				continue
+			}
+
+			layerID, err := layerIDFor(db, worldID, i, "overmap")
 			if err != nil {
 				return err
 			}
+			updatedLayerIDs = append(updatedLayerIDs, layerID)
 
-			err = stmt.Close()
+			txn, err := db.Begin()
 			if err != nil {
 				return err
 			}
 
-			err = txn.Commit()
+			nukeCellsStmt, err := txn.Prepare("delete from cell where layer_id = $1 and om_x = $2 and om_y = $3")
 			if err != nil {
+				txn.Rollback()
 				return err
 			}
-		}
-	}
 
-	if cities {
-		var layerID int
-		err = db.QueryRow("select layer_id from layer where world_id = $1 and z = $2 and type = 'city'", worldID, 10).Scan(&layerID)
-		if err == sql.ErrNoRows {
-			err = db.QueryRow("insert into layer (world_id, z, type) values ($1, $2, 'city') returning layer_id", worldID, 10).Scan(&layerID)
+			for _, ch := range overmaps {
+				if _, err := nukeCellsStmt.Exec(layerID, ch.X, ch.Y); err != nil {
+					txn.Rollback()
+					return err
+				}
+			}
+
+			for _, ch := range overmaps {
+				if err := copyCells(txn, w, layerID, i, w.Extent.Rect(world.Point{X: ch.X, Y: ch.Y})); err != nil {
+					txn.Rollback()
+					return err
+				}
+			}
+
+			err = txn.Commit()
 			if err != nil {
 				return err
 			}
-		} else if err != nil {
-			return err
 		}
+	}
 
-		txn, err := db.Begin()
+	if o.Cities && len(overmaps) > 0 {
+		layerID, err := copyCities(db, w, worldID)
 		if err != nil {
 			return err
 		}
+		updatedLayerIDs = append(updatedLayerIDs, layerID)
+	}
 
-		nukeCitiesStmt, err := txn.Prepare("delete from city where world_id = $1")
-		if err != nil {
-			return err
-		}
+	return notifyWorldUpdated(db, worldID, updatedLayerIDs)
+}
 
-		_, err = nukeCitiesStmt.Exec(worldID)
-		if err != nil {
-			return err
-		}
+// layerIDFor returns the ID of a world's terrain or city layer, creating it
+// if it doesn't exist yet.
+func layerIDFor(db *sqlx.DB, worldID, z int, layerType string) (int, error) {
+	var layerID int
+	err := db.QueryRow("select layer_id from layer where world_id = $1 and z = $2 and type = $3", worldID, z, layerType).Scan(&layerID)
+	if err == sql.ErrNoRows {
+		err = db.QueryRow("insert into layer (world_id, z, type) values ($1, $2, $3) returning layer_id", worldID, z, layerType).Scan(&layerID)
+	}
+	return layerID, err
+}
 
-		stmt, err := txn.Prepare(pq.CopyIn("city", "world_id", "name", "size", "the_geom"))
+// seenLayerIDs returns the IDs of a character's seen and seen solid layers,
+// creating the character and layers if they don't exist yet.
+func seenLayerIDs(db *sqlx.DB, worldID, z int, name string, seen, seenSolid bool) ([]int, error) {
+	var characterID int
+	err := db.QueryRow("select character_id from character where world_id = $1 and namehash = $2", worldID, name).Scan(&characterID)
+	if err == sql.ErrNoRows {
+		err = db.QueryRow("insert into character (world_id, namehash, name) values ($1, $2, $2) returning character_id", worldID, name).Scan(&characterID)
 		if err != nil {
-			return err
+			return nil, err
 		}
+	} else if err != nil {
+		return nil, err
+	}
 
-		for _, c := range w.CityLayer.Cities {
-			x := float64(c.X)*cellWidth + cellWidth/2
-			y := float64(c.Y)*float64(cellHeight) + float64(cellWidth)/2
+	ids := []int{}
+	for _, t := range []string{"seen", "seen_solid"} {
+		if (t == "seen" && !seen) || (t == "seen_solid" && !seenSolid) {
+			continue
+		}
 
-			geom := fmt.Sprintf("POINT(%[1]f %[2]f)", x, y)
-			_, err = stmt.Exec(worldID, c.Name, c.Size, geom)
+		var layerID int
+		err = db.QueryRow("select layer_id from layer where world_id = $1 and z = $2 and character_id = $3 and type = $4", worldID, z, characterID, t).Scan(&layerID)
+		if err == sql.ErrNoRows {
+			err = db.QueryRow("insert into layer (world_id, z, character_id, type) values ($1, $2, $3, $4) returning layer_id", worldID, z, characterID, t).Scan(&layerID)
 			if err != nil {
-				return err
+				return nil, err
 			}
+		} else if err != nil {
+			return nil, err
 		}
-		_, err = stmt.Exec()
-		if err != nil {
-			return err
-		}
+		ids = append(ids, layerID)
+	}
+	return ids, nil
+}
 
-		err = stmt.Close()
-		if err != nil {
-			return err
+// copyCells copies the cells of a terrain layer inside cells, a rectangle of
+// columns and rows, into the cell table. Only the overmaps present have any
+// cells worth copying.
+func copyCells(txn *sql.Tx, w world.World, layerID, z int, cells image.Rectangle) error {
+	stmt, err := txn.Prepare(pq.CopyIn("cell", "layer_id", "id", "name", "the_geom", "om_x", "om_y", "x", "y", "symbol", "color_fg", "color_bg"))
+	if err != nil {
+		return err
+	}
+
+	for p, chunk := range w.TerrainLayers.Chunks {
+		om := w.Extent.Rect(p)
+		r := om.Intersect(cells)
+
+		for ri := r.Min.Y; ri < r.Max.Y; ri++ {
+			for ci := r.Min.X; ci < r.Max.X; ci++ {
+				k := chunk[z][(ri-om.Min.Y)*180+ci-om.Min.X]
+				if world.IsEmptyTerrain(k) {
+					continue
+				}
+
+				x := float64(ci) * cellWidth
+				y := float64(ri) * float64(cellHeight)
+				x2 := x + cellWidth
+				y2 := y + float64(cellHeight)
+
+				c := w.Palette.Cells[k]
+
+				geom := fmt.Sprintf("POLYGON((%[1]f %[2]f, %[3]f %[4]f, %[5]f %[6]f, %[7]f %[8]f, %[1]f %[2]f))", x, y, x2, y, x2, y2, x, y2)
+				omX, omY, tx, ty := gameCoordinates(w, ci, ri)
+				_, err = stmt.Exec(layerID, c.ID, c.Name, geom, omX, omY, tx, ty, c.Symbol, pixel.HexColor(c.ColorFG), pixel.HexColor(c.ColorBG))
+				if err != nil {
+					return err
+				}
+			}
 		}
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	return stmt.Close()
+}
+
+// copyCities replaces the world's cities, returning the city layer's ID.
+func copyCities(db *sqlx.DB, w world.World, worldID int) (int, error) {
+	layerID, err := layerIDFor(db, worldID, 10, "city")
+	if err != nil {
+		return 0, err
+	}
 
-		err = txn.Commit()
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	nukeCitiesStmt, err := txn.Prepare("delete from city where world_id = $1")
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = nukeCitiesStmt.Exec(worldID)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := txn.Prepare(pq.CopyIn("city", "world_id", "name", "size", "the_geom", "om_x", "om_y", "x", "y"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range w.CityLayer.Cities {
+		x, y := pixel.CellCenter(c.X, c.Y)
+
+		geom := fmt.Sprintf("POINT(%[1]f %[2]f)", x, y)
+		omX, omY, tx, ty := gameCoordinates(w, c.X, c.Y)
+		_, err = stmt.Exec(worldID, c.Name, c.Size, geom, omX, omY, tx, ty)
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return 0, err
+	}
 
-	return nil
+	err = stmt.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	return layerID, txn.Commit()
 }
 
-func nativeZoom(xCount, yCount int) int {
-	return int(math.Max(math.Ceil(math.Log2(float64(xCount))), math.Ceil(math.Log2(float64(yCount)))))
+func notifyWorldUpdated(db *sqlx.DB, worldID int, layerIDs []int) error {
+	payload, err := json.Marshal(notify.WorldUpdate{
+		WorldID:  worldID,
+		LayerIDs: layerIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("select pg_notify($1, $2)", notify.WorldUpdatedChannel, string(payload))
+	return err
 }
 
-var tileSize = 256
+// gameCoordinates converts a column and row in the rendered world into the
+// game's overmap coordinates and the overmap terrain position within it.
+func gameCoordinates(w world.World, column, row int) (int, int, int, int) {
+	return w.Extent.XMin + column/180, w.Extent.YMin + row/180, column % 180, row % 180
+}
@@ -0,0 +1,235 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/freetype"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+)
+
+// gridLine is one line of the coordinate grid, down a column when vertical
+// and across a row otherwise. Overmap lines fall on overmap boundaries, the
+// rest subdivide them.
+type gridLine struct {
+	vertical bool
+	at       int
+	overmap  bool
+}
+
+func init() {
+	Register("grid", Serial(chunkRenderer{
+		render: Grid,
+		// The grid only changes with the extent, which changing chunks
+		// within it leaves alone.
+		renderChunks: func(w world.World, o Options, chunks []world.Chunk) error {
+			return nil
+		},
+	}))
+}
+
+// Grid renders an overlay of the overmap coordinate grid, as an image to
+// tile alongside the other layers and as GeoJSON in the pixel space GIS
+// writes geometries in. Lines fall on every overmap boundary and, with
+// subdivisions above one, that many times across each overmap, which it
+// must divide evenly. Where lines cross they're labelled with the game's
+// absolute overmap terrain coordinates. The image is tiled when asked.
+func Grid(w world.World, o Options) error {
+	subdivisions := o.GridSubdivisions
+	if subdivisions > 1 && 180%subdivisions != 0 {
+		return fmt.Errorf("can't divide an overmap's 180 terrains into %v", subdivisions)
+	}
+
+	err := os.MkdirAll(o.OutputRoot, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	lines := gridLines(w, subdivisions)
+
+	b, err := json.Marshal(gridGeoJSON(w, lines))
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(o.OutputRoot, gridGeoJSONName(o.OvermapFilter)), b, 0644)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(o.OutputRoot, gridImageName(o.OvermapFilter))
+	f := func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+		drawGrid(img, c, w, lines)
+	}
+	if o.Tile {
+		return tileOvermaps(filename, w, presentOvermaps(w), &canvas{}, f)
+	}
+	e := &png.Encoder{
+		BufferPool: &pool{},
+	}
+	return write(filename, e, newStripImage(w, &canvas{}, f))
+}
+
+func gridLines(w world.World, subdivisions int) []gridLine {
+	step := 180
+	if subdivisions > 1 {
+		step = 180 / subdivisions
+	}
+
+	lines := []gridLine{}
+	for column := 0; column < w.Columns(); column += step {
+		lines = append(lines, gridLine{vertical: true, at: column, overmap: column%180 == 0})
+	}
+	for row := 0; row < w.Rows(); row += step {
+		lines = append(lines, gridLine{vertical: false, at: row, overmap: row%180 == 0})
+	}
+	return lines
+}
+
+// gridCrossings calls f with the column and row of every place a vertical
+// line crosses a horizontal one.
+func gridCrossings(lines []gridLine, f func(column, row int)) {
+	for _, v := range lines {
+		if !v.vertical {
+			continue
+		}
+		for _, h := range lines {
+			if !h.vertical {
+				f(v.at, h.at)
+			}
+		}
+	}
+}
+
+// absoluteCoordinates converts a column and row in the rendered world into
+// the game's absolute overmap terrain coordinates.
+func absoluteCoordinates(w world.World, column, row int) (int, int) {
+	return w.Extent.XMin*180 + column, w.Extent.YMin*180 + row
+}
+
+func drawGrid(img *image.RGBA, c *freetype.Context, w world.World, lines []gridLine) {
+	overmapLine := image.NewUniform(color.RGBA{255, 255, 255, 192})
+	subdivisionLine := image.NewUniform(color.RGBA{255, 255, 255, 96})
+	bg := image.NewUniform(color.RGBA{0, 0, 0, 192})
+	fg := image.NewUniform(color.RGBA{255, 255, 255, 255})
+
+	bounds := img.Bounds()
+	for _, l := range lines {
+		src, width := subdivisionLine, 1
+		if l.overmap {
+			src, width = overmapLine, 2
+		}
+
+		r := image.Rect(l.at*cellOverprintWidth, bounds.Min.Y, l.at*cellOverprintWidth+width, bounds.Max.Y)
+		if !l.vertical {
+			r = image.Rect(bounds.Min.X, l.at*cellHeight, bounds.Max.X, l.at*cellHeight+width)
+		}
+		draw.Draw(img, r, src, image.ZP, draw.Src)
+	}
+
+	gridCrossings(lines, func(column, row int) {
+		x, y := absoluteCoordinates(w, column, row)
+		text := fmt.Sprintf("%v,%v", x, y)
+
+		x0 := column*cellOverprintWidth + 4
+		y0 := row*cellHeight + 4
+		label := image.Rect(x0, y0, x0+len(text)*cellOverprintWidth, y0+cellHeight)
+		if !label.Overlaps(bounds) {
+			return
+		}
+		draw.Draw(img, label, bg, image.ZP, draw.Src)
+		c.SetSrc(fg)
+		drawLabel(c, text, freetype.Pt(label.Min.X, label.Max.Y))
+	})
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// gridGeoJSON returns the grid lines as LineStrings and their labels as
+// Points at the crossings.
+func gridGeoJSON(w world.World, lines []gridLine) geoJSONFeatureCollection {
+	width := float64(w.Columns()) * cellWidth
+	height := float64(w.Rows() * cellHeight)
+
+	features := []geoJSONFeature{}
+	for _, l := range lines {
+		kind := "subdivision"
+		if l.overmap {
+			kind = "overmap"
+		}
+
+		var coordinates [][]float64
+		var axis string
+		var at int
+		if l.vertical {
+			x := float64(l.at) * cellWidth
+			coordinates = [][]float64{{x, 0}, {x, height}}
+			axis = "x"
+			at, _ = absoluteCoordinates(w, l.at, 0)
+		} else {
+			y := float64(l.at * cellHeight)
+			coordinates = [][]float64{{0, y}, {width, y}}
+			axis = "y"
+			_, at = absoluteCoordinates(w, 0, l.at)
+		}
+
+		features = append(features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "LineString",
+				Coordinates: coordinates,
+			},
+			Properties: map[string]interface{}{
+				"kind": kind,
+				"axis": axis,
+				axis:   at,
+			},
+		})
+	}
+
+	gridCrossings(lines, func(column, row int) {
+		x, y := absoluteCoordinates(w, column, row)
+		features = append(features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: []float64{float64(column) * cellWidth, float64(row * cellHeight)},
+			},
+			Properties: map[string]interface{}{
+				"label": fmt.Sprintf("%v,%v", x, y),
+				"x":     x,
+				"y":     y,
+			},
+		})
+	})
+
+	return geoJSONFeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+func gridImageName(overmapFilter string) string {
+	return fmt.Sprintf("%vgrid.png", overmapFilter)
+}
+
+func gridGeoJSONName(overmapFilter string) string {
+	return fmt.Sprintf("%vgrid.geojson", overmapFilter)
+}
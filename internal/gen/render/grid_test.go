@@ -0,0 +1,162 @@
+package render
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/pixel"
+)
+
+// gridWorld is three overmaps wide and two high, starting at overmap -1,2.
+var gridWorld = world.World{Extent: world.Extent{XMin: -1, YMin: 2, XSize: 3, YSize: 2}}
+
+func TestGridLines(t *testing.T) {
+	tests := []struct {
+		subdivisions int
+		vertical     []int
+		horizontal   []int
+	}{
+		{0, []int{0, 180, 360}, []int{0, 180}},
+		{1, []int{0, 180, 360}, []int{0, 180}},
+		{4, []int{0, 45, 90, 135, 180, 225, 270, 315, 360, 405, 450, 495}, []int{0, 45, 90, 135, 180, 225, 270, 315}},
+	}
+	for _, tt := range tests {
+		vertical, horizontal := []int{}, []int{}
+		for _, l := range gridLines(gridWorld, tt.subdivisions) {
+			if l.overmap != (l.at%180 == 0) {
+				t.Errorf("subdivisions %v: line at %v marked overmap %v", tt.subdivisions, l.at, l.overmap)
+			}
+			if l.vertical {
+				vertical = append(vertical, l.at)
+			} else {
+				horizontal = append(horizontal, l.at)
+			}
+		}
+		if !equalInts(vertical, tt.vertical) || !equalInts(horizontal, tt.horizontal) {
+			t.Errorf("subdivisions %v: expected %v and %v, got %v and %v", tt.subdivisions, tt.vertical, tt.horizontal, vertical, horizontal)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGridGeoJSON(t *testing.T) {
+	lines := gridLines(gridWorld, 4)
+	b, err := json.Marshal(gridGeoJSON(gridWorld, lines))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fc struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Type        string
+				Coordinates json.RawMessage
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(b, &fc); err != nil {
+		t.Fatal(err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 12+8+12*8 {
+		t.Fatalf("expected a collection of 20 lines and 96 labels, got %v of %v", fc.Type, len(fc.Features))
+	}
+
+	// Multiplied at run time, as gridGeoJSON does, to round the same way.
+	cw := pixel.CellWidth
+	width, height := 540*cw, float64(360*pixel.CellHeight)
+	lineTests := []struct {
+		i           int
+		kind, axis  string
+		at          float64
+		coordinates [][]float64
+	}{
+		{0, "overmap", "x", -180, [][]float64{{0, 0}, {0, height}}},
+		{1, "subdivision", "x", -135, [][]float64{{45 * cw, 0}, {45 * cw, height}}},
+		{4, "overmap", "x", 0, [][]float64{{180 * cw, 0}, {180 * cw, height}}},
+		{16, "overmap", "y", 540, [][]float64{{0, 180 * pixel.CellHeight}, {width, 180 * pixel.CellHeight}}},
+	}
+	for _, tt := range lineTests {
+		f := fc.Features[tt.i]
+		var coordinates [][]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &coordinates); err != nil {
+			t.Fatal(err)
+		}
+		if f.Geometry.Type != "LineString" || f.Properties["kind"] != tt.kind || f.Properties["axis"] != tt.axis || f.Properties[tt.axis] != tt.at {
+			t.Errorf("line %v: expected a %v line at %v %v, got %v %+v", tt.i, tt.kind, tt.axis, tt.at, f.Geometry.Type, f.Properties)
+		}
+		for j := range tt.coordinates {
+			if coordinates[j][0] != tt.coordinates[j][0] || coordinates[j][1] != tt.coordinates[j][1] {
+				t.Errorf("line %v: expected %v, got %v", tt.i, tt.coordinates, coordinates)
+				break
+			}
+		}
+	}
+
+	// Labels follow the lines, a column of crossings at a time.
+	label := fc.Features[20+4*8+4]
+	var point []float64
+	if err := json.Unmarshal(label.Geometry.Coordinates, &point); err != nil {
+		t.Fatal(err)
+	}
+	if label.Geometry.Type != "Point" || label.Properties["label"] != "0,540" || label.Properties["x"] != 0.0 || label.Properties["y"] != 540.0 {
+		t.Errorf("expected the label 0,540, got %v %+v", label.Geometry.Type, label.Properties)
+	}
+	if point[0] != 180*cw || point[1] != 180*pixel.CellHeight {
+		t.Errorf("expected the label at the corner of overmap 0,3, got %v", point)
+	}
+}
+
+func TestDrawGrid(t *testing.T) {
+	lines := gridLines(gridWorld, 4)
+
+	// A piece of the image around where the overmap lines at column and
+	// row 180 cross, the lines a subdivision to the right and below, and
+	// the label at the crossing.
+	cells := image.Rect(170, 170, 230, 230)
+	img := image.NewRGBA(cellPixels(cells))
+	drawGrid(img, newContext(img), gridWorld, lines)
+
+	overmapLine := color.RGBA{255, 255, 255, 192}
+	subdivisionLine := color.RGBA{255, 255, 255, 96}
+	labelBG := color.RGBA{0, 0, 0, 192}
+	tests := []struct {
+		x, y int
+		c    color.RGBA
+	}{
+		{180*24 - 1, 4100, color.RGBA{}},
+		{180 * 24, 4100, overmapLine},
+		{180*24 + 1, 4100, overmapLine},
+		{180*24 + 2, 4100, color.RGBA{}},
+		{225 * 24, 4100, subdivisionLine},
+		{225*24 + 1, 4100, color.RGBA{}},
+		{4100, 180*24 - 1, color.RGBA{}},
+		{4100, 180 * 24, overmapLine},
+		{4100, 180*24 + 1, overmapLine},
+		{4100, 180*24 + 2, color.RGBA{}},
+		{4100, 225 * 24, subdivisionLine},
+		{180*24 + 4, 180*24 + 4, labelBG},
+		{180*24 + 3, 180*24 + 4, color.RGBA{}},
+		{180*24 + 4, 180*24 + 3, color.RGBA{}},
+	}
+	for _, tt := range tests {
+		if c := img.RGBAAt(tt.x, tt.y); c != tt.c {
+			t.Errorf("%v,%v: expected %v, got %v", tt.x, tt.y, tt.c, c)
+		}
+	}
+}
@@ -9,21 +9,25 @@ import (
 	"image/png"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
 	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/pixel"
 	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
 )
 
 var dpi = 72.0
 var size = 24.0
 var spacing = 1.0
-var cellWidth = 21.3594
-var cellHeight = 24
+var cellWidth = pixel.CellWidth
+var cellHeight = pixel.CellHeight
 var cellOverprintWidth = 24
 var mapFont *truetype.Font
 var colorCache map[color.RGBA]*image.Uniform
+var colorCacheMu sync.Mutex
 
 func init() {
 	fontBytes, err := Asset("Topaz-8.ttf")
@@ -37,10 +41,23 @@ func init() {
 	}
 
 	colorCache = make(map[color.RGBA]*image.Uniform)
+
+	Register("image", Serial(chunkRenderer{render: Image, renderChunks: ImageChunks}))
+}
+
+// Image renders each layer to a PNG or, when asked, straight into tiles.
+// Any annotations are baked into the terrain images of their layers.
+func Image(w world.World, o Options) error {
+	if len(o.Layers) == 0 {
+		return nil
+	}
+	return images(w, o, o.Layers, o.Cities)
 }
 
-func Image(w world.World, outputRoot, overmapFilter string, includeLayers []int, terrain, seen, seenSolid, skipEmpty, cities bool) error {
-	err := os.MkdirAll(outputRoot, os.ModePerm)
+// images renders the terrain and seen images of layers that o asks for, and
+// the cities if asked.
+func images(w world.World, o Options, layers []int, cities bool) error {
+	err := os.MkdirAll(o.OutputRoot, os.ModePerm)
 	if err != nil {
 		return err
 	}
@@ -48,109 +65,160 @@ func Image(w world.World, outputRoot, overmapFilter string, includeLayers []int,
 	e := &png.Encoder{
 		BufferPool: &pool{},
 	}
-
-	if len(includeLayers) == 0 {
-		return nil
+	cv := &canvas{}
+	overmaps := presentOvermaps(w)
+	out := func(name string, f drawFunc) error {
+		filename := filepath.Join(o.OutputRoot, name)
+		if o.Tile {
+			return tileOvermaps(filename, w, overmaps, cv, f)
+		}
+		return write(filename, e, newStripImage(w, cv, f))
 	}
 
-	l := w.TerrainLayers[includeLayers[0]]
-
-	width := int(cellOverprintWidth * len(l.TerrainRows[0].TerrainCellKeys))
-	height := cellHeight * len(l.TerrainRows)
-
-	fullImage := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	c := freetype.NewContext()
-	c.SetDPI(dpi)
-	c.SetFont(mapFont)
-	c.SetFontSize(size)
-	c.SetClip(fullImage.Bounds())
-	c.SetDst(fullImage)
-	c.SetHinting(font.HintingNone)
-
-	for _, layerID := range includeLayers {
-		if terrain {
-			err := terrainToImage(e, fullImage, c, w, outputRoot, overmapFilter, layerID, skipEmpty)
+	for _, layerID := range layers {
+		layerID := layerID
+		if o.Terrain && !(w.TerrainLayers.Empty[layerID] && o.SkipEmpty) {
+			err := out(terrainImageName(o.OvermapFilter, layerID), func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+				drawTerrain(img, c, w, layerID, cells, o.Annotations)
+			})
 			if err != nil {
 				return err
 			}
 		}
 
-		if seen {
-			err := seenToImage(e, fullImage, c, w, outputRoot, overmapFilter, layerID, skipEmpty)
-			if err != nil {
-				return err
+		for _, solid := range []bool{false, true} {
+			if (solid && !o.SeenSolid) || (!solid && !o.Seen) {
+				continue
 			}
-		}
-
-		if seenSolid {
-			err := seenToImageSolid(e, fullImage, c, w, outputRoot, overmapFilter, layerID, skipEmpty)
-			if err != nil {
-				return err
+			for _, name := range w.Characters() {
+				if w.SeenLayers[name].Empty[layerID] && o.SkipEmpty {
+					continue
+				}
+				name, solid := name, solid
+				err := out(seenImageName(name, o.OvermapFilter, layerID, solid), func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+					drawSeen(img, c, w, name, layerID, cells, solid)
+				})
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
 
 	if cities {
-		err := citiesToImage(e, fullImage, c, w, outputRoot, overmapFilter)
+		err := out(citiesImageName(o.OvermapFilter), func(img *image.RGBA, c *freetype.Context, cells image.Rectangle) {
+			drawCities(img, c, w, cells)
+		})
 		if err != nil {
 			return err
 		}
 	}
+
 	return nil
 }
 
-func terrainToImage(e *png.Encoder, fullImage *image.RGBA, c *freetype.Context, w world.World, outputRoot, overmapFilter string, layerID int, skipEmpty bool) error {
-	l := w.TerrainLayers[layerID]
+// newContext returns a context for drawing symbols in the map font onto img.
+func newContext(img *image.RGBA) *freetype.Context {
+	c := freetype.NewContext()
+	c.SetDPI(dpi)
+	c.SetFont(mapFont)
+	c.SetFontSize(size)
+	c.SetClip(img.Bounds())
+	c.SetDst(img)
+	c.SetHinting(font.HintingNone)
+	return c
+}
 
-	if l.Empty && skipEmpty {
-		return nil
-	}
+// worldCells is the rectangle of columns and rows covering the whole world.
+func worldCells(w world.World) image.Rectangle {
+	return image.Rect(0, 0, w.Columns(), w.Rows())
+}
 
-	draw.Draw(fullImage, fullImage.Bounds(), image.Black, image.ZP, draw.Src)
+// cellPixels is the rectangle of the image covered by a rectangle of cells.
+func cellPixels(cells image.Rectangle) image.Rectangle {
+	return image.Rect(cells.Min.X*cellOverprintWidth, cells.Min.Y*cellHeight, cells.Max.X*cellOverprintWidth, cells.Max.Y*cellHeight)
+}
 
-	pt := freetype.Pt(0, 0+int(c.PointToFixed(size)>>6))
-	for _, r := range l.TerrainRows {
-		for _, k := range r.TerrainCellKeys {
-			cell := w.TerrainCellLookup[k]
-			bg, ok := colorCache[cell.ColorBG]
-			if !ok {
-				bg = image.NewUniform(cell.ColorBG)
-				colorCache[cell.ColorBG] = bg
-			}
+// cellPoint is where the symbol of the cell at column, row is drawn, the
+// left end of its baseline.
+func cellPoint(column, row int) fixed.Point26_6 {
+	return freetype.Pt(column*cellOverprintWidth, (row+1)*cellHeight)
+}
 
-			fg, ok := colorCache[cell.ColorFG]
-			if !ok {
-				fg = image.NewUniform(cell.ColorFG)
-				colorCache[cell.ColorFG] = fg
-			}
+// drawLabel draws text with its baseline starting at pt. Glyphs after the
+// first can land between pixels, and the context caches a glyph at whichever
+// of those positions it was first drawn at, so the cache is emptied first to
+// draw a label the same however many others came before it, and so however
+// a world is split up to be drawn.
+func drawLabel(c *freetype.Context, text string, pt fixed.Point26_6) {
+	c.SetHinting(font.HintingNone)
+	c.DrawString(text, pt)
+}
 
-			draw.Draw(fullImage, image.Rect(int(pt.X>>6), int(pt.Y>>6), int(pt.X>>6)+cellOverprintWidth, int(pt.Y>>6)-cellHeight), bg, image.ZP, draw.Src)
-			c.SetSrc(fg)
-			c.DrawString(cell.Symbol, pt)
-			pt.X += c.PointToFixed(float64(cellOverprintWidth))
+// uniform returns a shared image of color c. Worlds may be rendered
+// concurrently, so the cache is locked.
+func uniform(c color.RGBA) *image.Uniform {
+	colorCacheMu.Lock()
+	defer colorCacheMu.Unlock()
+	u, ok := colorCache[c]
+	if !ok {
+		u = image.NewUniform(c)
+		colorCache[c] = u
+	}
+	return u
+}
+
+// drawTerrain draws the terrain of a layer inside cells, a rectangle of
+// columns and rows, at its place in img, along with any annotations there.
+// Only the overmaps present are drawn cell by cell, the rest are blank.
+func drawTerrain(img *image.RGBA, c *freetype.Context, w world.World, layerID int, cells image.Rectangle, annotations []Annotation) {
+	blank := []image.Rectangle{}
+	for _, p := range w.Extent.OvermapsIn(cells) {
+		om := w.Extent.Rect(p)
+		r := om.Intersect(cells)
+		if r.Empty() {
+			continue
+		}
+		chunk, ok := w.TerrainLayers.Chunks[p]
+		if !ok {
+			blank = append(blank, r)
+			continue
+		}
+
+		for ri := r.Min.Y; ri < r.Max.Y; ri++ {
+			for ci := r.Min.X; ci < r.Max.X; ci++ {
+				cell := w.Palette.Cells[chunk[layerID][(ri-om.Min.Y)*180+ci-om.Min.X]]
+				drawCell(img, c, cellPoint(ci, ri), cell.Symbol, cell.ColorFG, cell.ColorBG)
+			}
 		}
-		pt.X = c.PointToFixed(0)
-		pt.Y += c.PointToFixed(size * spacing)
 	}
 
-	filename := filepath.Join(outputRoot, fmt.Sprintf("o%v_%v.png", overmapFilter, layerID))
-	err := write(filename, e, fullImage)
-	if err != nil {
-		return err
+	bg := uniform(w.Blank().ColorBG)
+	for _, r := range blank {
+		draw.Draw(img, cellPixels(r), bg, image.ZP, draw.Src)
 	}
 
-	return nil
+	annotationsToImage(img, c, w, layerID, annotations)
 }
 
-func write(filename string, e *png.Encoder, fullImage *image.RGBA) error {
+// drawCell fills the background of the cell whose symbol is drawn at pt and
+// draws the symbol over it.
+func drawCell(img *image.RGBA, c *freetype.Context, pt fixed.Point26_6, symbol string, fg, bg color.RGBA) {
+	draw.Draw(img, image.Rect(int(pt.X>>6), int(pt.Y>>6), int(pt.X>>6)+cellOverprintWidth, int(pt.Y>>6)-cellHeight), uniform(bg), image.ZP, draw.Src)
+	if symbol != "" {
+		c.SetSrc(uniform(fg))
+		c.DrawString(symbol, pt)
+	}
+}
+
+func write(filename string, e *png.Encoder, img image.Image) error {
 	outFile, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 
 	b := bufio.NewWriter(outFile)
-	err = e.Encode(b, fullImage)
+	err = e.Encode(b, img)
 	if err != nil {
 		outFile.Close()
 		return err
@@ -166,115 +234,123 @@ func write(filename string, e *png.Encoder, fullImage *image.RGBA) error {
 	return nil
 }
 
-func seenToImage(e *png.Encoder, fullImage *image.RGBA, c *freetype.Context, w world.World, outputRoot, overmapFilter string, layerID int, skipEmpty bool) error {
-	for name, layers := range w.SeenLayers {
-		l := layers[layerID]
-
-		if l.Empty && skipEmpty {
+// drawSeen draws what a character has seen of a layer inside cells. Solid
+// overlays only fill the background of each cell. Overmaps the character
+// has no seen file for are copied a row at a time from a rendered row of
+// unseen cells.
+func drawSeen(img *image.RGBA, c *freetype.Context, w world.World, name string, layerID int, cells image.Rectangle, solid bool) {
+	l := w.SeenLayers[name]
+	unseen := []image.Rectangle{}
+	for _, p := range w.Extent.OvermapsIn(cells) {
+		om := w.Extent.Rect(p)
+		r := om.Intersect(cells)
+		if r.Empty() {
+			continue
+		}
+		chunk, ok := l.Chunks[p]
+		if !ok {
+			unseen = append(unseen, r)
 			continue
 		}
 
-		draw.Draw(fullImage, fullImage.Bounds(), image.Black, image.ZP, draw.Src)
-
-		pt := freetype.Pt(0, 0+int(c.PointToFixed(size)>>6))
-		for _, r := range l.SeenRows {
-			for _, k := range r.SeenCellKeys {
-				cell := w.SeenCellLookup[k]
-				bg, ok := colorCache[cell.ColorBG]
-				if !ok {
-					bg = image.NewUniform(cell.ColorBG)
-					colorCache[cell.ColorBG] = bg
-				}
-
-				fg, ok := colorCache[cell.ColorFG]
-				if !ok {
-					fg = image.NewUniform(cell.ColorFG)
-					colorCache[cell.ColorFG] = fg
+		for ri := r.Min.Y; ri < r.Max.Y; ri++ {
+			for ci := r.Min.X; ci < r.Max.X; ci++ {
+				cell := w.SeenCellLookup[chunk[layerID][(ri-om.Min.Y)*180+ci-om.Min.X]]
+				symbol := cell.Symbol
+				if solid {
+					symbol = ""
 				}
-
-				draw.Draw(fullImage, image.Rect(int(pt.X>>6), int(pt.Y>>6), int(pt.X>>6)+cellOverprintWidth, int(pt.Y>>6)-cellHeight), bg, image.ZP, draw.Src)
-				c.SetSrc(fg)
-				c.DrawString(cell.Symbol, pt)
-				pt.X += c.PointToFixed(float64(cellOverprintWidth))
+				drawCell(img, c, cellPoint(ci, ri), symbol, cell.ColorFG, cell.ColorBG)
 			}
-			pt.X = c.PointToFixed(0)
-			pt.Y += c.PointToFixed(size * spacing)
 		}
+	}
 
-		filename := filepath.Join(outputRoot, fmt.Sprintf("%v%v_visible_%v.png", name, overmapFilter, layerID))
-		err := write(filename, e, fullImage)
-		if err != nil {
-			return err
+	if len(unseen) == 0 {
+		return
+	}
+	row := unseenRow(w.SeenCellLookup[false], solid)
+	for _, r := range unseen {
+		for ri := r.Min.Y; ri < r.Max.Y; ri++ {
+			draw.Draw(img, cellPixels(image.Rect(r.Min.X, ri, r.Max.X, ri+1)), row, image.ZP, draw.Src)
 		}
 	}
-
-	return nil
 }
 
-func seenToImageSolid(e *png.Encoder, fullImage *image.RGBA, c *freetype.Context, w world.World, outputRoot, overmapFilter string, layerID int, skipEmpty bool) error {
-	for name, layers := range w.SeenLayers {
-		l := layers[layerID]
+type unseenRowKey struct {
+	cell  world.SeenCell
+	solid bool
+}
 
-		if l.Empty && skipEmpty {
-			continue
-		}
+var unseenRows = make(map[unseenRowKey]*image.RGBA)
+var unseenRowsMu sync.Mutex
 
-		draw.Draw(fullImage, fullImage.Bounds(), image.Black, image.ZP, draw.Src)
+// unseenRow returns an overmap wide row of unseen cells, rendered once.
+func unseenRow(cell world.SeenCell, solid bool) *image.RGBA {
+	unseenRowsMu.Lock()
+	defer unseenRowsMu.Unlock()
 
-		pt := freetype.Pt(0, 0+int(c.PointToFixed(size)>>6))
-		for _, r := range l.SeenRows {
-			for _, k := range r.SeenCellKeys {
-				cell := w.SeenCellLookup[k]
-				bg, ok := colorCache[cell.ColorBG]
-				if !ok {
-					bg = image.NewUniform(cell.ColorBG)
-					colorCache[cell.ColorBG] = bg
-				}
+	k := unseenRowKey{cell: cell, solid: solid}
+	if row, ok := unseenRows[k]; ok {
+		return row
+	}
 
-				draw.Draw(fullImage, image.Rect(int(pt.X>>6), int(pt.Y>>6), int(pt.X>>6)+cellOverprintWidth, int(pt.Y>>6)-cellHeight), bg, image.ZP, draw.Src)
-				pt.X += c.PointToFixed(float64(cellOverprintWidth))
-			}
-			pt.X = c.PointToFixed(0)
-			pt.Y += c.PointToFixed(size * spacing)
-		}
+	row := image.NewRGBA(cellPixels(image.Rect(0, 0, 180, 1)))
+	c := newContext(row)
 
-		filename := filepath.Join(outputRoot, fmt.Sprintf("%v%v_visible_solid_%v.png", name, overmapFilter, layerID))
-		err := write(filename, e, fullImage)
-		if err != nil {
-			return err
-		}
+	symbol := cell.Symbol
+	if solid {
+		symbol = ""
 	}
-
-	return nil
+	for ci := 0; ci < 180; ci++ {
+		drawCell(row, c, cellPoint(ci, 0), symbol, cell.ColorFG, cell.ColorBG)
+	}
+	unseenRows[k] = row
+	return row
 }
 
-func citiesToImage(e *png.Encoder, fullImage *image.RGBA, c *freetype.Context, w world.World, outputRoot, overmapFilter string) error {
-	draw.Draw(fullImage, fullImage.Bounds(), image.Transparent, image.ZP, draw.Src)
+// drawCities labels the cities inside cells over a transparent background.
+func drawCities(img *image.RGBA, c *freetype.Context, w world.World, cells image.Rectangle) {
+	draw.Draw(img, cellPixels(cells), image.Transparent, image.ZP, draw.Src)
 
 	bg := image.NewUniform(color.RGBA{255, 255, 0, 255})
 	fg := image.NewUniform(color.RGBA{0, 0, 0, 255})
 
-	pt := freetype.Pt(0, 0+int(c.PointToFixed(size)>>6))
-	for _, r := range w.CityLayer.CityRows {
-		for _, k := range r.CityCell {
-			if k != "" {
-				draw.Draw(fullImage, image.Rect(int(pt.X>>6), int(pt.Y>>6)+2, int(pt.X>>6)+cellOverprintWidth, int(pt.Y>>6)-cellHeight), bg, image.ZP, draw.Src)
+	for _, p := range w.Extent.OvermapsIn(cells) {
+		chunk, ok := w.CityLayer.Chunks[p]
+		if !ok {
+			continue
+		}
+		om := w.Extent.Rect(p)
+		r := om.Intersect(cells)
+
+		for ri := r.Min.Y; ri < r.Max.Y; ri++ {
+			for ci := r.Min.X; ci < r.Max.X; ci++ {
+				k := chunk[(ri-om.Min.Y)*180+ci-om.Min.X]
+				if k == "" {
+					continue
+				}
+				pt := cellPoint(ci, ri)
+				draw.Draw(img, image.Rect(int(pt.X>>6), int(pt.Y>>6)+2, int(pt.X>>6)+cellOverprintWidth, int(pt.Y>>6)-cellHeight), bg, image.ZP, draw.Src)
 				c.SetSrc(fg)
 				c.DrawString(k, pt)
 			}
-			pt.X += c.PointToFixed(float64(cellOverprintWidth))
 		}
-		pt.X = c.PointToFixed(0)
-		pt.Y += c.PointToFixed(size * spacing)
 	}
+}
 
-	filename := filepath.Join(outputRoot, fmt.Sprintf("%vcities.png", overmapFilter))
-	err := write(filename, e, fullImage)
-	if err != nil {
-		return err
+func terrainImageName(overmapFilter string, layerID int) string {
+	return fmt.Sprintf("o%v_%v.png", overmapFilter, layerID)
+}
+
+func seenImageName(name, overmapFilter string, layerID int, solid bool) string {
+	if solid {
+		return fmt.Sprintf("%v%v_visible_solid_%v.png", name, overmapFilter, layerID)
 	}
+	return fmt.Sprintf("%v%v_visible_%v.png", name, overmapFilter, layerID)
+}
 
-	return nil
+func citiesImageName(overmapFilter string) string {
+	return fmt.Sprintf("%vcities.png", overmapFilter)
 }
 
 type pool struct {
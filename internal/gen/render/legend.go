@@ -0,0 +1,261 @@
+package render
+
+import (
+	"bufio"
+	"encoding/json"
+	"html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/freetype"
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/pixel"
+)
+
+type legendGroup struct {
+	LandUseCode string        `json:"landUseCode,omitempty"`
+	Entries     []legendEntry `json:"entries"`
+}
+
+// legendEntry is one symbol of the legend, with every terrain drawn with it
+// under the same name.
+type legendEntry struct {
+	Symbol  string   `json:"symbol"`
+	ColorFG string   `json:"colorFg"`
+	ColorBG string   `json:"colorBg"`
+	Name    string   `json:"name"`
+	IDs     []string `json:"ids"`
+	fg      color.RGBA
+	bg      color.RGBA
+}
+
+type legend struct {
+	World         string        `json:"world"`
+	ByLandUseCode bool          `json:"byLandUseCode"`
+	Groups        []legendGroup `json:"groups"`
+}
+
+func init() {
+	Register("legend", RendererFunc(Legend))
+}
+
+// Legend writes a legend of the terrain on the rendered layers to a legend
+// folder under the output root, out of the way of tiling, as PNG, HTML and JSON.
+// Each symbol is shown in its colors beside the name of its terrain, and
+// grouped by land use code when the map is symbolized by them.
+func Legend(w world.World, o Options) error {
+	root := filepath.Join(o.OutputRoot, "legend")
+	err := os.MkdirAll(root, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	l := buildLegend(w, o.Layers, o.SkipEmpty, o.LandUseCode)
+	name := filepath.Join(root, o.OvermapFilter+"legend")
+
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(name+".json", b, 0644); err != nil {
+		return err
+	}
+
+	if err := legendToHTML(l, name+".html"); err != nil {
+		return err
+	}
+
+	return legendToImage(l, name+".png")
+}
+
+// buildLegend collects the terrain that appears on the layers rendered,
+// leaving out the blank shown where there's no overmap.
+func buildLegend(w world.World, includeLayers []int, skipEmpty, byLandUseCode bool) legend {
+	present := make([]bool, len(w.Palette.Cells))
+	for _, layerID := range includeLayers {
+		if w.TerrainLayers.Empty[layerID] && skipEmpty {
+			continue
+		}
+		for _, chunk := range w.TerrainLayers.Chunks {
+			for _, i := range chunk[layerID] {
+				present[i] = true
+			}
+		}
+	}
+
+	type entryKey struct {
+		symbol string
+		fg, bg color.RGBA
+		name   string
+	}
+
+	groups := make(map[string]map[entryKey]*legendEntry)
+	for i, c := range w.Palette.Cells {
+		if !present[i] || c.ID == "" {
+			continue
+		}
+
+		code := ""
+		if byLandUseCode {
+			code = c.LandUseCode
+		}
+		name := c.Name
+		if name == "" {
+			name = c.ID
+		}
+
+		entries, ok := groups[code]
+		if !ok {
+			entries = make(map[entryKey]*legendEntry)
+			groups[code] = entries
+		}
+		k := entryKey{symbol: c.Symbol, fg: c.ColorFG, bg: c.ColorBG, name: name}
+		e, ok := entries[k]
+		if !ok {
+			e = &legendEntry{
+				Symbol:  c.Symbol,
+				ColorFG: pixel.HexColor(c.ColorFG),
+				ColorBG: pixel.HexColor(c.ColorBG),
+				Name:    name,
+				IDs:     []string{},
+				fg:      c.ColorFG,
+				bg:      c.ColorBG,
+			}
+			entries[k] = e
+		}
+		e.IDs = append(e.IDs, c.ID)
+	}
+
+	l := legend{
+		World:         w.Name,
+		ByLandUseCode: byLandUseCode,
+		Groups:        []legendGroup{},
+	}
+	for code, entries := range groups {
+		g := legendGroup{LandUseCode: code}
+		for _, e := range entries {
+			sort.Strings(e.IDs)
+			g.Entries = append(g.Entries, *e)
+		}
+		sort.Slice(g.Entries, func(i, j int) bool {
+			a, b := g.Entries[i], g.Entries[j]
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.Symbol < b.Symbol
+		})
+		l.Groups = append(l.Groups, g)
+	}
+
+	// Terrain without a land use code goes last.
+	sort.Slice(l.Groups, func(i, j int) bool {
+		a, b := l.Groups[i].LandUseCode, l.Groups[j].LandUseCode
+		if (a == "") != (b == "") {
+			return b == ""
+		}
+		return a < b
+	})
+	return l
+}
+
+func groupTitle(code string) string {
+	if code == "" {
+		return "no land use code"
+	}
+	return code
+}
+
+func legendToImage(l legend, filename string) error {
+	const margin = 8
+
+	rows := 0
+	longest := 0
+	for _, g := range l.Groups {
+		if l.ByLandUseCode {
+			rows++
+			if n := len([]rune(groupTitle(g.LandUseCode))); n > longest {
+				longest = n
+			}
+		}
+		for _, e := range g.Entries {
+			rows++
+			if n := len([]rune(e.Name)) + 2; n > longest {
+				longest = n
+			}
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 2*margin+(longest+1)*cellOverprintWidth, 2*margin+rows*(cellHeight+4)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{16, 16, 16, 255}), image.ZP, draw.Src)
+
+	c := newContext(img)
+	header := image.NewUniform(color.RGBA{255, 255, 0, 255})
+	text := image.NewUniform(color.RGBA{200, 200, 200, 255})
+
+	y := margin
+	for _, g := range l.Groups {
+		if l.ByLandUseCode {
+			c.SetSrc(header)
+			c.DrawString(groupTitle(g.LandUseCode), freetype.Pt(margin, y+cellHeight))
+			y += cellHeight + 4
+		}
+		for _, e := range g.Entries {
+			drawCell(img, c, freetype.Pt(margin, y+cellHeight), e.Symbol, e.fg, e.bg)
+			c.SetSrc(text)
+			c.DrawString(e.Name, freetype.Pt(margin+2*cellOverprintWidth, y+cellHeight))
+			y += cellHeight + 4
+		}
+	}
+
+	e := &png.Encoder{
+		BufferPool: &pool{},
+	}
+	return write(filename, e, img)
+}
+
+var legendTemplate = template.Must(template.New("legend").Funcs(template.FuncMap{
+	"title": groupTitle,
+	"join":  func(ids []string) string { return strings.Join(ids, ", ") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.World}} legend</title>
+<style>
+body { background: #101010; color: #c8c8c8; font-family: monospace; }
+h2 { color: #ffff00; font-size: 1em; margin: 1em 0 0.25em; }
+td { padding: 0 0.5em; }
+.symbol { display: inline-block; width: 1.2em; text-align: center; white-space: pre; }
+.ids { color: #808080; }
+</style>
+</head>
+<body>
+<h1>{{.World}}</h1>
+{{range .Groups}}{{if $.ByLandUseCode}}<h2>{{title .LandUseCode}}</h2>
+{{end}}<table>
+{{range .Entries}}<tr><td><span class="symbol" style="color: {{.ColorFG}}; background: {{.ColorBG}}">{{.Symbol}}</span></td><td>{{.Name}}</td><td class="ids">{{join .IDs}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+func legendToHTML(l legend, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b := bufio.NewWriter(f)
+	if err := legendTemplate.Execute(b, l); err != nil {
+		return err
+	}
+	return b.Flush()
+}
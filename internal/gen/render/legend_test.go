@@ -0,0 +1,114 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"testing"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+)
+
+func legendWorld(t *testing.T) world.World {
+	green := color.RGBA{0, 110, 0, 255}
+	black := color.RGBA{0, 0, 0, 255}
+	p := world.NewPalette(
+		world.TerrainCell{ID: ""},
+		world.TerrainCell{ID: "empty_rock", Symbol: "%", Name: "solid rock", ColorFG: black, ColorBG: black},
+		world.TerrainCell{ID: "open_air", Symbol: ".", Name: "open air", ColorFG: black, ColorBG: black},
+	)
+
+	ids := make(map[string]uint16)
+	for _, c := range []world.TerrainCell{
+		{ID: "field", Symbol: ".", Name: "field", LandUseCode: "farm", ColorFG: green, ColorBG: black},
+		{ID: "forest", Symbol: "F", Name: "forest", LandUseCode: "forest", ColorFG: green, ColorBG: black},
+		{ID: "forest_thick", Symbol: "F", Name: "forest", LandUseCode: "forest", ColorFG: green, ColorBG: black},
+		{ID: "lake", Symbol: "~", Name: "lake", ColorFG: color.RGBA{0, 0, 255, 255}, ColorBG: black},
+		{ID: "road_ns", Symbol: "│", Name: "road", ColorFG: black, ColorBG: black},
+		{ID: "road_ew", Symbol: "─", Name: "road", ColorFG: black, ColorBG: black},
+		{ID: "house", Symbol: "^", Name: "house", LandUseCode: "residential", ColorFG: green, ColorBG: black},
+	} {
+		i, err := p.Add(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[c.ID] = i
+	}
+
+	// Every terrain but the house is on layer 10, beside the blank left
+	// where there's no overmap. Layer 11 is nothing but open air.
+	chunk := &world.TerrainChunk{}
+	for i, id := range []string{"field", "forest", "forest_thick", "lake", "road_ns", "road_ew"} {
+		chunk[10][i+1] = ids[id]
+	}
+	openAir, _ := p.Index("open_air")
+	for i := range chunk[11] {
+		chunk[11][i] = openAir
+	}
+
+	w := world.World{
+		Name:    "Anchorage",
+		Palette: p,
+		TerrainLayers: world.TerrainLayers{
+			Chunks: map[world.Point]*world.TerrainChunk{{X: 0, Y: 0}: chunk},
+		},
+	}
+	w.TerrainLayers.Empty[11] = true
+	return w
+}
+
+// legendLines flattens a legend to a line per entry, in order.
+func legendLines(l legend) []string {
+	lines := []string{}
+	for _, g := range l.Groups {
+		for _, e := range g.Entries {
+			lines = append(lines, fmt.Sprintf("%v: %v %v %v", g.LandUseCode, e.Symbol, e.Name, e.IDs))
+		}
+	}
+	return lines
+}
+
+func TestBuildLegend(t *testing.T) {
+	w := legendWorld(t)
+
+	tests := []struct {
+		skipEmpty, byLandUseCode bool
+		lines                    []string
+	}{
+		{true, true, []string{
+			"farm: . field [field]",
+			"forest: F forest [forest forest_thick]",
+			": ~ lake [lake]",
+			": ─ road [road_ew]",
+			": │ road [road_ns]",
+		}},
+		{false, true, []string{
+			"farm: . field [field]",
+			"forest: F forest [forest forest_thick]",
+			": ~ lake [lake]",
+			": . open air [open_air]",
+			": ─ road [road_ew]",
+			": │ road [road_ns]",
+		}},
+		{true, false, []string{
+			": . field [field]",
+			": F forest [forest forest_thick]",
+			": ~ lake [lake]",
+			": ─ road [road_ew]",
+			": │ road [road_ns]",
+		}},
+	}
+	for _, tt := range tests {
+		l := buildLegend(w, []int{10, 11}, tt.skipEmpty, tt.byLandUseCode)
+		if l.World != "Anchorage" || l.ByLandUseCode != tt.byLandUseCode {
+			t.Errorf("skip empty %v, by land use code %v: expected the legend of Anchorage, got %v %v", tt.skipEmpty, tt.byLandUseCode, l.World, l.ByLandUseCode)
+		}
+		lines := legendLines(l)
+		if fmt.Sprint(lines) != fmt.Sprint(tt.lines) {
+			t.Errorf("skip empty %v, by land use code %v: expected\n%v\ngot\n%v", tt.skipEmpty, tt.byLandUseCode, tt.lines, lines)
+		}
+	}
+
+	if l := buildLegend(w, []int{11}, true, true); len(l.Groups) != 0 {
+		t.Errorf("expected no groups when every layer is skipped, got %+v", l.Groups)
+	}
+}
@@ -0,0 +1,205 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+)
+
+// Options are the settings shared by every renderer, each of which uses the
+// ones that apply to it.
+type Options struct {
+	OutputRoot       string
+	OvermapFilter    string
+	Layers           []int
+	Terrain          bool
+	Seen             bool
+	SeenSolid        bool
+	Cities           bool
+	SkipEmpty        bool
+	LandUseCode      bool
+	Tile             bool
+	Annotations      []Annotation
+	ConnectionString string
+	GridSubdivisions int
+}
+
+// Renderer writes a world out in some form.
+type Renderer interface {
+	Render(w world.World, o Options) error
+}
+
+// ChunkRenderer is a Renderer that can bring its output up to date with just
+// the chunks of the world that changed. Renderers that can't are run again
+// in full instead.
+type ChunkRenderer interface {
+	Renderer
+	RenderChunks(w world.World, o Options, chunks []world.Chunk) error
+}
+
+// RendererFunc lets an ordinary function be a Renderer.
+type RendererFunc func(w world.World, o Options) error
+
+// Render calls f(w, o).
+func (f RendererFunc) Render(w world.World, o Options) error {
+	return f(w, o)
+}
+
+// serialRenderer is a Renderer that draws images, which take enough memory
+// and CPU that renderers like it are run one after another.
+type serialRenderer interface {
+	Renderer
+	serial()
+}
+
+// Serial marks r as drawing images, so Run and RunChunks run it one after
+// another with any other renderers marked so, while the rest run alongside.
+func Serial(r Renderer) Renderer {
+	return serialized{r}
+}
+
+type serialized struct {
+	Renderer
+}
+
+func (r serialized) RenderChunks(w world.World, o Options, chunks []world.Chunk) error {
+	if cr, ok := r.Renderer.(ChunkRenderer); ok {
+		return cr.RenderChunks(w, o, chunks)
+	}
+	return r.Render(w, o)
+}
+
+func (r serialized) serial() {}
+
+// chunkRenderer pairs a full render with an update from changed chunks.
+type chunkRenderer struct {
+	render       RendererFunc
+	renderChunks func(w world.World, o Options, chunks []world.Chunk) error
+}
+
+func (r chunkRenderer) Render(w world.World, o Options) error {
+	return r.render(w, o)
+}
+
+func (r chunkRenderer) RenderChunks(w world.World, o Options, chunks []world.Chunk) error {
+	return r.renderChunks(w, o, chunks)
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = make(map[string]Renderer)
+)
+
+// Register makes a renderer available by name. It panics if the name is
+// taken, as registering twice is a programming error.
+func Register(name string, r Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	if r == nil {
+		panic("render: Register renderer is nil")
+	}
+	if _, dup := renderers[name]; dup {
+		panic("render: Register called twice for renderer " + name)
+	}
+	renderers[name] = r
+}
+
+// Renderers lists the names of the registered renderers.
+func Renderers() []string {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the renderer registered under name.
+func Lookup(name string) (Renderer, error) {
+	renderersMu.RLock()
+	r, ok := renderers[name]
+	renderersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no renderer named %v, use one of %v", name, strings.Join(Renderers(), ", "))
+	}
+	return r, nil
+}
+
+// Run renders the whole world with each of the named renderers at once,
+// except those drawing images, which run one after another.
+func Run(w world.World, o Options, names []string) error {
+	return run(names, func(r Renderer) error {
+		return r.Render(w, o)
+	})
+}
+
+// RunChunks brings the output of each of the named renderers up to date
+// with the changed chunks as Run does, rendering in full with those that
+// can't do it chunk by chunk.
+func RunChunks(w world.World, o Options, names []string, chunks []world.Chunk) error {
+	return run(names, func(r Renderer) error {
+		if cr, ok := r.(ChunkRenderer); ok {
+			return cr.RenderChunks(w, o, chunks)
+		}
+		return r.Render(w, o)
+	})
+}
+
+// run looks up every renderer before starting any, then runs them and
+// returns the first error. Serial renderers take turns in one goroutine,
+// in the order named, and stop at the first of them to fail.
+func run(names []string, f func(Renderer) error) error {
+	rs := make([]Renderer, len(names))
+	for i, name := range names {
+		r, err := Lookup(name)
+		if err != nil {
+			return err
+		}
+		rs[i] = r
+	}
+
+	errs := make([]error, len(rs))
+	render := func(i int) error {
+		if err := f(rs[i]); err != nil {
+			errs[i] = fmt.Errorf("%v: %v", names[i], err)
+		}
+		return errs[i]
+	}
+
+	serial := []int{}
+	var wg sync.WaitGroup
+	for i, r := range rs {
+		if _, ok := r.(serialRenderer); ok {
+			serial = append(serial, i)
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			render(i)
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, i := range serial {
+			if render(i) != nil {
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
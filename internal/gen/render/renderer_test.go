@@ -0,0 +1,169 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+)
+
+// fakeRenderer records how it was run and fails with err when it's set.
+type fakeRenderer struct {
+	mu      sync.Mutex
+	full    int
+	chunks  []world.Chunk
+	err     error
+	running *concurrency
+}
+
+// concurrency tracks how many renderers sharing it run at once.
+type concurrency struct {
+	mu      sync.Mutex
+	now     int
+	highest int
+}
+
+func (c *concurrency) enter() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now++
+	if c.now > c.highest {
+		c.highest = c.now
+	}
+}
+
+func (c *concurrency) leave() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now--
+}
+
+func (r *fakeRenderer) Render(w world.World, o Options) error {
+	if r.running != nil {
+		r.running.enter()
+		defer r.running.leave()
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.full++
+	return r.err
+}
+
+// fakeChunkRenderer can also render just the changed chunks.
+type fakeChunkRenderer struct {
+	fakeRenderer
+}
+
+func (r *fakeChunkRenderer) RenderChunks(w world.World, o Options, chunks []world.Chunk) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chunks = append(r.chunks, chunks...)
+	return r.err
+}
+
+var (
+	fakePlain   = &fakeRenderer{}
+	fakeChunks  = &fakeChunkRenderer{}
+	fakeSerialA = &fakeRenderer{}
+	fakeSerialB = &fakeRenderer{}
+)
+
+func init() {
+	Register("test-plain", fakePlain)
+	Register("test-chunks", fakeChunks)
+	Register("test-serial-a", Serial(fakeSerialA))
+	Register("test-serial-b", Serial(fakeSerialB))
+}
+
+func resetFakes() {
+	running := &concurrency{}
+	for _, r := range []*fakeRenderer{fakePlain, &fakeChunks.fakeRenderer, fakeSerialA, fakeSerialB} {
+		r.full, r.chunks, r.err, r.running = 0, nil, nil, nil
+	}
+	fakeSerialA.running, fakeSerialB.running = running, running
+}
+
+func TestRun(t *testing.T) {
+	resetFakes()
+
+	if err := Run(world.World{}, Options{}, []string{"test-plain", "test-chunks"}); err != nil {
+		t.Fatal(err)
+	}
+	if fakePlain.full != 1 || fakeChunks.full != 1 {
+		t.Errorf("expected each renderer to run once, got %v and %v", fakePlain.full, fakeChunks.full)
+	}
+	if len(fakeChunks.chunks) != 0 {
+		t.Errorf("expected a full render, got chunks %v", fakeChunks.chunks)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	resetFakes()
+	fakeChunks.err = errors.New("disk full")
+
+	err := Run(world.World{}, Options{}, []string{"test-plain", "test-chunks"})
+	if err == nil || err.Error() != "test-chunks: disk full" {
+		t.Fatalf("expected the failing renderer's error with its name, got %v", err)
+	}
+	if fakePlain.full != 1 {
+		t.Errorf("expected the other renderer to still run, got %v", fakePlain.full)
+	}
+}
+
+func TestRunUnknown(t *testing.T) {
+	resetFakes()
+
+	err := Run(world.World{}, Options{}, []string{"test-plain", "test-missing"})
+	if err == nil || !strings.Contains(err.Error(), "test-missing") {
+		t.Fatalf("expected an error naming the unknown renderer, got %v", err)
+	}
+	if fakePlain.full != 0 {
+		t.Errorf("expected nothing to run, got %v", fakePlain.full)
+	}
+}
+
+func TestRunChunks(t *testing.T) {
+	resetFakes()
+	chunks := []world.Chunk{{X: 1, Y: 2}, {X: 3, Y: 4, Character: "Bruce"}}
+
+	if err := RunChunks(world.World{}, Options{}, []string{"test-plain", "test-chunks"}, chunks); err != nil {
+		t.Fatal(err)
+	}
+	if fakePlain.full != 1 {
+		t.Errorf("expected a renderer without chunk support to render in full, got %v", fakePlain.full)
+	}
+	if fakeChunks.full != 0 || len(fakeChunks.chunks) != 2 || fakeChunks.chunks[1] != chunks[1] {
+		t.Errorf("expected just the chunks, got %v full renders and chunks %v", fakeChunks.full, fakeChunks.chunks)
+	}
+}
+
+func TestRunSerial(t *testing.T) {
+	resetFakes()
+
+	if err := Run(world.World{}, Options{}, []string{"test-serial-a", "test-plain", "test-serial-b"}); err != nil {
+		t.Fatal(err)
+	}
+	if fakeSerialA.full != 1 || fakeSerialB.full != 1 || fakePlain.full != 1 {
+		t.Errorf("expected each renderer to run once, got %v, %v and %v", fakeSerialA.full, fakeSerialB.full, fakePlain.full)
+	}
+	if fakeSerialA.running.highest != 1 {
+		t.Errorf("expected serial renderers to run one at a time, %v ran at once", fakeSerialA.running.highest)
+	}
+}
+
+func TestRunSerialError(t *testing.T) {
+	resetFakes()
+	fakeSerialA.err = errors.New("out of memory")
+
+	err := RunChunks(world.World{}, Options{}, []string{"test-serial-a", "test-serial-b"}, []world.Chunk{{X: 1, Y: 2}})
+	if err == nil || err.Error() != "test-serial-a: out of memory" {
+		t.Fatalf("expected the failing renderer's error with its name, got %v", err)
+	}
+	if fakeSerialB.full != 0 {
+		t.Errorf("expected the serial renderers after a failure to be skipped, got %v", fakeSerialB.full)
+	}
+}
@@ -9,29 +9,33 @@ import (
 	"github.com/ralreegorganon/cddamap/internal/gen/world"
 )
 
-func Text(w world.World, outputRoot, overmapFilter string, includeLayers []int, terrain, seen, skipEmpty, cities bool) error {
-	err := os.MkdirAll(outputRoot, os.ModePerm)
+func init() {
+	Register("text", RendererFunc(Text))
+}
+
+func Text(w world.World, o Options) error {
+	err := os.MkdirAll(o.OutputRoot, os.ModePerm)
 	if err != nil {
 		return err
 	}
 
-	for _, layerID := range includeLayers {
-		if terrain {
-			err := terrainToText(w, outputRoot, overmapFilter, layerID, skipEmpty)
+	for _, layerID := range o.Layers {
+		if o.Terrain {
+			err := terrainToText(w, o.OutputRoot, o.OvermapFilter, layerID, o.SkipEmpty)
 			if err != nil {
 				return err
 			}
 		}
-		if seen {
-			err = seenToText(w, outputRoot, overmapFilter, layerID, skipEmpty)
+		if o.Seen {
+			err = seenToText(w, o.OutputRoot, o.OvermapFilter, layerID, o.SkipEmpty)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
-	if cities {
-		err = cityToText(w, outputRoot, overmapFilter)
+	if o.Cities {
+		err = cityToText(w, o.OutputRoot, o.OvermapFilter)
 		if err != nil {
 			return err
 		}
@@ -40,17 +44,24 @@ func Text(w world.World, outputRoot, overmapFilter string, includeLayers []int,
 }
 
 func terrainToText(w world.World, outputRoot, overmapFilter string, layerID int, skipEmpty bool) error {
-	l := w.TerrainLayers[layerID]
-
-	if l.Empty && skipEmpty {
+	if w.TerrainLayers.Empty[layerID] && skipEmpty {
 		return nil
 	}
 
+	blank := strings.Repeat(w.Blank().Symbol, 180)
+
 	var b strings.Builder
-	for _, r := range l.TerrainRows {
-		for _, k := range r.TerrainCellKeys {
-			c := w.TerrainCellLookup[k]
-			b.WriteString(c.Symbol)
+	for r := 0; r < w.Rows(); r++ {
+		for x := 0; x < w.Extent.XSize; x++ {
+			chunk, ok := w.TerrainLayers.Chunks[overmapOfRow(w, x, r)]
+			if !ok {
+				b.WriteString(blank)
+				continue
+			}
+			for _, k := range chunk[layerID][(r%180)*180 : (r%180+1)*180] {
+				c := w.Palette.Cells[k]
+				b.WriteString(c.Symbol)
+			}
 		}
 		b.WriteString("\n")
 	}
@@ -67,19 +78,27 @@ func terrainToText(w world.World, outputRoot, overmapFilter string, layerID int,
 }
 
 func seenToText(w world.World, outputRoot, overmapFilter string, layerID int, skipEmpty bool) error {
-	for name, layers := range w.SeenLayers {
-		l := layers[layerID]
+	unseen := strings.Repeat(w.SeenCellLookup[false].Symbol, 180)
 
-		if l.Empty && skipEmpty {
+	for _, name := range w.Characters() {
+		l := w.SeenLayers[name]
+
+		if l.Empty[layerID] && skipEmpty {
 			continue
 		}
 
 		var b strings.Builder
-		for _, r := range l.SeenRows {
-			for _, k := range r.SeenCellKeys {
-				cell := w.SeenCellLookup[k]
-				b.WriteString(cell.Symbol)
-
+		for r := 0; r < w.Rows(); r++ {
+			for x := 0; x < w.Extent.XSize; x++ {
+				chunk, ok := l.Chunks[overmapOfRow(w, x, r)]
+				if !ok {
+					b.WriteString(unseen)
+					continue
+				}
+				for _, k := range chunk[layerID][(r%180)*180 : (r%180+1)*180] {
+					cell := w.SeenCellLookup[k]
+					b.WriteString(cell.Symbol)
+				}
 			}
 			b.WriteString("\n")
 		}
@@ -97,13 +116,22 @@ func seenToText(w world.World, outputRoot, overmapFilter string, layerID int, sk
 }
 
 func cityToText(w world.World, outputRoot, overmapFilter string) error {
+	blank := strings.Repeat(" ", 180)
+
 	var b strings.Builder
-	for _, r := range w.CityLayer.CityRows {
-		for _, k := range r.CityCell {
-			if k == "" {
-				b.WriteString(" ")
-			} else {
-				b.WriteString(k)
+	for r := 0; r < w.Rows(); r++ {
+		for x := 0; x < w.Extent.XSize; x++ {
+			chunk, ok := w.CityLayer.Chunks[overmapOfRow(w, x, r)]
+			if !ok {
+				b.WriteString(blank)
+				continue
+			}
+			for _, k := range chunk[(r%180)*180 : (r%180+1)*180] {
+				if k == "" {
+					b.WriteString(" ")
+				} else {
+					b.WriteString(k)
+				}
 			}
 		}
 		b.WriteString("\n")
@@ -119,3 +147,9 @@ func cityToText(w world.World, outputRoot, overmapFilter string) error {
 	f.WriteString(b.String())
 	return nil
 }
+
+// overmapOfRow returns the overmap that's the xth across the world on the
+// given row.
+func overmapOfRow(w world.World, x, row int) world.Point {
+	return world.Point{X: w.Extent.XMin + x, Y: w.Extent.YMin + row/180}
+}
@@ -0,0 +1,123 @@
+package save
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ChunkRef names a chunk of the save by the game's om_x and om_y. Character
+// is set for a character's seen chunk and empty for an overmap chunk.
+type ChunkRef struct {
+	Character string
+	X         int
+	Y         int
+}
+
+// Cache remembers the parsed chunks of a save by a hash of the file each was
+// read from, so BuildCached can skip the ones that haven't changed. Key is
+// for the caller, to record what else the cached output depends on.
+type Cache struct {
+	Key    string
+	Chunks map[string]cachedChunk
+}
+
+type cachedChunk struct {
+	Hash      string
+	Character string
+	Overmap   *OvermapChunk
+	Seen      *SeenChunk
+}
+
+func (c cachedChunk) ref() ChunkRef {
+	if c.Seen != nil {
+		return ChunkRef{Character: c.Character, X: c.Seen.X, Y: c.Seen.Y}
+	}
+	return ChunkRef{X: c.Overmap.X, Y: c.Overmap.Y}
+}
+
+// NewCache returns an empty cache.
+func NewCache() *Cache {
+	return &Cache{Chunks: make(map[string]cachedChunk)}
+}
+
+// LoadCache reads a cache written by Cache.Write, returning an empty one if
+// there is none yet.
+func LoadCache(filename string) (*Cache, error) {
+	c := NewCache()
+
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := gob.NewDecoder(f).Decode(c); err != nil {
+		return nil, fmt.Errorf("reading chunk cache %v: %v", filename, err)
+	}
+	if c.Chunks == nil {
+		c.Chunks = make(map[string]cachedChunk)
+	}
+	return c, nil
+}
+
+// Write saves the cache to filename, replacing it only once it's complete
+// so an interrupted run can't leave a corrupt cache behind.
+func (c *Cache) Write(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(c); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Len returns the number of chunks in the cache.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.Chunks)
+}
+
+func (c *Cache) key(save, f string, b []byte) (string, string) {
+	if c == nil {
+		return f, ""
+	}
+	rel, err := filepath.Rel(save, f)
+	if err != nil {
+		rel = f
+	}
+	return filepath.ToSlash(rel), fmt.Sprintf("%x", sha1.Sum(b))
+}
+
+func (c *Cache) lookup(rel, hash string) (cachedChunk, bool) {
+	if c == nil {
+		return cachedChunk{}, false
+	}
+	cc, ok := c.Chunks[rel]
+	return cc, ok && cc.Hash == hash
+}
+
+func (c *Cache) put(rel string, cc cachedChunk) {
+	if c != nil {
+		c.Chunks[rel] = cc
+	}
+}
@@ -0,0 +1,218 @@
+package save
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+const (
+	testOvermap = `# version 33
+{"layers": [[["field", 32400]]], "cities": [{"name": "Spenard", "x": 10, "y": 20, "size": 3}]}`
+	testOvermapChanged = `# version 33
+{"layers": [[["forest", 32400]]], "cities": []}`
+	testSeen = `# version 33
+{"visible": [[[true, 1], [false, 32399]]], "explored": []}`
+)
+
+// testSave writes a save with two overmaps and a seen chunk into a temporary
+// directory, returning it and a cache file beside it.
+func testSave(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "cddamap-save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := filepath.Join(dir, "Spenard")
+	for name, contents := range map[string]string{
+		"mods.json":               `["dda"]`,
+		"overmaps/o.0.0":          testOvermap,
+		"overmaps/o.1.0":          testOvermap,
+		"overmaps/#Qg==.seen.0.0": testSeen,
+	} {
+		writeFile(t, filepath.Join(save, name), contents)
+	}
+	return save, filepath.Join(dir, "cache")
+}
+
+func writeFile(t *testing.T, filename, contents string) {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// buildCached builds the save with the cache in filename, writes the cache
+// back and returns the changes.
+func buildCached(t *testing.T, save, filename string) (Save, []ChunkRef) {
+	cache, err := LoadCache(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, changes, err := BuildCached(save, "", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Write(filename); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Character != changes[j].Character {
+			return changes[i].Character < changes[j].Character
+		}
+		return changes[i].X < changes[j].X
+	})
+	return s, changes
+}
+
+func expectChanges(t *testing.T, got []ChunkRef, want ...ChunkRef) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected changes %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected changes %v, got %v", want, got)
+		}
+	}
+}
+
+var allChunks = []ChunkRef{{X: 0, Y: 0}, {X: 1, Y: 0}, {Character: "#Qg==", X: 0, Y: 0}}
+
+func TestBuildCachedHit(t *testing.T) {
+	save, cache := testSave(t)
+	defer os.RemoveAll(filepath.Dir(save))
+
+	_, changes := buildCached(t, save, cache)
+	expectChanges(t, changes, allChunks...)
+
+	s, changes := buildCached(t, save, cache)
+	expectChanges(t, changes)
+
+	if len(s.Overmap.Chunks) != 2 || len(s.Seen["#Qg=="].Chunks) != 1 {
+		t.Fatalf("expected the cached chunks in the save, got %+v", s)
+	}
+	for _, c := range s.Overmap.Chunks {
+		if c.Layers[0][0].OvermapTerrainID != "field" || len(c.Cities) != 1 || c.Cities[0].Name != "Spenard" {
+			t.Errorf("unexpected cached overmap %+v", c)
+		}
+	}
+	if seen := s.Seen["#Qg=="].Chunks[0]; !seen.Visible[0][0].Seen || seen.Visible[0][1].Count != 32399 {
+		t.Errorf("unexpected cached seen chunk %+v", seen)
+	}
+	if len(s.Mods) != 1 || s.Name != "Spenard" {
+		t.Errorf("unexpected save %+v", s)
+	}
+}
+
+func TestBuildCachedChanged(t *testing.T) {
+	save, cache := testSave(t)
+	defer os.RemoveAll(filepath.Dir(save))
+
+	buildCached(t, save, cache)
+	writeFile(t, filepath.Join(save, "overmaps", "o.1.0"), testOvermapChanged)
+
+	s, changes := buildCached(t, save, cache)
+	expectChanges(t, changes, ChunkRef{X: 1, Y: 0})
+
+	for _, c := range s.Overmap.Chunks {
+		want := "field"
+		if c.X == 1 {
+			want = "forest"
+		}
+		if c.Layers[0][0].OvermapTerrainID != want {
+			t.Errorf("overmap %v,%v: expected %v, got %v", c.X, c.Y, want, c.Layers[0][0].OvermapTerrainID)
+		}
+	}
+
+	_, changes = buildCached(t, save, cache)
+	expectChanges(t, changes)
+}
+
+func TestBuildCachedDeleted(t *testing.T) {
+	save, cache := testSave(t)
+	defer os.RemoveAll(filepath.Dir(save))
+
+	buildCached(t, save, cache)
+	if err := os.Remove(filepath.Join(save, "overmaps", "#Qg==.seen.0.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	s, changes := buildCached(t, save, cache)
+	expectChanges(t, changes, ChunkRef{Character: "#Qg==", X: 0, Y: 0})
+	if len(s.Seen) != 0 {
+		t.Errorf("expected no seen chunks, got %+v", s.Seen)
+	}
+
+	c, err := LoadCache(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected the deleted chunk to leave the cache, got %v chunks", c.Len())
+	}
+}
+
+func TestLoadCacheCorrupt(t *testing.T) {
+	save, cache := testSave(t)
+	defer os.RemoveAll(filepath.Dir(save))
+
+	buildCached(t, save, cache)
+	writeFile(t, cache, "not a cache")
+
+	if _, err := LoadCache(cache); err == nil {
+		t.Fatal("expected an error reading a corrupt cache")
+	}
+}
+
+func TestLoadCacheOldFormat(t *testing.T) {
+	save, cache := testSave(t)
+	defer os.RemoveAll(filepath.Dir(save))
+
+	// Caches from before chunks were hashed kept only the parsed chunks,
+	// which decode without a hash and so never match.
+	type oldChunk struct {
+		Overmap *OvermapChunk
+	}
+	type oldCache struct {
+		Key    string
+		Chunks map[string]oldChunk
+	}
+	stale := OvermapChunk{X: 0, Y: 0}
+	f, err := os.Create(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gob.NewEncoder(f).Encode(oldCache{Key: "old", Chunks: map[string]oldChunk{"overmaps/o.0.0": {Overmap: &stale}}})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, changes := buildCached(t, save, cache)
+	expectChanges(t, changes, allChunks...)
+	for _, c := range s.Overmap.Chunks {
+		if len(c.Layers) != 1 {
+			t.Errorf("expected overmap %v,%v to be parsed again, got %+v", c.X, c.Y, c)
+		}
+	}
+}
+
+func TestBuildCachedNil(t *testing.T) {
+	save, _ := testSave(t)
+	defer os.RemoveAll(filepath.Dir(save))
+
+	for i := 0; i < 2; i++ {
+		_, changes, err := BuildCached(save, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != len(allChunks) {
+			t.Errorf("expected every chunk to be parsed without a cache, got %v", changes)
+		}
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"hash/fnv"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Save struct {
@@ -85,27 +85,47 @@ func (sg *SeenGroup) UnmarshalJSON(bs []byte) error {
 }
 
 func Build(save, filter string) (Save, error) {
+	s, _, err := BuildCached(save, filter, nil)
+	return s, err
+}
+
+// BuildCached builds the save like Build, but only parses the chunk files
+// whose contents have changed since they were put in the cache, and returns
+// those chunks along with any that have gone. The cache is updated to match
+// the save. A nil cache parses everything.
+func BuildCached(save, filter string, cache *Cache) (Save, []ChunkRef, error) {
 	s := Save{}
+	changes := []ChunkRef{}
+	found := make(map[string]bool)
 
-	o, err := overmapFromSave(save, filter)
+	o, err := overmapFromSave(save, filter, cache, found, &changes)
 	if err != nil {
-		return s, err
+		return s, nil, err
 	}
 
-	cs, err := characterSeenFromSave(save, filter)
+	cs, err := characterSeenFromSave(save, filter, cache, found, &changes)
 	if err != nil {
-		return s, err
+		return s, nil, err
+	}
+
+	if cache != nil {
+		for rel, c := range cache.Chunks {
+			if !found[rel] {
+				changes = append(changes, c.ref())
+				delete(cache.Chunks, rel)
+			}
+		}
 	}
 
 	saveModsPath := filepath.Join(save, "mods.json")
 	b, err := ioutil.ReadFile(saveModsPath)
 	if err != nil {
-		return s, err
+		return s, nil, err
 	}
 	var mods []string
 	err = json.Unmarshal(b, &mods)
 	if err != nil {
-		return s, err
+		return s, nil, err
 	}
 
 	name := filepath.Base(save)
@@ -117,12 +137,26 @@ func Build(save, filter string) (Save, error) {
 		Seen:    cs,
 	}
 
-	return s, nil
+	return s, changes, nil
+}
+
+var overmapChunkFile = regexp.MustCompile(`o\.-?\d+\.-?\d+$`)
+var seenChunkFile = regexp.MustCompile(`\.seen\.-?\d+\.-?\d+$`)
+
+// IsOvermapChunkFile reports whether path names an overmap chunk, o.X.Y.
+func IsOvermapChunkFile(path string) bool {
+	return overmapChunkFile.MatchString(path)
 }
 
-func overmapFromSave(save, filter string) (Overmap, error) {
+// IsSeenChunkFile reports whether path names a character's seen chunk,
+// <character>.seen.X.Y.
+func IsSeenChunkFile(path string) bool {
+	return seenChunkFile.MatchString(path)
+}
+
+func overmapFromSave(save, filter string, cache *Cache, found map[string]bool, changes *[]ChunkRef) (Overmap, error) {
 	o := Overmap{}
-	chunkFiles, err := overmapChunkFiles(save, filter)
+	chunkFiles, err := chunkFiles(save, filter, overmapChunkFile)
 	if err != nil {
 		return o, err
 	}
@@ -130,38 +164,25 @@ func overmapFromSave(save, filter string) (Overmap, error) {
 	chunks := make([]OvermapChunk, 0)
 
 	for _, f := range chunkFiles {
-		t, err := ioutil.ReadFile(f)
+		b, err := ioutil.ReadFile(f)
 		if err != nil {
 			return o, err
 		}
 
-		lines := strings.Split(string(t), "\n")
-
-		if !strings.HasPrefix(lines[0], "# version 33") {
-			return o, fmt.Errorf("unsupported version: %v", lines[0])
-		}
-
-		var buffer bytes.Buffer
-		for i := 1; i < len(lines); i++ {
-			buffer.WriteString(lines[i])
-		}
-
-		pruned := buffer.Bytes()
-
-		var chunk OvermapChunk
-		err = json.Unmarshal(pruned, &chunk)
-		if err != nil {
-			return o, err
+		rel, hash := cache.key(save, f, b)
+		found[rel] = true
+		if c, ok := cache.lookup(rel, hash); ok && c.Overmap != nil {
+			chunks = append(chunks, *c.Overmap)
+			continue
 		}
 
-		x, y, err := chunkFileNameToCoordinates(f)
+		chunk, err := parseOvermapChunk(f, b)
 		if err != nil {
 			return o, err
 		}
-		chunk.X = x
-		chunk.Y = y
-
 		chunks = append(chunks, chunk)
+		*changes = append(*changes, ChunkRef{X: chunk.X, Y: chunk.Y})
+		cache.put(rel, cachedChunk{Hash: hash, Overmap: &chunk})
 	}
 
 	o = Overmap{
@@ -170,9 +191,49 @@ func overmapFromSave(save, filter string) (Overmap, error) {
 	return o, nil
 }
 
-func overmapChunkFiles(root, filter string) ([]string, error) {
+// ReadOvermapChunk reads one overmap chunk file.
+func ReadOvermapChunk(f string) (OvermapChunk, error) {
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		return OvermapChunk{}, err
+	}
+	return parseOvermapChunk(f, b)
+}
+
+func parseOvermapChunk(f string, b []byte) (OvermapChunk, error) {
+	var chunk OvermapChunk
+	if err := parseChunk(b, &chunk); err != nil {
+		return chunk, err
+	}
+
+	x, y, err := chunkFileNameToCoordinates(f)
+	if err != nil {
+		return chunk, err
+	}
+	chunk.X = x
+	chunk.Y = y
+	return chunk, nil
+}
+
+func parseChunk(t []byte, chunk interface{}) error {
+	lines := strings.Split(string(t), "\n")
+
+	if !strings.HasPrefix(lines[0], "# version 33") {
+		return fmt.Errorf("unsupported version: %v", lines[0])
+	}
+
+	var buffer bytes.Buffer
+	for i := 1; i < len(lines); i++ {
+		buffer.WriteString(lines[i])
+	}
+
+	pruned := buffer.Bytes()
+
+	return json.Unmarshal(pruned, chunk)
+}
+
+func chunkFiles(root, filter string, re *regexp.Regexp) ([]string, error) {
 	files := []string{}
-	re := regexp.MustCompile(`o\.-?\d+\.-?\d+$`)
 
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -187,8 +248,7 @@ func overmapChunkFiles(root, filter string) ([]string, error) {
 			}
 		}
 
-		isOvermapChunk := re.MatchString(path)
-		if isOvermapChunk {
+		if re.MatchString(path) {
 			files = append(files, path)
 		}
 
@@ -201,6 +261,34 @@ func overmapChunkFiles(root, filter string) ([]string, error) {
 	return files, nil
 }
 
+// ChunkTimes returns the modification time of every overmap and seen chunk
+// file in the save, so callers can tell which ones the game has rewritten.
+func ChunkTimes(save, filter string) (map[string]time.Time, error) {
+	times := make(map[string]time.Time)
+
+	err := filepath.Walk(save, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if filter != "" && !strings.HasSuffix(path, filter) {
+			return nil
+		}
+
+		if IsOvermapChunkFile(path) || IsSeenChunkFile(path) {
+			times[path] = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return times, nil
+}
+
 func chunkFileNameToCoordinates(chunkFile string) (int, int, error) {
 	_, file := filepath.Split(chunkFile)
 	parts := strings.Split(file, ".")
@@ -215,42 +303,35 @@ func chunkFileNameToCoordinates(chunkFile string) (int, int, error) {
 	return x, y, nil
 }
 
-func characterSeenFromSave(save, filter string) (map[string]Seen, error) {
+func characterSeenFromSave(save, filter string, cache *Cache, found map[string]bool, changes *[]ChunkRef) (map[string]Seen, error) {
 	s := make(map[string]Seen)
 
-	chunkFiles, err := characterSeenChunkFiles(save, filter)
+	chunkFiles, err := chunkFiles(save, filter, seenChunkFile)
 	if err != nil {
 		return s, err
 	}
 
 	for _, f := range chunkFiles {
-		t, err := ioutil.ReadFile(f)
+		b, err := ioutil.ReadFile(f)
 		if err != nil {
 			return s, err
 		}
 
-		lines := strings.Split(string(t), "\n")
-
-		if !strings.HasPrefix(lines[0], "# version 33") {
-			return s, fmt.Errorf("unsupported version: %v", lines[0])
-		}
-
-		var buffer bytes.Buffer
-		for i := 1; i < len(lines); i++ {
-			buffer.WriteString(lines[i])
-		}
-
-		pruned := buffer.Bytes()
-
+		var name string
 		var chunk SeenChunk
-		err = json.Unmarshal(pruned, &chunk)
-		if err != nil {
-			return s, err
+		rel, hash := cache.key(save, f, b)
+		found[rel] = true
+		if c, ok := cache.lookup(rel, hash); ok && c.Seen != nil {
+			name, chunk = c.Character, *c.Seen
+		} else {
+			name, chunk, err = parseSeenChunk(f, b)
+			if err != nil {
+				return s, err
+			}
+			*changes = append(*changes, ChunkRef{Character: name, X: chunk.X, Y: chunk.Y})
+			cache.put(rel, cachedChunk{Hash: hash, Character: name, Seen: &chunk})
 		}
 
-		parts := strings.Split(filepath.Base(f), ".")
-		name := parts[0]
-
 		if _, ok := s[name]; !ok {
 			s[name] = Seen{
 				Character: name,
@@ -258,13 +339,6 @@ func characterSeenFromSave(save, filter string) (map[string]Seen, error) {
 			}
 		}
 
-		x, y, err := characterSeenFileNameToCoordinates(f)
-		if err != nil {
-			return s, err
-		}
-		chunk.X = x
-		chunk.Y = y
-
 		seen := s[name]
 		seen.Chunks = append(seen.Chunks, chunk)
 		s[name] = seen
@@ -273,35 +347,65 @@ func characterSeenFromSave(save, filter string) (map[string]Seen, error) {
 	return s, nil
 }
 
-func characterSeenChunkFiles(root, filter string) ([]string, error) {
-	files := []string{}
-	re := regexp.MustCompile(`\.seen\.-?\d\.-?\d$`)
+// ReadSeenChunk reads one character's seen chunk file, returning the
+// character's name with it.
+func ReadSeenChunk(f string) (string, SeenChunk, error) {
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		return "", SeenChunk{}, err
+	}
+	return parseSeenChunk(f, b)
+}
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if filter != "" {
-			if !strings.HasSuffix(path, filter) {
-				return nil
-			}
-		}
+func parseSeenChunk(f string, b []byte) (string, SeenChunk, error) {
+	var chunk SeenChunk
+	if err := parseChunk(b, &chunk); err != nil {
+		return "", chunk, err
+	}
 
-		isCharacterSeenChunk := re.MatchString(path)
-		if isCharacterSeenChunk {
-			files = append(files, path)
-		}
+	parts := strings.Split(filepath.Base(f), ".")
+	name := parts[0]
 
-		return nil
-	})
+	x, y, err := characterSeenFileNameToCoordinates(f)
 	if err != nil {
-		return nil, err
+		return "", chunk, err
 	}
+	chunk.X = x
+	chunk.Y = y
+	return name, chunk, nil
+}
 
-	return files, nil
+// SetOvermapChunk replaces the save's chunk at the same position as c, or
+// adds c if the save had none there.
+func (s *Save) SetOvermapChunk(c OvermapChunk) {
+	for i := range s.Overmap.Chunks {
+		if s.Overmap.Chunks[i].X == c.X && s.Overmap.Chunks[i].Y == c.Y {
+			s.Overmap.Chunks[i] = c
+			return
+		}
+	}
+	s.Overmap.Chunks = append(s.Overmap.Chunks, c)
+}
+
+// SetSeenChunk replaces what the named character has seen of the chunk at
+// the same position as c.
+func (s *Save) SetSeenChunk(name string, c SeenChunk) {
+	if s.Seen == nil {
+		s.Seen = make(map[string]Seen)
+	}
+	seen, ok := s.Seen[name]
+	if !ok {
+		seen = Seen{Character: name, Chunks: make([]SeenChunk, 0)}
+	}
+	for i := range seen.Chunks {
+		if seen.Chunks[i].X == c.X && seen.Chunks[i].Y == c.Y {
+			seen.Chunks[i] = c
+			s.Seen[name] = seen
+			return
+		}
+	}
+	seen.Chunks = append(seen.Chunks, c)
+	s.Seen[name] = seen
 }
 
 func characterSeenFileNameToCoordinates(chunkFile string) (int, int, error) {
@@ -317,9 +421,3 @@ func characterSeenFileNameToCoordinates(chunkFile string) (int, int, error) {
 	}
 	return x, y, nil
 }
-
-func HashTerrainID(s string) uint32 {
-	h := fnv.New32a()
-	h.Write([]byte(s))
-	return h.Sum32()
-}
@@ -0,0 +1,114 @@
+package world
+
+import (
+	"image"
+	"sort"
+)
+
+// Columns is how many cells wide the rendered world is.
+func (w World) Columns() int {
+	return 180 * w.Extent.XSize
+}
+
+// Rows is how many cells high the rendered world is.
+func (w World) Rows() int {
+	return 180 * w.Extent.YSize
+}
+
+// Overmaps returns the position of every overmap in the extent, present or
+// not, a row at a time from the top left.
+func (e Extent) Overmaps() []Point {
+	points := make([]Point, 0, e.XSize*e.YSize)
+	for y := e.YMin; y < e.YMin+e.YSize; y++ {
+		for x := e.XMin; x < e.XMin+e.XSize; x++ {
+			points = append(points, Point{X: x, Y: y})
+		}
+	}
+	return points
+}
+
+// OvermapsIn returns the position of every overmap, present or not, that
+// covers part of cells, a rectangle of columns and rows of the rendered
+// world, in the same order as Overmaps.
+func (e Extent) OvermapsIn(cells image.Rectangle) []Point {
+	cells = cells.Intersect(image.Rect(0, 0, 180*e.XSize, 180*e.YSize))
+	if cells.Empty() {
+		return nil
+	}
+
+	points := []Point{}
+	for y := cells.Min.Y / 180; y <= (cells.Max.Y-1)/180; y++ {
+		for x := cells.Min.X / 180; x <= (cells.Max.X-1)/180; x++ {
+			points = append(points, Point{X: e.XMin + x, Y: e.YMin + y})
+		}
+	}
+	return points
+}
+
+// Rect returns the rectangle of columns and rows of the rendered world
+// covered by the overmap at p.
+func (e Extent) Rect(p Point) image.Rectangle {
+	column, row := e.Cells(p.X, p.Y)
+	return image.Rect(column, row, column+180, row+180)
+}
+
+// locate returns the overmap holding the cell at column, row of the rendered
+// world and the cell's index within the overmap's chunk.
+func (e Extent) locate(column, row int) (Point, int) {
+	p := Point{X: e.XMin + column/180, Y: e.YMin + row/180}
+	return p, (row%180)*180 + column%180
+}
+
+// Blank is the cell shown where there's no overmap.
+func (w World) Blank() TerrainCell {
+	return w.Palette.Cells[blankIndex]
+}
+
+// TerrainIndex returns the palette index of the terrain of a layer at
+// column, row of the rendered world.
+func (w World) TerrainIndex(layer, column, row int) uint16 {
+	p, i := w.Extent.locate(column, row)
+	if c, ok := w.TerrainLayers.Chunks[p]; ok {
+		return c[layer][i]
+	}
+	return blankIndex
+}
+
+// Terrain returns the terrain of a layer at column, row of the rendered
+// world, blank where there's no overmap.
+func (w World) Terrain(layer, column, row int) TerrainCell {
+	return w.Palette.Cells[w.TerrainIndex(layer, column, row)]
+}
+
+// Seen returns whether the named character has seen the cell of a layer at
+// column, row of the rendered world.
+func (w World) Seen(name string, layer, column, row int) SeenCell {
+	seen := false
+	if l, ok := w.SeenLayers[name]; ok {
+		p, i := w.Extent.locate(column, row)
+		if c, ok := l.Chunks[p]; ok {
+			seen = c[layer][i]
+		}
+	}
+	return w.SeenCellLookup[seen]
+}
+
+// CityLabel returns the letter of a city name written at column, row of the
+// rendered world, if there is one.
+func (w World) CityLabel(column, row int) string {
+	p, i := w.Extent.locate(column, row)
+	if c, ok := w.CityLayer.Chunks[p]; ok {
+		return c[i]
+	}
+	return ""
+}
+
+// Characters returns the names of the characters with seen layers, sorted.
+func (w World) Characters() []string {
+	names := make([]string, 0, len(w.SeenLayers))
+	for name := range w.SeenLayers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
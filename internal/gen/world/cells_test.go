@@ -0,0 +1,225 @@
+package world
+
+import (
+	"image"
+	"testing"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+)
+
+// testChunk is an overmap of open air but for the layers given.
+func testChunk(x, y int, layers map[int][]save.TerrainGroup, cities ...save.City) save.OvermapChunk {
+	c := save.OvermapChunk{X: x, Y: y, Cities: cities}
+	for i := 0; i < 21; i++ {
+		l, ok := layers[i]
+		if !ok {
+			l = []save.TerrainGroup{{OvermapTerrainID: "open_air", Count: 32400}}
+		}
+		c.Layers = append(c.Layers, l)
+	}
+	return c
+}
+
+// testSave has two overmaps that don't touch, at -1,0 and 1,1, so the world
+// is three overmaps wide and two high with four of them missing.
+func testSave() save.Save {
+	return save.Save{
+		Name: "Spenard",
+		Overmap: save.Overmap{Chunks: []save.OvermapChunk{
+			testChunk(-1, 0, map[int][]save.TerrainGroup{
+				10: {{OvermapTerrainID: "field", Count: 1}, {OvermapTerrainID: "forest", Count: 32398}, {OvermapTerrainID: "lake", Count: 1}},
+				12: {{OvermapTerrainID: "empty_rock", Count: 32400}},
+			}, save.City{Name: "Spenard", X: 10, Y: 20, Size: 3}),
+			testChunk(1, 1, map[int][]save.TerrainGroup{
+				11: {{OvermapTerrainID: "house", Count: 1}, {OvermapTerrainID: "open_air", Count: 32399}},
+				12: {{OvermapTerrainID: "empty_rock", Count: 32400}},
+			}, save.City{Name: "Muldoon", X: 5, Y: 6, Size: 2}),
+		}},
+		Seen: map[string]save.Seen{
+			"Bruce": {Character: "Bruce", Chunks: []save.SeenChunk{
+				{X: -1, Y: 0, Visible: [][]save.SeenGroup{10: {{Seen: true, Count: 1}, {Seen: false, Count: 32399}}}},
+				{X: 5, Y: 5, Visible: [][]save.SeenGroup{10: {{Seen: true, Count: 32400}}}},
+			}},
+		},
+	}
+}
+
+func testWorld(t *testing.T) World {
+	w, err := Build(metadata.Overmap{}, testSave(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestExtent(t *testing.T) {
+	w := testWorld(t)
+
+	if w.Extent != (Extent{XMin: -1, YMin: 0, XSize: 3, YSize: 2}) {
+		t.Fatalf("unexpected extent: %+v", w.Extent)
+	}
+	if w.Columns() != 540 || w.Rows() != 360 {
+		t.Errorf("expected 540 x 360 cells, got %v x %v", w.Columns(), w.Rows())
+	}
+	if len(w.TerrainLayers.Chunks) != 2 {
+		t.Errorf("expected only the 2 overmaps in the save to be stored, got %v", len(w.TerrainLayers.Chunks))
+	}
+
+	overmaps := w.Extent.Overmaps()
+	if len(overmaps) != 6 || overmaps[0] != (Point{X: -1, Y: 0}) || overmaps[5] != (Point{X: 1, Y: 1}) {
+		t.Errorf("unexpected overmaps: %v", overmaps)
+	}
+	if r := w.Extent.Rect(Point{X: 1, Y: 1}); r.Min.X != 360 || r.Min.Y != 180 || r.Dx() != 180 || r.Dy() != 180 {
+		t.Errorf("unexpected rect: %v", r)
+	}
+}
+
+func TestOvermapsIn(t *testing.T) {
+	e := testWorld(t).Extent
+
+	tests := []struct {
+		cells image.Rectangle
+		want  []Point
+	}{
+		{image.Rect(0, 0, 180, 180), []Point{{X: -1, Y: 0}}},
+		{image.Rect(179, 0, 181, 1), []Point{{X: -1, Y: 0}, {X: 0, Y: 0}}},
+		{image.Rect(-10, 170, 370, 190), []Point{{X: -1, Y: 0}, {X: 0, Y: 0}, {X: 1, Y: 0}, {X: -1, Y: 1}, {X: 0, Y: 1}, {X: 1, Y: 1}}},
+		{image.Rect(360, 180, 540, 360), []Point{{X: 1, Y: 1}}},
+		{image.Rect(540, 0, 600, 10), nil},
+	}
+	for _, tt := range tests {
+		got := e.OvermapsIn(tt.cells)
+		if len(got) != len(tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.cells, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%v: expected %v, got %v", tt.cells, tt.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestTerrain(t *testing.T) {
+	w := testWorld(t)
+
+	tests := []struct {
+		layer, column, row int
+		id                 string
+	}{
+		{10, 0, 0, "field"},
+		{10, 1, 0, "forest"},
+		{10, 0, 1, "forest"},
+		{10, 179, 179, "lake"},
+		{10, 180, 0, ""},
+		{10, 0, 180, ""},
+		{10, 359, 180, ""},
+		{10, 539, 359, "open_air"},
+		{11, 0, 0, "open_air"},
+		{11, 360, 180, "house"},
+		{11, 361, 180, "open_air"},
+		{11, 360, 181, "open_air"},
+		{12, 0, 0, "empty_rock"},
+		{12, 270, 90, ""},
+	}
+	for _, tt := range tests {
+		if id := w.Terrain(tt.layer, tt.column, tt.row).ID; id != tt.id {
+			t.Errorf("layer %v at %v,%v: expected %q, got %q", tt.layer, tt.column, tt.row, tt.id, id)
+		}
+	}
+
+	if i := w.TerrainIndex(10, 200, 200); i != blankIndex {
+		t.Errorf("expected a missing overmap to read as blank, got %v", i)
+	}
+	if w.Blank().ID != "" {
+		t.Errorf("unexpected blank cell: %+v", w.Blank())
+	}
+}
+
+func TestEmptyLayers(t *testing.T) {
+	w := testWorld(t)
+
+	tests := []struct {
+		layer int
+		empty bool
+	}{
+		{0, true},
+		{9, true},
+		{10, false},
+		{11, false},
+		{12, true},
+		{20, true},
+	}
+	for _, tt := range tests {
+		if w.TerrainLayers.Empty[tt.layer] != tt.empty {
+			t.Errorf("terrain layer %v: expected empty %v", tt.layer, tt.empty)
+		}
+	}
+
+	seen := w.SeenLayers["Bruce"]
+	if seen.Empty[10] || !seen.Empty[11] {
+		t.Errorf("unexpected empty seen layers: %v", seen.Empty)
+	}
+}
+
+func TestSeen(t *testing.T) {
+	w := testWorld(t)
+
+	if len(w.SeenLayers["Bruce"].Chunks) != 1 {
+		t.Errorf("expected seen overmaps outside the world to be dropped, got %v", len(w.SeenLayers["Bruce"].Chunks))
+	}
+
+	tests := []struct {
+		name               string
+		layer, column, row int
+		seen               bool
+	}{
+		{"Bruce", 10, 0, 0, true},
+		{"Bruce", 10, 1, 0, false},
+		{"Bruce", 11, 0, 0, false},
+		{"Bruce", 10, 360, 180, false},
+		{"Bruce", 10, 180, 0, false},
+		{"Jack", 10, 0, 0, false},
+	}
+	for _, tt := range tests {
+		if s := w.Seen(tt.name, tt.layer, tt.column, tt.row); s.Seen != tt.seen {
+			t.Errorf("%v on layer %v at %v,%v: expected seen %v", tt.name, tt.layer, tt.column, tt.row, tt.seen)
+		}
+	}
+}
+
+func TestCities(t *testing.T) {
+	w := testWorld(t)
+
+	want := []City{
+		{Name: "Spenard", X: 10, Y: 20, Size: 3},
+		{Name: "Muldoon", X: 365, Y: 186, Size: 2},
+	}
+	if len(w.CityLayer.Cities) != len(want) {
+		t.Fatalf("expected %v cities, got %+v", len(want), w.CityLayer.Cities)
+	}
+	for i, c := range want {
+		if w.CityLayer.Cities[i] != c {
+			t.Errorf("expected %+v, got %+v", c, w.CityLayer.Cities[i])
+		}
+	}
+
+	tests := []struct {
+		column, row int
+		label       string
+	}{
+		{7, 20, "S"},
+		{13, 20, "d"},
+		{14, 20, ""},
+		{362, 186, "M"},
+		{187, 20, ""},
+	}
+	for _, tt := range tests {
+		if l := w.CityLabel(tt.column, tt.row); l != tt.label {
+			t.Errorf("%v,%v: expected %q, got %q", tt.column, tt.row, tt.label, l)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package world
+
+import (
+	"fmt"
+	"math"
+)
+
+// The first indices of every palette are fixed, so a zeroed chunk is blank
+// and telling an empty cell apart needs no lookup.
+const (
+	blankIndex uint16 = iota
+	emptyRockIndex
+	openAirIndex
+)
+
+// Palette is every distinct terrain cell of a world. Terrain chunks hold
+// indices into Cells rather than the cells themselves, handed out densely
+// by terrain ID as each ID is first seen.
+type Palette struct {
+	Cells   []TerrainCell
+	indices map[string]uint16
+}
+
+// NewPalette returns a palette holding just the blank cell shown where
+// there's no overmap, empty rock and open air.
+func NewPalette(blank, emptyRock, openAir TerrainCell) Palette {
+	p := Palette{
+		indices: make(map[string]uint16),
+	}
+	for _, c := range []TerrainCell{blank, emptyRock, openAir} {
+		p.indices[c.ID] = uint16(len(p.Cells))
+		p.Cells = append(p.Cells, c)
+	}
+	return p
+}
+
+// Index returns the index of a terrain ID, if it's in the palette.
+func (p Palette) Index(id string) (uint16, bool) {
+	i, ok := p.indices[id]
+	return i, ok
+}
+
+// Add puts c in the palette under its ID, returning its index. IDs already
+// in the palette keep their index. It fails rather than wrap around once
+// every index is taken.
+func (p *Palette) Add(c TerrainCell) (uint16, error) {
+	if i, ok := p.indices[c.ID]; ok {
+		return i, nil
+	}
+	if len(p.Cells) > math.MaxUint16 {
+		return 0, fmt.Errorf("more than %v distinct terrains, can't add %v", math.MaxUint16+1, c.ID)
+	}
+	if p.indices == nil {
+		p.indices = make(map[string]uint16)
+	}
+
+	i := uint16(len(p.Cells))
+	p.indices[c.ID] = i
+	p.Cells = append(p.Cells, c)
+	return i, nil
+}
+
+// IsEmptyTerrain reports whether i is blank, empty rock or open air, which
+// leave a layer empty and aren't worth importing.
+func IsEmptyTerrain(i uint16) bool {
+	return i <= openAirIndex
+}
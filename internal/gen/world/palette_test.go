@@ -0,0 +1,90 @@
+package world
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func testPalette() Palette {
+	return NewPalette(TerrainCell{ID: ""}, TerrainCell{ID: "empty_rock"}, TerrainCell{ID: "open_air"})
+}
+
+func TestPaletteFixedIndices(t *testing.T) {
+	p := testPalette()
+
+	tests := []struct {
+		id    string
+		index uint16
+		empty bool
+	}{
+		{"", blankIndex, true},
+		{"empty_rock", emptyRockIndex, true},
+		{"open_air", openAirIndex, true},
+	}
+	for _, tt := range tests {
+		i, ok := p.Index(tt.id)
+		if !ok || i != tt.index {
+			t.Errorf("%q: expected index %v, got %v (%v)", tt.id, tt.index, i, ok)
+		}
+		if IsEmptyTerrain(i) != tt.empty {
+			t.Errorf("%q: expected empty %v", tt.id, tt.empty)
+		}
+	}
+	if blankIndex != 0 || emptyRockIndex != 1 || openAirIndex != 2 {
+		t.Errorf("fixed indices moved: %v %v %v", blankIndex, emptyRockIndex, openAirIndex)
+	}
+}
+
+func TestPaletteAdd(t *testing.T) {
+	p := testPalette()
+
+	tests := []struct {
+		id    string
+		index uint16
+	}{
+		{"field", 3},
+		{"forest", 4},
+		{"field", 3},
+		{"open_air", openAirIndex},
+		{"road_ns", 5},
+		{"forest", 4},
+	}
+	for _, tt := range tests {
+		i, err := p.Add(TerrainCell{ID: tt.id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i != tt.index {
+			t.Errorf("%q: expected index %v, got %v", tt.id, tt.index, i)
+		}
+		if p.Cells[i].ID != tt.id {
+			t.Errorf("%q: index %v holds %q", tt.id, i, p.Cells[i].ID)
+		}
+		if IsEmptyTerrain(i) != (i <= openAirIndex) {
+			t.Errorf("%q: unexpected emptiness", tt.id)
+		}
+	}
+	if len(p.Cells) != 6 {
+		t.Errorf("expected 6 cells, got %v", len(p.Cells))
+	}
+}
+
+func TestPaletteOverflow(t *testing.T) {
+	p := testPalette()
+	for len(p.Cells) <= math.MaxUint16 {
+		if _, err := p.Add(TerrainCell{ID: fmt.Sprintf("t%v", len(p.Cells))}); err != nil {
+			t.Fatalf("adding cell %v: %v", len(p.Cells), err)
+		}
+	}
+
+	if _, err := p.Add(TerrainCell{ID: "one_too_many"}); err == nil {
+		t.Error("expected an error once every index is taken")
+	}
+	if i, err := p.Add(TerrainCell{ID: "t3"}); err != nil || i != 3 {
+		t.Errorf("expected a full palette to still find t3 at 3, got %v, %v", i, err)
+	}
+	if i, _ := p.Index(fmt.Sprintf("t%v", math.MaxUint16)); i != math.MaxUint16 {
+		t.Errorf("expected the last index to be %v, got %v", math.MaxUint16, i)
+	}
+}
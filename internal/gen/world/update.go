@@ -0,0 +1,85 @@
+package world
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+)
+
+// Chunk identifies one overmap of the world by the game's om_x and om_y.
+// Character is set when only that character's seen layers changed, and is
+// empty when the overmap's terrain and cities did.
+type Chunk struct {
+	Character string
+	X         int
+	Y         int
+}
+
+// Contains reports whether the overmap at om_x, om_y is inside the extent.
+func (e Extent) Contains(x, y int) bool {
+	return x >= e.XMin && x < e.XMin+e.XSize && y >= e.YMin && y < e.YMin+e.YSize
+}
+
+// Cells returns the columns and rows of the rendered world covered by the
+// overmap at om_x, om_y.
+func (e Extent) Cells(x, y int) (int, int) {
+	return (x - e.XMin) * 180, (y - e.YMin) * 180
+}
+
+// UpdateChunk replaces the terrain and cities of one overmap with c. The
+// overmap must lie within the world's extent, growing the world takes a full
+// Build. Layers only ever go from empty to not, a layer emptied by the
+// update is still rendered.
+func (w *World) UpdateChunk(m metadata.Overmap, c save.OvermapChunk, symbolizeByLandUseCode bool) error {
+	if !w.Extent.Contains(c.X, c.Y) {
+		return fmt.Errorf("overmap %v,%v is outside the world", c.X, c.Y)
+	}
+
+	if err := w.TerrainLayers.set(m, &w.Palette, c, symbolizeByLandUseCode); err != nil {
+		return err
+	}
+
+	r := w.Extent.Rect(Point{X: c.X, Y: c.Y})
+	cities := make([]City, 0, len(w.CityLayer.Cities))
+	for _, city := range w.CityLayer.Cities {
+		if !image.Pt(city.X, city.Y).In(r) {
+			cities = append(cities, city)
+		}
+	}
+	w.CityLayer.Cities = cities
+	w.CityLayer.set(c, w.Extent)
+	return nil
+}
+
+// UpdateSeenChunk replaces what the named character has seen of one overmap
+// with c. The overmap must lie within the world's extent.
+func (w *World) UpdateSeenChunk(name string, c save.SeenChunk) error {
+	if !w.Extent.Contains(c.X, c.Y) {
+		return fmt.Errorf("overmap %v,%v is outside the world", c.X, c.Y)
+	}
+
+	layers, ok := w.SeenLayers[name]
+	if !ok {
+		layers = newSeenLayers()
+		if w.SeenLayers == nil {
+			w.SeenLayers = make(map[string]*SeenLayers)
+		}
+		w.SeenLayers[name] = layers
+	}
+	layers.set(c)
+	return nil
+}
+
+// ExtentOf returns the extent of the world Build would make from s, without
+// building it.
+func ExtentOf(s save.Save) Extent {
+	wcd := calculateWorldChunkDimensions(metadata.Overmap{}, s)
+	return Extent{
+		XMin:  wcd.XMin,
+		YMin:  wcd.YMin,
+		XSize: wcd.XSize,
+		YSize: wcd.YSize,
+	}
+}
@@ -0,0 +1,105 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/metadata"
+	"github.com/ralreegorganon/cddamap/internal/gen/save"
+)
+
+func TestUpdateChunk(t *testing.T) {
+	w := testWorld(t)
+	before := w.CityLayer.Cities
+
+	err := w.UpdateChunk(metadata.Overmap{}, testChunk(1, 1, map[int][]save.TerrainGroup{
+		10: {{OvermapTerrainID: "open_air", Count: 1}, {OvermapTerrainID: "road_ns", Count: 1}, {OvermapTerrainID: "open_air", Count: 32398}},
+	}, save.City{Name: "Eagle River", X: 50, Y: 60, Size: 4}), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before[0].Name != "Spenard" || before[1].Name != "Muldoon" {
+		t.Errorf("expected the cities from before the update to be left alone, got %+v", before)
+	}
+	want := []City{
+		{Name: "Spenard", X: 10, Y: 20, Size: 3},
+		{Name: "Eagle River", X: 410, Y: 240, Size: 4},
+	}
+	if len(w.CityLayer.Cities) != len(want) {
+		t.Fatalf("expected %v cities, got %+v", len(want), w.CityLayer.Cities)
+	}
+	for i, c := range want {
+		if w.CityLayer.Cities[i] != c {
+			t.Errorf("expected %+v, got %+v", c, w.CityLayer.Cities[i])
+		}
+	}
+
+	labels := []struct {
+		column, row int
+		label       string
+	}{
+		{362, 186, ""},
+		{405, 240, "E"},
+		{7, 20, "S"},
+	}
+	for _, tt := range labels {
+		if l := w.CityLabel(tt.column, tt.row); l != tt.label {
+			t.Errorf("%v,%v: expected %q, got %q", tt.column, tt.row, tt.label, l)
+		}
+	}
+
+	terrain := []struct {
+		layer, column, row int
+		id                 string
+	}{
+		{10, 361, 180, "road_ns"},
+		{10, 360, 180, "open_air"},
+		{11, 360, 180, "open_air"},
+		{10, 0, 0, "field"},
+	}
+	for _, tt := range terrain {
+		if id := w.Terrain(tt.layer, tt.column, tt.row).ID; id != tt.id {
+			t.Errorf("layer %v at %v,%v: expected %q, got %q", tt.layer, tt.column, tt.row, tt.id, id)
+		}
+	}
+	if w.TerrainLayers.Empty[10] || w.TerrainLayers.Empty[11] {
+		t.Error("expected layers to stay rendered once they have terrain")
+	}
+
+	if err := w.UpdateChunk(metadata.Overmap{}, testChunk(2, 0, nil), false); err == nil {
+		t.Error("expected an error updating an overmap outside the world")
+	}
+}
+
+func TestUpdateSeenChunk(t *testing.T) {
+	w := testWorld(t)
+
+	if err := w.UpdateSeenChunk("Bruce", save.SeenChunk{X: -1, Y: 0, Visible: [][]save.SeenGroup{10: {{Seen: false, Count: 32400}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.UpdateSeenChunk("Jack", save.SeenChunk{X: 1, Y: 1, Visible: [][]save.SeenGroup{11: {{Seen: true, Count: 32400}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name               string
+		layer, column, row int
+		seen               bool
+	}{
+		{"Bruce", 10, 0, 0, false},
+		{"Jack", 11, 360, 180, true},
+		{"Jack", 11, 0, 0, false},
+	}
+	for _, tt := range tests {
+		if s := w.Seen(tt.name, tt.layer, tt.column, tt.row); s.Seen != tt.seen {
+			t.Errorf("%v on layer %v at %v,%v: expected seen %v", tt.name, tt.layer, tt.column, tt.row, tt.seen)
+		}
+	}
+	if w.SeenLayers["Jack"].Empty[11] {
+		t.Error("expected Jack's layer 11 to have something seen")
+	}
+
+	if err := w.UpdateSeenChunk("Bruce", save.SeenChunk{X: 5, Y: 5}); err == nil {
+		t.Error("expected an error updating an overmap outside the world")
+	}
+}
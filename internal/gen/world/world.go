@@ -31,40 +31,63 @@ func abs(x int) int {
 }
 
 type World struct {
-	Name              string
-	TerrainLayers     []TerrainLayer
-	SeenLayers        map[string][]SeenLayer
-	TerrainCellLookup map[uint32]TerrainCell
-	SeenCellLookup    map[bool]SeenCell
-	CityLayer         CityLayer
+	Name           string
+	TerrainLayers  TerrainLayers
+	SeenLayers     map[string]*SeenLayers
+	Palette        Palette
+	SeenCellLookup map[bool]SeenCell
+	CityLayer      CityLayer
+	Extent         Extent
 }
 
-type TerrainLayer struct {
-	Empty       bool
-	TerrainRows []TerrainRow
+// Extent is the bounding rectangle of the world in overmap coordinates, where
+// XMin and YMin are the game's om_x and om_y of the top left overmap.
+type Extent struct {
+	XMin  int
+	YMin  int
+	XSize int
+	YSize int
 }
 
-type TerrainRow struct {
-	TerrainCellKeys []uint32
+// Point is the position of an overmap by the game's om_x and om_y.
+type Point struct {
+	X int
+	Y int
 }
 
-type TerrainCell struct {
-	Symbol  string
-	ColorFG color.RGBA
-	ColorBG color.RGBA
-	Name    string
-	ID      string
+// TerrainLayers holds the terrain of only the overmaps in the save, so a
+// world explored in far apart places costs no more than the overmaps it
+// has. Overmaps missing from Chunks are blank. Empty is kept per layer, a
+// layer is empty when it's nothing but rock, air and blanks.
+type TerrainLayers struct {
+	Empty  [21]bool
+	Chunks map[Point]*TerrainChunk
 }
 
-type SeenLayer struct {
-	Empty    bool
-	SeenRows []SeenRow
+// TerrainChunk is the terrain of one overmap, for each layer 180 rows of 180
+// indices into the world's Palette.
+type TerrainChunk [21][32400]uint16
+
+type TerrainCell struct {
+	Symbol      string
+	ColorFG     color.RGBA
+	ColorBG     color.RGBA
+	Name        string
+	ID          string
+	LandUseCode string
 }
 
-type SeenRow struct {
-	SeenCellKeys []bool
+// SeenLayers holds what a character has seen of the overmaps they have a
+// seen file for. Overmaps missing from Chunks are unseen.
+type SeenLayers struct {
+	Empty  [21]bool
+	Chunks map[Point]*SeenChunk
 }
 
+// SeenChunk is what a character has seen of one overmap, laid out like a
+// TerrainChunk.
+type SeenChunk [21][32400]bool
+
 type SeenCell struct {
 	ID      string
 	Symbol  string
@@ -73,14 +96,17 @@ type SeenCell struct {
 	ColorBG color.RGBA
 }
 
+// CityLayer holds the city names written across the overmaps that have
+// cities, one letter to a cell, and the cities themselves positioned by
+// column and row.
 type CityLayer struct {
-	CityRows []CityRow
-	Cities   []City
+	Chunks map[Point]*CityChunk
+	Cities []City
 }
 
-type CityRow struct {
-	CityCell []string
-}
+// CityChunk is the city names of one overmap, laid out like a layer of a
+// TerrainChunk.
+type CityChunk [32400]string
 
 type City struct {
 	Name string
@@ -91,8 +117,6 @@ type City struct {
 
 func Build(m metadata.Overmap, s save.Save, symbolizeByLandUseCode bool) (World, error) {
 
-	terrainCellLookup := make(map[uint32]TerrainCell)
-
 	seenCellLookup := map[bool]SeenCell{
 		true: SeenCell{
 			Symbol:  " ",
@@ -108,17 +132,28 @@ func Build(m metadata.Overmap, s save.Save, symbolizeByLandUseCode bool) (World,
 		},
 	}
 
-	terrainLayers := buildTerrainLayers(m, s, terrainCellLookup, symbolizeByLandUseCode)
-	characterSeenLayers := buildCharacterSeenLayers(m, s)
-	cityLayer := buildCityLayer(m, s)
+	wcd := calculateWorldChunkDimensions(m, s)
+	extent := Extent{
+		XMin:  wcd.XMin,
+		YMin:  wcd.YMin,
+		XSize: wcd.XSize,
+		YSize: wcd.YSize,
+	}
+
+	palette := buildPalette(m, symbolizeByLandUseCode)
+	terrainLayers, err := buildTerrainLayers(m, s, &palette, symbolizeByLandUseCode)
+	if err != nil {
+		return World{}, err
+	}
 
 	world := World{
-		Name:              s.Name,
-		TerrainLayers:     terrainLayers,
-		SeenLayers:        characterSeenLayers,
-		TerrainCellLookup: terrainCellLookup,
-		SeenCellLookup:    seenCellLookup,
-		CityLayer:         cityLayer,
+		Name:           s.Name,
+		TerrainLayers:  terrainLayers,
+		SeenLayers:     buildCharacterSeenLayers(s, extent),
+		Palette:        palette,
+		SeenCellLookup: seenCellLookup,
+		CityLayer:      buildCityLayer(s, extent),
+		Extent:         extent,
 	}
 
 	return world, nil
@@ -168,122 +203,100 @@ func calculateWorldChunkDimensions(m metadata.Overmap, s save.Save) worldChunkDi
 	return wcd
 }
 
-func buildCityLayer(m metadata.Overmap, s save.Save) CityLayer {
-	wcd := calculateWorldChunkDimensions(m, s)
-	chunkCapacity := wcd.XSize * wcd.YSize
-
-	cities := make([]City, 0)
-	cells := make([]string, 32400*chunkCapacity)
+func buildCityLayer(s save.Save, e Extent) CityLayer {
+	layer := CityLayer{
+		Chunks: make(map[Point]*CityChunk),
+		Cities: make([]City, 0),
+	}
 
 	for _, c := range s.Overmap.Chunks {
-		ci := c.X + (0 - wcd.XMin) + wcd.XSize*(c.Y+0-wcd.YMin)
-		for _, city := range c.Cities {
-
-			ce := City{
-				Name: city.Name,
-				Size: city.Size,
-				X:    (c.X+(0-wcd.XMin))*180 + city.X,
-				Y:    (c.Y+0-wcd.YMin)*180 + city.Y,
-			}
-
-			cities = append(cities, ce)
-
-			nameStart := ci*32400 + city.Y*180 + city.X - len(city.Name)/2
-			if nameStart < 0 {
-				nameStart = 0
-			}
-			for i := 0; i < len(city.Name); i++ {
-				cells[nameStart+i] = string(city.Name[i])
-			}
-		}
+		layer.set(c, e)
 	}
 
-	layer := CityLayer{
-		Cities: cities,
-	}
-	layer.CityRows = make([]CityRow, 180*wcd.YSize)
-	for r := 0; r < 180*wcd.YSize; r++ {
-		layer.CityRows[r].CityCell = make([]string, 180*wcd.XSize)
+	return layer
+}
+
+// set writes the names of the cities of one overmap into its chunk, and
+// adds the cities to the list.
+func (l *CityLayer) set(c save.OvermapChunk, e Extent) {
+	p := Point{X: c.X, Y: c.Y}
+	if len(c.Cities) == 0 {
+		delete(l.Chunks, p)
+		return
 	}
 
-	for xi := 0; xi < wcd.XSize; xi++ {
-		for yi := 0; yi < wcd.YSize; yi++ {
-			for ri := 0; ri < 180; ri++ {
-				for ci := 0; ci < 180; ci++ {
-					cell := cells[(xi+yi*wcd.XSize)*32400+ri*180+ci]
-					layer.CityRows[yi*180+ri].CityCell[xi*180+ci] = cell
-				}
-			}
+	cells := &CityChunk{}
+	column, row := e.Cells(c.X, c.Y)
+	for _, city := range c.Cities {
+		l.Cities = append(l.Cities, City{
+			Name: city.Name,
+			Size: city.Size,
+			X:    column + city.X,
+			Y:    row + city.Y,
+		})
+
+		nameStart := city.Y*180 + city.X - len(city.Name)/2
+		if nameStart < 0 {
+			nameStart = 0
+		}
+		for i := 0; i < len(city.Name) && nameStart+i < 32400; i++ {
+			cells[nameStart+i] = string(city.Name[i])
 		}
 	}
-
-	return layer
+	l.Chunks[p] = cells
 }
 
-func buildCharacterSeenLayers(m metadata.Overmap, s save.Save) map[string][]SeenLayer {
-	wcd := calculateWorldChunkDimensions(m, s)
-	chunkCapacity := wcd.XSize * wcd.YSize
-
-	seen := make(map[string][]SeenLayer)
+// buildCharacterSeenLayers keeps the seen chunks of each character that lie
+// within the world, there being no terrain to show them over elsewhere.
+func buildCharacterSeenLayers(s save.Save, e Extent) map[string]*SeenLayers {
+	seen := make(map[string]*SeenLayers)
 
 	for name, chunks := range s.Seen {
-		doneChunks := make(map[int]bool)
-		cells := make([]bool, 680400*chunkCapacity)
+		layers := newSeenLayers()
 		for _, c := range chunks.Chunks {
-			ci := c.X + (0 - wcd.XMin) + wcd.XSize*(c.Y+0-wcd.YMin)
-			doneChunks[ci] = true
-			for li, l := range c.Visible {
-				lzp := 0
-				for _, e := range l {
-					for i := 0; i < int(e.Count); i++ {
-						tmi := ci*680400 + li*32400 + lzp
-						cells[tmi] = e.Seen
-						lzp++
-					}
-				}
+			if e.Contains(c.X, c.Y) {
+				layers.set(c)
 			}
 		}
+		seen[name] = layers
+	}
 
-		for i := 0; i < chunkCapacity; i++ {
-			if _, ok := doneChunks[i]; !ok {
-				for e := 0; e < 680400; e++ {
-					cells[i*680400+e] = false
-				}
-			}
-		}
+	return seen
+}
 
-		layers := make([]SeenLayer, 21)
-		for l := 0; l < 21; l++ {
-			layers[l].SeenRows = make([]SeenRow, 180*wcd.YSize)
-			for r := 0; r < 180*wcd.YSize; r++ {
-				layers[l].SeenRows[r].SeenCellKeys = make([]bool, 180*wcd.XSize)
-			}
-		}
+func newSeenLayers() *SeenLayers {
+	l := &SeenLayers{
+		Chunks: make(map[Point]*SeenChunk),
+	}
+	for i := range l.Empty {
+		l.Empty[i] = true
+	}
+	return l
+}
 
-		for li := 0; li < 21; li++ {
-			empty := true
-			for xi := 0; xi < wcd.XSize; xi++ {
-				for yi := 0; yi < wcd.YSize; yi++ {
-					for ri := 0; ri < 180; ri++ {
-						for ci := 0; ci < 180; ci++ {
-							cell := cells[(xi+yi*wcd.XSize)*680400+li*32400+ri*180+ci]
-							layers[li].SeenRows[yi*180+ri].SeenCellKeys[xi*180+ci] = cells[(xi+yi*wcd.XSize)*680400+li*32400+ri*180+ci]
-							if empty && cell != false {
-								empty = false
-							}
-						}
-					}
-				}
+// set replaces the chunk of one overmap with c. Layers only ever go from
+// empty to not.
+func (l *SeenLayers) set(c save.SeenChunk) {
+	cells := &SeenChunk{}
+	for li, vl := range c.Visible {
+		if li >= 21 {
+			break
+		}
+		p := 0
+		for _, e := range vl {
+			if e.Seen {
+				l.Empty[li] = false
+			}
+			for i := 0; i < int(e.Count) && p < 32400; i++ {
+				cells[li][p] = e.Seen
+				p++
 			}
-			layers[li].Empty = empty
 		}
-		seen[name] = layers
 	}
-
-	return seen
+	l.Chunks[Point{X: c.X, Y: c.Y}] = cells
 }
 
-func buildTerrainLayers(m metadata.Overmap, s save.Save, tcl map[uint32]TerrainCell, symbolizeByLandUseCode bool) []TerrainLayer {
+func buildTerrainLayers(m metadata.Overmap, s save.Save, p *Palette, symbolizeByLandUseCode bool) (TerrainLayers, error) {
 	missingTerrain := make(map[string]int)
 
 	for _, c := range s.Overmap.Chunks {
@@ -303,87 +316,79 @@ func buildTerrainLayers(m metadata.Overmap, s save.Save, tcl map[uint32]TerrainC
 		fmt.Printf("missing terrain: %v x %v\n", k, v)
 	}
 
-	wcd := calculateWorldChunkDimensions(m, s)
-	chunkCapacity := wcd.XSize * wcd.YSize
+	layers := TerrainLayers{
+		Chunks: make(map[Point]*TerrainChunk),
+	}
+	for i := range layers.Empty {
+		layers.Empty[i] = true
+	}
 
-	doneChunks := make(map[int]bool)
-	cells := make([]uint32, 680400*chunkCapacity)
 	for _, c := range s.Overmap.Chunks {
-		ci := c.X + (0 - wcd.XMin) + wcd.XSize*(c.Y+0-wcd.YMin)
-		doneChunks[ci] = true
-		for li, l := range c.Layers {
-			lzp := 0
-			for _, e := range l {
-				h := save.HashTerrainID(e.OvermapTerrainID)
-				_, ok := tcl[h]
-				if !ok {
-					s := m.Symbol(e.OvermapTerrainID, symbolizeByLandUseCode)
-					cfg, cbg := m.Color(e.OvermapTerrainID, symbolizeByLandUseCode)
-					tc := TerrainCell{
-						ID:      e.OvermapTerrainID,
-						Symbol:  s,
-						ColorFG: cfg,
-						ColorBG: cbg,
-					}
-					tcl[h] = tc
-				}
-
-				for i := 0; i < int(e.Count); i++ {
-					tmi := ci*680400 + li*32400 + lzp
-					cells[tmi] = h
-					lzp++
-				}
-			}
+		if err := layers.set(m, p, c, symbolizeByLandUseCode); err != nil {
+			return layers, err
 		}
 	}
 
+	return layers, nil
+}
+
+// buildPalette starts the palette with the cells every world has.
+func buildPalette(m metadata.Overmap, symbolizeByLandUseCode bool) Palette {
 	dfg, dbg := m.Color("default", symbolizeByLandUseCode)
-	tc := TerrainCell{
+	blank := TerrainCell{
 		ID:      "",
 		Symbol:  " ",
 		ColorFG: dfg,
 		ColorBG: dbg,
 	}
-	h := save.HashTerrainID(tc.ID)
-	tcl[h] = tc
+	return NewPalette(blank, terrainCell(m, "empty_rock", symbolizeByLandUseCode), terrainCell(m, "open_air", symbolizeByLandUseCode))
+}
 
-	for i := 0; i < chunkCapacity; i++ {
-		if _, ok := doneChunks[i]; !ok {
-			for e := 0; e < 680400; e++ {
-				cells[i*680400+e] = h
+// set replaces the chunk of one overmap with c. Layers only ever go from
+// empty to not.
+func (l *TerrainLayers) set(m metadata.Overmap, p *Palette, c save.OvermapChunk, symbolizeByLandUseCode bool) error {
+	cells := &TerrainChunk{}
+	for li, tl := range c.Layers {
+		if li >= 21 {
+			break
+		}
+		n := 0
+		for _, e := range tl {
+			ti, err := terrainIndex(m, p, e.OvermapTerrainID, symbolizeByLandUseCode)
+			if err != nil {
+				return err
+			}
+			if !IsEmptyTerrain(ti) {
+				l.Empty[li] = false
+			}
+			for i := 0; i < int(e.Count) && n < 32400; i++ {
+				cells[li][n] = ti
+				n++
 			}
 		}
 	}
+	l.Chunks[Point{X: c.X, Y: c.Y}] = cells
+	return nil
+}
 
-	layers := make([]TerrainLayer, 21)
-	for l := 0; l < 21; l++ {
-		layers[l].TerrainRows = make([]TerrainRow, 180*wcd.YSize)
-		for r := 0; r < 180*wcd.YSize; r++ {
-			layers[l].TerrainRows[r].TerrainCellKeys = make([]uint32, 180*wcd.XSize)
-		}
+// terrainIndex returns the palette index of an overmap terrain, adding its
+// symbology to the palette the first time it's seen.
+func terrainIndex(m metadata.Overmap, p *Palette, id string, symbolizeByLandUseCode bool) (uint16, error) {
+	if i, ok := p.Index(id); ok {
+		return i, nil
 	}
+	return p.Add(terrainCell(m, id, symbolizeByLandUseCode))
+}
 
-	emptyRockHash := save.HashTerrainID("empty_rock")
-	openAirHash := save.HashTerrainID("open_air")
-	blankHash := save.HashTerrainID("")
-
-	for li := 0; li < 21; li++ {
-		empty := true
-		for xi := 0; xi < wcd.XSize; xi++ {
-			for yi := 0; yi < wcd.YSize; yi++ {
-				for ri := 0; ri < 180; ri++ {
-					for ci := 0; ci < 180; ci++ {
-						cell := cells[(xi+yi*wcd.XSize)*680400+li*32400+ri*180+ci]
-						layers[li].TerrainRows[yi*180+ri].TerrainCellKeys[xi*180+ci] = cell
-						if empty && cell != emptyRockHash && cell != openAirHash && cell != blankHash {
-							empty = false
-						}
-					}
-				}
-			}
-		}
-		layers[li].Empty = empty
+func terrainCell(m metadata.Overmap, id string, symbolizeByLandUseCode bool) TerrainCell {
+	s := m.Symbol(id, symbolizeByLandUseCode)
+	cfg, cbg := m.Color(id, symbolizeByLandUseCode)
+	return TerrainCell{
+		ID:          id,
+		Name:        m.Name(id),
+		Symbol:      s,
+		ColorFG:     cfg,
+		ColorBG:     cbg,
+		LandUseCode: m.LandUseCode(id),
 	}
-
-	return layers
 }
@@ -0,0 +1,13 @@
+// Package notify holds the Postgres notifications the generator sends the
+// server, so both sides agree on the channel and payload.
+package notify
+
+// WorldUpdatedChannel is the Postgres notification channel render.GIS
+// signals on after it finishes importing a world.
+const WorldUpdatedChannel = "world_updated"
+
+// WorldUpdate is the payload of a WorldUpdatedChannel notification.
+type WorldUpdate struct {
+	WorldID  int   `json:"worldId"`
+	LayerIDs []int `json:"layerIds"`
+}
@@ -0,0 +1,43 @@
+// Package pixel holds the pixel space maps are drawn in, which the server
+// also stores cell and city geometries in, so the generator and the server
+// place things the same way without the server importing the generator.
+package pixel
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// CellWidth and CellHeight are the size of a single overmap terrain.
+const (
+	CellWidth  = 21.3594
+	CellHeight = 24
+)
+
+// TileSize is the width and height of a map tile.
+const TileSize = 256
+
+// CellCenter is the middle of the cell at column, row.
+func CellCenter(column, row int) (float64, float64) {
+	return (float64(column) + 0.5) * CellWidth, (float64(row) + 0.5) * CellHeight
+}
+
+// MaxZoom is the deepest zoom level of the tile pyramid for an image of
+// width by height pixels, where tiles are drawn at native resolution.
+func MaxZoom(width, height int) int {
+	tileXCount := math.Ceil(float64(width) / TileSize)
+	tileYCount := math.Ceil(float64(height) / TileSize)
+	return int(math.Max(math.Ceil(math.Log2(tileXCount)), math.Ceil(math.Log2(tileYCount))))
+}
+
+// WorldMaxZoom is the deepest zoom level of the tiles of a world columns by
+// rows cells in size.
+func WorldMaxZoom(columns, rows int) int {
+	return MaxZoom(int(CellWidth*float64(columns)), CellHeight*rows)
+}
+
+// HexColor formats a color the way cells store their symbology.
+func HexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// World visibility. Public worlds are listed and open to everyone, unlisted
+// worlds are open to anyone who knows their ID but only listed for tokens
+// granted access, and private worlds are only open to those tokens.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// Token is an API token. Only a hash of the secret is ever stored; admin
+// tokens can see every world.
+type Token struct {
+	ID    int    `db:"api_token_id"`
+	Name  string `db:"name"`
+	Admin bool   `db:"admin"`
+}
+
+// NewToken generates a random API token secret.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+type contextKey int
+
+const (
+	tokenKey contextKey = iota
+	restrictedKey
+)
+
+// requestToken finds the token in an Authorization: Bearer header or, for
+// clients that cannot set headers like EventSource and tile layers, a
+// token query parameter.
+func requestToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	return r.URL.Query().Get("token")
+}
+
+func requestTokenFrom(r *http.Request) *Token {
+	t, _ := r.Context().Value(tokenKey).(*Token)
+	return t
+}
+
+// restricted reports whether the request is for a world that isn't public,
+// so responses must not be kept in shared caches.
+func restricted(r *http.Request) bool {
+	v, _ := r.Context().Value(restrictedKey).(bool)
+	return v
+}
+
+// authenticate attaches the request's token, if any, to its context.
+// Presenting an unknown token is an error rather than anonymous access.
+func (s *HTTPServer) authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	raw := requestToken(r)
+	if raw == "" {
+		return r, nil
+	}
+
+	t, err := s.DB.GetToken(HashToken(raw))
+	if _, ok := err.(NotFoundError); ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="cddamap"`)
+		return r, Unauthorized("invalid api token")
+	} else if err != nil {
+		return r, err
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), tokenKey, &t)), nil
+}
+
+// authorize checks the request may see the world in its route, and that the
+// layer in its route belongs to that world. Worlds the token may not see are
+// reported as missing so their existence isn't leaked.
+func (s *HTTPServer) authorize(r *http.Request, vars map[string]string) (*http.Request, error) {
+	if _, ok := vars["worldID"]; !ok {
+		return r, nil
+	}
+
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return r, err
+	}
+
+	world, err := s.DB.GetWorld(worldID)
+	if err != nil {
+		return r, err
+	}
+
+	ok, err := s.canView(world, requestTokenFrom(r), false)
+	if err != nil {
+		return r, err
+	}
+	if !ok {
+		return r, NotFound("world %v not found", worldID)
+	}
+
+	if _, ok := vars["layerID"]; ok {
+		layerID, err := intVar(vars, "layerID")
+		if err != nil {
+			return r, err
+		}
+		layerWorldID, err := s.DB.GetLayerWorldID(layerID)
+		if err != nil {
+			return r, err
+		}
+		if layerWorldID != worldID {
+			return r, NotFound("layer %v not found", layerID)
+		}
+	}
+
+	if world.Visibility != VisibilityPublic {
+		r = r.WithContext(context.WithValue(r.Context(), restrictedKey, true))
+	}
+	return r, nil
+}
+
+// canView reports whether token t may see world w, either by ID or, when
+// listing, in the list of worlds.
+func (s *HTTPServer) canView(w World, t *Token, listing bool) (bool, error) {
+	switch {
+	case w.Visibility == VisibilityPublic:
+		return true, nil
+	case w.Visibility == VisibilityUnlisted && !listing:
+		return true, nil
+	case t == nil:
+		return false, nil
+	case t.Admin:
+		return true, nil
+	}
+	return s.DB.HasWorldAccess(w.ID, t.ID)
+}
@@ -0,0 +1,244 @@
+// Code generated for package server by go-bindata DO NOT EDIT. (@generated)
+// sources:
+// viewer/index.html
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func bindataRead(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, gz)
+	clErr := gz.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+	if clErr != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type asset struct {
+	bytes []byte
+	info  os.FileInfo
+}
+
+type bindataFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// Name return file name
+func (fi bindataFileInfo) Name() string {
+	return fi.name
+}
+
+// Size return file size
+func (fi bindataFileInfo) Size() int64 {
+	return fi.size
+}
+
+// Mode return file mode
+func (fi bindataFileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+// Mode return file modify time
+func (fi bindataFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+// IsDir return file whether a directory
+func (fi bindataFileInfo) IsDir() bool {
+	return fi.mode&os.ModeDir != 0
+}
+
+// Sys return file is sys mode
+func (fi bindataFileInfo) Sys() interface{} {
+	return nil
+}
+
+var _indexHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x7b\x6b\x73\xdb\xb6\xd2\xf0\x67\xf9\x57\x6c\x54\x9f\x43\xea\x84\xa2\xa4\xc4\x69\x53\xd9\x72\x4e\xe2\xb8\x6f\xd3\x3a\x4d\x9a\x38\xa7\xe7\xad\x47\x53\x43\x24\x24\x21\x86\x08\x06\x80\xee\xd1\x7f\x7f\x66\x01\xf0\x2a\x39\x6d\x3a\xf3\xcc\x3c\x5f\x64\x12\x58\x2c\xf6\xbe\x8b\x05\x7d\xf6\xe0\xe5\x9b\x8b\xeb\xff\xff\xf6\x12\xa6\x7a\xc6\xcf\x8f\xce\xec\x9f\xa3\xb3\x29\x25\xf1\xf9\x51\xe3\x4c\x33\xcd\xe9\xf9\xc5\xcb\x97\xcf\xe1\x35\x49\xcf\x3a\xf6\xfd\xa8\x71\x36\xa3\x9a\x40\x34\x25\x52\x51\x3d\x68\xce\xf5\xb8\xfd\xb4\x09\x9d\x7c\x26\x21\x33\x3a\x68\x2e\x18\x5d\xa6\x42\xea\x26\x44\x22\xd1\x34\xd1\x83\xe6\x92\xc5\x7a\x3a\x88\xe9\x82\x45\xb4\x6d\x5e\x02\x60\x09\xd3\x8c\xf0\xb6\x8a\x08\xa7\x83\x5e\xd8\x6d\x22\x1e\xce\x92\x3b\x90\x94\x0f\x9a\x4a\xaf\x39\x55\x53\x4a\x75\x13\xa6\x92\x8e\x07\xcd\xa9\xd6\xa9\xea\x77\x3a\xf3\x24\xbd\x9b\x84\x91\x98\x75\x38\x25\x63\x4e\xf5\xbf\x7b\xe1\xe3\xb0\xd7\x89\x99\xd2\xd9\x50\x18\x29\xd5\x04\x96\x68\x3a\x91\x4c\xaf\x07\x4d\x35\x25\x4f\x7a\x8f\xda\xef\xee\xd4\xec\xc9\x3b\x9a\xbc\xb8\xfc\xf9\xfd\xcf\x3f\x7c\x9c\xbc\x7a\x4c\x4e\x7a\x0b\xf9\xf1\x6e\x79\x72\xf9\x9f\xb7\xfc\xa7\xc7\x0f\xdf\xb0\x57\xdf\x3e\x59\x5c\x7f\x7c\x15\x8b\xef\x47\x92\x3f\x27\xd1\xe5\xfc\xe7\x37\xec\xd7\x27\x6f\x7e\x98\x7e\x17\xbd\x79\xd5\x1b\xdd\xbd\x5c\x5e\x7d\x8a\xaf\x96\x8f\x7f\x9f\x74\xa3\x77\x3f\x3d\x7f\xfe\xeb\x60\xd0\x3c\x6a\x40\x24\x85\x52\x42\xb2\x09\x4b\x06\x4d\x27\x17\x15\x49\x96\x6a\x50\x32\xfa\x4a\xfa\x3f\x1e\x24\xbf\xf3\x8b\x5a\x7d\xff\xdf\x93\x1f\xe9\x88\x2c\xc4\x8b\xc5\xe5\x8b\xf9\x3a\x7d\xfc\xea\x3b\x11\x3f\xd1\xcf\xbb\x1f\x36\xcf\x57\xea\xe1\xc7\xa7\x8f\x7f\x9e\x5c\x3c\x7d\xfb\xa1\x7b\x37\x79\x71\xf2\x5f\xf6\xf3\xc9\xd5\x98\x9e\xac\x4f\xa2\xc9\x0b\x4d\xde\xfd\xf4\xeb\xe5\xab\x1f\x2e\x7e\x7b\x28\x2e\x9e\x74\xbf\x25\x6f\xaf\x1f\x5d\xf5\x36\xcb\x43\xe4\x9f\x9f\x75\x2c\xed\x7f\xce\xc5\xc7\x4f\x73\x2a\xd7\xff\x7e\x5c\x30\x61\x47\x0e\xf0\xf0\xf8\xe9\x49\x7b\xfc\xd3\x87\x6f\xd5\xff\x9b\xad\x93\xee\x77\xa3\x87\xf3\x97\xbd\xe4\xf5\xdd\x77\x1d\xf6\x7e\x74\xb2\x5e\x10\xb1\x8c\xe6\x94\xfd\x3a\x1d\xff\x67\xf2\xeb\xfc\xe5\xf7\x4f\xe5\x78\x41\x9e\xce\x22\xd9\xa3\xef\x17\x1f\xa3\xe9\x38\x7d\x2d\x7f\xfc\x33\x5a\xd1\x6e\xce\x8f\x1a\x8d\x91\x88\xd7\xb0\x3d\x6a\x34\x1a\x29\x89\x63\x96\x4c\xfa\xd0\x3d\xc5\xd7\x19\x91\x13\x96\x64\x6f\x63\x91\xe8\xf6\x98\xcc\x18\x5f\xf7\x61\x26\x12\xa1\x52\x12\x51\x04\xdc\x1d\x1d\x35\x1a\xe8\x1b\x81\xc3\x86\x7f\xbf\x99\x91\xd4\x62\x9d\x52\x36\x99\xea\x3e\xf4\xba\xdd\x7f\x20\x78\xc3\x18\x76\xe9\x7d\x44\xa2\xbb\x89\x14\xf3\x24\xee\xc3\x37\xdd\x6e\x37\xc7\xf9\x0d\x3a\x86\x14\x5c\x39\xf2\x84\x62\x9a\x89\xa4\x0f\x64\xa4\x04\x9f\x6b\xb3\x7b\x43\x8b\x14\x91\xa5\x2b\xf3\xc6\xe9\x58\xf7\xe1\x49\xf6\xba\x69\xb3\x24\xa6\x2b\x04\xb0\x88\x0b\x26\xbf\x4d\x57\xf0\x34\x5d\xed\x91\x20\x27\x23\xe2\x3f\x7a\xf2\x24\x80\xe2\xa7\x1b\x7e\xdf\xb2\x80\x42\xc6\x54\xb6\x25\x89\xd9\x5c\xf5\xe1\x24\x5d\x1d\xa0\x96\x93\x11\xe5\x96\x66\x2b\xc3\xb6\xb4\x22\x70\xdb\x59\xf0\x4d\x09\x2c\x66\x2a\xe5\x64\xdd\x07\x96\x70\x96\xd0\xf6\x88\x8b\xe8\xae\x2c\xac\x47\x74\x96\xaf\x0c\x23\xa6\xd7\xed\xd2\xea\x8a\x00\xc7\xe3\x6e\x89\xd2\x3e\x24\x22\xa1\x6e\x60\xd5\x56\x53\x12\x8b\x65\x69\x30\x12\x5c\xc8\x42\xec\x5f\xd0\xb2\x9d\x5a\x3a\x65\x8e\x04\x8f\x0b\x82\x48\x92\x08\x4d\x50\x37\xf7\x93\xd5\x1d\xff\x0d\xb2\xc6\xe3\xf1\x9f\x90\xb5\x3b\x6a\x9c\x75\x9c\x2d\x9f\x75\x6c\x64\x3e\x3a\x43\x9b\x46\x87\x8c\xd9\x02\x58\x3c\x68\xce\x48\xda\x3c\x3f\xeb\xc4\x6c\x51\x1e\xcd\x14\x86\x11\xb5\x71\x66\x28\x3f\xff\x4d\x48\x1e\xc3\x99\xa2\x9c\x46\xda\xac\x5d\xe2\x08\xae\xb6\x63\xe7\x67\x1d\x0b\x59\xac\xf9\x1d\xce\x58\x92\xce\x2d\xf8\x86\xd3\x05\xe5\x4d\xd0\xeb\x94\x0e\x9a\x92\x24\x13\xda\x84\x19\x86\xba\x76\xaf\xdb\x84\x19\x59\x0d\x9a\xf8\xa0\x34\x4d\x07\xcd\x5e\x13\x16\x84\xcf\xe9\xa0\xd9\xc5\x0c\x01\x67\x2a\x25\x89\xc3\x83\xbb\x34\xcf\xbb\x67\x1d\x1c\x3b\xb0\xed\x05\xd3\xeb\xf2\xce\x68\x16\xd9\xbe\x8a\x12\x19\x4d\x9b\x90\x72\x12\xd1\xa9\xe0\x31\x95\xd9\x20\x44\x4c\x33\xaa\x70\xbb\x02\x67\x2e\x9b\x07\xed\x36\x44\x71\x4c\xd0\x7d\x23\x91\x8c\xd9\x04\xda\xed\x22\xb6\xe1\xf6\x9d\x0e\x3c\x07\x85\xfa\x8e\x80\xae\x30\x85\xc1\x94\x28\x48\x04\x28\x2a\x17\x54\xc2\x88\x4e\x59\x12\x03\xd3\x01\x28\x01\x7a\x4a\x01\x73\x1d\x95\x20\x29\x89\x15\x0e\x58\x2c\x76\x31\x8d\xe1\xa7\xf7\x6f\x7e\x81\x31\xe3\x54\x01\x49\x62\x88\x05\x55\xc0\x85\xb8\x9b\xa7\x76\xc0\x11\xce\xb4\xa2\x7c\x1c\x1e\x35\x1a\x0b\x22\x33\xea\x06\xb0\x64\x49\x2c\x96\xa1\xa3\xfa\xc2\x0e\x7f\xfe\x0c\x5b\x20\x29\xeb\x83\xd7\x21\x29\xf3\x02\x47\x71\x1f\xc6\x84\x2b\x0a\xbb\x53\x87\x86\xa4\x0c\x06\x0e\x59\x48\x52\x76\x8a\xce\x39\x9e\x27\x11\x9a\x33\x48\xaa\xc4\x5c\x46\xd4\x4f\x89\x9e\xb6\xac\x5d\x4b\xaa\xe7\x32\xc9\x96\x38\x41\x3c\x83\xdb\xe3\x2d\x49\xd9\xee\x78\x8b\xa0\xbb\xf0\xa3\x12\xc9\x2d\xf4\x6b\xc3\xb7\xb9\xd3\x74\x3a\xf0\x56\xb2\x05\xd1\x14\x8c\x85\x29\x20\x92\x82\x48\x69\x42\x63\x58\x32\x3d\x2d\x09\xce\x53\xf0\x4c\x8b\x3b\x9a\x0c\x02\x58\x4e\x19\x8a\x42\x81\xa2\x89\xb6\x78\x88\x02\x02\x68\xfa\x54\xc2\x72\x4a\x25\x85\x54\x28\xc5\x46\x9c\x1a\xe9\xb1\xc4\xa0\xfa\xf0\xee\xca\xcd\x32\x0d\x4c\x25\x9e\xce\x24\x69\x50\xc3\x00\x12\xba\x84\x0f\xef\xae\xde\x1b\x69\xbf\x25\x92\xcc\x94\xef\x84\xcb\x45\x64\xfc\x3b\xb4\xaa\x68\x85\x13\xaa\x7d\xcf\x2c\xf4\x4c\x64\x64\x63\xf0\xcd\xab\x13\xd2\x71\x48\x3e\x92\xd5\x7b\xaa\xe7\xa9\xbf\x75\xc4\xa9\x3e\x6c\xe1\xf9\x5c\x4f\x85\x64\x1b\x83\xae\x0f\xb7\x2f\x28\x91\x54\xc2\xf1\xd6\xac\xde\xdd\xc2\x0e\x76\xad\x5c\x4a\xb9\x22\x50\x22\xd7\x08\xe1\xcf\x25\xaf\x2a\xc2\x2c\xb4\x0a\x98\x4b\xbe\x3b\xde\xce\x25\x0f\x4d\xd8\x7f\x33\xf6\xbd\x67\x5e\x0b\x06\x83\x01\xb4\x7b\xf0\x0c\xbc\x67\x1e\xf4\xc1\xfb\xa7\xb7\x33\x8b\x06\xc7\x5b\x9a\x44\x22\xa6\x1f\xde\xbd\xba\x10\xb3\x54\x24\x34\xd1\x8e\x8d\x1d\x2a\x6f\x2e\x79\x4e\x0a\x4a\x0a\xbd\x62\x00\x57\xe1\x8c\xa4\xbe\x37\x23\xa9\x17\x58\x4a\x22\xa9\xfa\x70\x15\x5e\xbc\x7b\x1f\xbe\x67\xb3\x94\x53\x4c\x81\x8d\x19\x4b\x7e\x17\x62\xd6\x87\x2e\xe2\x68\x85\x8a\xea\xff\x30\xba\xf4\x6f\xda\xbd\x47\x4f\x03\xe8\x3d\x7a\x3a\x0c\xa0\xd7\x3a\xcd\xb0\x73\xb2\xa6\xf2\xc2\x06\x25\xb3\x8d\x0b\x50\xa1\x99\x50\x7e\x32\xe7\x3c\x00\xfb\xbb\x85\x48\x70\x4e\x52\x45\xe3\xdc\xa4\x5b\x21\x89\xe3\x6b\xe1\xcf\x48\x5a\x20\x45\xfb\xa4\x30\xb0\x74\x1a\x4b\xeb\x5b\x14\xf8\xbe\xe9\x43\xd7\x90\xaa\xa9\x94\x84\x25\xa5\x29\xb1\xa0\x92\x93\x35\xea\x6c\x67\x06\x68\x42\x46\x9c\xc6\x6f\xea\xe3\x36\x9c\x94\x56\xaa\x29\x91\x71\x69\xdd\x82\x26\xda\xcd\xa3\x1c\xaa\x0e\x36\x4f\x52\x29\x3e\xd2\x48\xfb\x91\x10\x32\x56\x55\xd5\xce\x48\x1a\x16\x10\x37\x16\xe4\xa6\x3b\x0c\xc0\x3d\xf6\x86\x43\xeb\xd9\x34\x34\xbc\x85\x33\xb2\xda\x1c\x30\x9f\x0c\x05\x27\x9a\x27\x93\xfd\x4d\xaa\xf3\x7f\x09\xa5\x66\x9c\x7e\x90\xdc\x37\xca\x79\x15\x57\x71\x16\xe6\xea\xfc\xbf\x63\x70\xa9\xce\xf1\xb6\x8c\x9a\xc5\xbb\x8e\x59\x8f\x13\x0e\xd1\xae\x83\x98\x55\x67\xbb\xd9\x75\xb6\xab\x5d\x67\xbb\xde\x85\x69\x32\x79\xb6\x18\x54\xd7\x4a\xba\x60\x8a\x89\x44\xdd\xb8\x85\x43\xf8\xfc\x19\xba\xbb\xdb\x7b\x68\xbd\x42\xa8\x8c\xda\x00\x44\x8a\x33\x35\x79\x5f\x85\x05\x64\x9d\xbf\x00\xae\x42\xba\xd2\x34\x89\x7d\xc3\x69\xc3\x4d\xf4\xc1\x3d\x18\xe5\x37\x66\x64\x65\x8d\xbe\x4c\x2b\x8a\x10\x1e\xc2\xe3\x1c\xe4\x17\xa2\xd9\x82\x1e\x06\xb4\x40\x89\xf8\x4d\x92\xb4\x0f\x5a\xce\xad\x37\x35\x46\x58\x4a\x18\x3f\xe3\x44\x5f\x25\x93\x17\xe6\xdd\x2f\xd9\x47\x37\x80\xee\xb0\x15\x94\x8c\xea\xa6\x8c\x3c\x66\x33\x9a\x18\x91\x19\x36\xd5\x6f\x2c\xa6\xf0\x2f\x78\x74\x12\xc0\x97\xc0\x7e\x64\x93\xa9\x01\x1b\xb6\x5a\x48\xc8\xae\x10\x5e\x21\xea\x4e\x07\x7e\x21\x33\x97\xc0\x58\xac\x20\x12\x33\x0a\x63\x29\x66\x30\x21\x33\x1b\x88\x67\x22\x86\x98\x68\x92\xa5\xc5\xb5\x27\x29\x50\x15\x91\x94\xc6\x36\x92\x8f\xe8\x58\x48\x0a\x13\xc1\x92\x09\x1e\x6b\x04\x68\x21\xb8\x66\xa9\x0a\xcb\xea\xb4\x6b\x7e\xbc\x7e\x7d\xe5\xd7\x14\x78\xec\x7b\xa6\x84\x38\xf7\x5a\xa1\xa6\x2b\xed\xab\x56\x88\xa5\xb9\x5f\x50\x6a\x82\x82\x29\x65\x68\x7c\x41\xb9\x8d\x34\x13\x2a\x30\x09\xbb\x10\x63\xf4\x6b\x3d\xec\x5a\x5c\x19\x61\xf7\x21\xdf\xdd\x8f\xdc\x9e\xd9\xa6\x85\xb8\x23\xb3\x4d\xc3\x3a\xbe\xa9\xca\x30\xe4\xbb\x6a\xce\xfb\x66\x3c\xee\x7a\x01\x64\x35\xe4\xa3\x00\x73\x3e\xcf\xc3\x17\x12\xd8\x0a\x47\x2c\x89\xaf\x2d\xd3\x7e\xb1\xa5\x31\x31\xb7\x2d\x32\x80\x61\xd8\x8c\x85\x63\x4a\xf4\x5c\xd2\x30\x95\x22\xa5\x12\x43\xd1\x69\x49\x1e\xb7\xc7\xdb\x92\xb0\xd2\x10\x8f\xde\xe8\x26\x69\xc8\xe2\xd6\xee\x6c\x24\x3b\xe7\x35\x88\x7c\x5c\xcc\xe0\x78\x9b\x86\x62\xf6\xc7\x6a\x17\x64\x8f\xeb\x1d\x74\xcc\x73\x36\x66\x06\x36\x66\x68\xe3\xb2\xfb\xc1\x30\x8c\xc5\x99\xf1\xab\xff\x35\x71\xa7\x82\x25\x1a\xd7\xaf\xa9\x2c\x2f\x77\x02\x0a\xa0\x12\xfb\x32\x64\x57\x61\xc4\x64\xc4\xe9\x6b\x22\xef\x4c\x78\x40\x18\x4c\x2f\xd9\x11\xe7\x51\x50\xd5\x1f\xd4\x74\x54\x12\xde\xbe\x2a\x8c\xbc\x5b\x8e\xc3\x46\x23\xa5\x72\x46\x30\xc7\x96\xdd\xba\x11\x33\x49\x4d\x32\xe8\x83\x17\xd1\x44\x53\xe9\xb9\x99\x88\x13\xa5\xd0\xad\x70\x26\x3f\xf4\x78\x66\x72\xe7\x78\x3f\xca\x1e\xcb\xd9\x13\x35\xe0\x12\x95\x9f\x4b\x3e\x00\xef\xc2\xe4\x2a\xaf\x50\x4b\x71\x72\xf9\x3f\xa5\x9c\xcc\xc4\xf7\x25\x7a\xfa\x55\xca\x3b\x29\x2b\xaf\x3b\xf6\x60\x67\x22\x58\xa3\x51\x51\xe1\x5e\xd0\x48\x4d\xec\xc8\x22\x07\x22\xac\x6b\x0e\xca\x4a\x33\xa7\x5b\x2f\x80\xb2\xba\xea\x47\xc2\xda\xd6\x6f\x45\x3a\xb7\x1b\xe3\xb9\xc3\x73\x53\x8d\x90\xa4\x29\x26\x18\x9c\x18\xd5\xc9\x09\xc0\x33\x8e\xe9\x05\xb0\x47\xf1\x2d\x3a\x20\x31\xa5\x25\x86\xe7\xbf\xe8\xba\xb7\x05\xce\x43\x14\x10\xd7\x50\xfb\xa6\x69\x59\x1b\x34\x4b\x5c\xc5\x94\x53\x4d\x9b\x48\x01\xd1\x5a\xfa\x1e\x46\xf6\x36\x8b\xbd\xc0\x06\x17\x43\xb3\xef\xbd\x34\x60\x5e\x2b\xc7\x6f\x65\x5a\x37\x5e\x2c\x9f\x1f\x54\x4e\x14\xce\x14\x8a\x1d\x8d\x81\x56\x42\x4b\xe3\x7e\xa3\xaf\x2d\x0b\xc0\x7b\x9e\x8f\x28\xaf\x48\x06\xb9\x25\x6e\xae\xf0\xd8\xea\xbb\x5d\x9d\x7d\x95\xb3\xe2\xc6\xa5\xd2\x0d\x3c\x84\x5e\xd7\xd4\x1a\xdb\x2c\xf3\xbb\xe2\x0f\x14\xa5\x76\x3f\x5b\xfd\x99\xf7\xf7\x82\xb3\xb8\x18\x84\xdd\xfe\xde\x11\xa7\x44\x5a\x90\x8c\x00\x94\x87\xdd\xcf\x55\xa6\x6e\xbc\x81\xc5\x9a\xa4\x33\xb1\x70\x55\x4a\x15\x08\x71\x37\x1a\x95\x31\x3c\xd7\xcc\x39\xcf\xe5\xdd\x18\x0b\x09\x3e\x3a\x18\xc6\x26\x60\x19\x97\x59\xbd\x9b\x6d\x84\x10\x6e\x0c\x06\x35\x98\x1b\x5c\x3a\x2c\x6f\x56\x2b\x8e\x2d\x00\x0c\x00\xc9\x9d\x12\x65\x69\x75\xcb\x1d\x95\x15\xd5\x95\x59\xaa\x82\xd5\x19\xae\xcc\x1a\x86\xaa\xb4\x61\xa9\x6f\x64\xdc\x28\x27\xf9\xb0\x22\xe3\x7d\x15\xa8\xa9\x58\xfe\x6e\x4d\x60\xe3\x24\x90\xa9\x7b\x00\x1b\x84\x6f\x1c\xfb\x9e\xeb\x59\x65\x4e\x67\x0b\xed\x46\x0d\x75\x96\xa2\x37\x58\x59\x64\x66\x75\x9a\x29\x75\xc3\x43\x67\x34\xf0\x60\x60\x55\x93\x49\xbc\xae\xb6\xa2\x14\x2d\x16\x55\x73\x6b\x26\x01\x2e\x48\x5c\xb2\xef\x32\x15\x25\xb1\xdc\x0c\x4f\x2b\xfa\xc7\x06\x3d\x89\x34\x95\x68\x04\x1b\x1e\x16\xd6\x9b\x51\x94\x2d\x0e\xd3\xb9\x9a\xfa\x37\xb7\xc7\xdb\x7c\xcd\xce\x18\xf7\x6d\x50\xa5\xb2\x40\x71\x93\x43\x0e\x31\x7e\x8a\x94\x60\x1e\xea\x43\x37\xfc\x0e\x76\xad\x61\xeb\x80\x3d\x1e\xa2\xa7\xe4\x3d\x7f\x99\x28\xf0\x15\xae\x6a\x1d\x22\xae\x84\xef\x7e\x0a\x9f\xd4\x28\xcc\x77\x1c\x0b\x79\x49\xa2\x69\xa9\x2e\x13\x19\x55\x35\x07\x11\x37\xdd\xe1\x10\x06\x20\x6e\x7a\xc3\x03\xe6\x5e\x8a\x54\x08\x11\x00\xc2\x3b\x83\x2f\x5c\xbf\xee\x54\x08\x34\xcc\x36\x6c\xe0\xc2\xba\x35\x58\x73\x38\xd4\x3f\x40\x03\x37\xbd\x3d\xdf\x84\xb3\xfc\xbc\x76\x8c\x9d\x0c\x53\x8b\xe5\xad\x9e\xdb\xe2\xac\xe6\x60\x4d\xa2\x28\x98\x36\xa3\x19\x1d\x7b\x8e\x95\xc9\xc2\x40\xc1\xc0\x76\x77\xca\x13\xf6\x7c\x5c\xf8\x69\xe3\x20\xb7\x6e\x3e\x8f\x45\xa6\xad\xa8\x60\x00\x6f\x46\x58\x5e\x84\x77\x74\xad\x7c\x17\x9a\x5b\xa6\x21\x51\x10\x78\xd7\xc2\x02\xce\xc6\xf0\x14\x6f\xa1\x5e\x25\xda\xbf\x0b\xa0\xd7\x6d\x41\x1b\x7a\xdd\x53\xd7\x62\x71\x4d\x0d\xe3\x6d\xaf\x89\x9e\x86\x33\x96\x60\x02\xe4\x6b\x57\xfc\xd8\x4d\xcb\xb0\x64\x95\xc3\x92\xd5\xfd\xb0\x36\x58\xe0\x48\x96\x1f\xb7\xd8\x04\xed\xe3\x4f\x80\x58\xfa\xf8\x83\xb5\xe4\x82\x70\x3f\xdb\xdb\x3d\x90\x95\xdf\x0d\x10\xb2\x65\x40\x5b\xce\x9d\x1b\xa5\x20\x95\x73\x55\xd9\x08\x71\xb5\x0c\x9b\xd9\x12\x8c\x0c\xb6\xe8\xcb\x94\xc3\x99\xd2\x34\xf9\x41\xc8\x0f\x69\x4c\x34\xcd\x43\x96\xc9\x2c\x59\x87\xa6\xa8\xe2\x6e\xbe\x78\x74\xec\x3d\x0a\xe0\x8b\x87\xc6\xde\x23\x3c\x8f\xe6\x6c\x95\x4e\xc3\x6d\x78\x1c\x40\x37\x2b\x04\x0e\x58\x6c\x99\xf4\xbf\x62\xab\xe5\x64\x8d\x7d\x05\xdb\x97\xa9\x5a\xae\x1d\xcb\x4c\xd7\xae\xb0\x63\xd8\x03\x65\x45\x81\x99\x17\xcd\xfb\x89\xa3\x3c\x49\xe2\xf8\x25\xd1\x24\xc3\xfb\x45\x5e\x2a\x01\xba\xc8\xf2\x87\x8a\x1e\x57\xdf\x16\x11\xa8\xe0\xfc\x4f\xdb\x29\x45\xe5\xa3\x6e\x31\xf2\x6e\xb2\x06\xc3\x06\x76\x65\x51\x94\xe0\xb2\x5d\x8b\xa1\x7b\x59\xaf\x83\x64\x02\x28\xc6\xbf\x20\x05\x77\xcb\x43\x5d\x00\xce\xa4\x90\xb7\xfe\x4a\x49\xea\xde\xfa\xc7\x02\xda\xd8\x5f\x05\x38\x90\x51\xbe\x5c\xe1\xec\xa3\xca\xc3\x37\x2e\x2c\x67\x00\x17\x4b\xec\x8a\x7d\xc6\x14\x99\x51\xc7\x13\x09\x60\xe4\x36\x70\x8b\x30\xb8\x86\x4a\x4b\x96\x4c\xd8\x78\xed\x13\xdb\x8e\xad\x8d\x8e\x0e\x88\xcb\x04\x17\xeb\xa5\xfe\xdc\xfc\xf9\x3b\x7e\xf0\x85\xd0\x8d\x82\x77\x1d\x97\x5a\xa5\x72\x7f\x08\x47\xc5\x3c\x28\xf1\x6b\xd7\x07\xf9\xf2\x56\x86\xbc\x1c\xad\x9c\x01\x3a\xdc\x3b\xa0\xd8\xef\x70\x60\x7b\x46\x71\x20\xcb\x96\xbb\x1f\x8e\x06\x2b\x90\xac\x2c\x52\x79\xbf\xdb\x0c\x84\xae\x3f\x95\x57\x4d\xa6\xd6\x6a\xf7\x0a\x14\x36\x1d\x63\x47\x1a\x5b\x7c\x95\x56\xdf\xde\x62\x47\xb6\x33\x85\xcc\xb4\xb3\xd7\xbd\x08\x7b\xd0\xff\xf7\x82\x6e\xbd\xcc\xb7\x5d\xe2\x8c\xc0\xf2\x58\x18\x71\xa1\x68\x86\xfc\x60\xd8\xc0\xc3\xc8\x03\x77\x53\x71\x89\x88\xde\x1b\xab\xb8\x2f\x9a\x94\xb1\xbb\x6b\x8f\xd2\x2a\xff\x2b\x7a\xb7\x16\xc7\xad\x8b\xe2\x15\xaa\x49\x1c\x1b\xa4\x57\x86\x75\x2a\x7d\xcf\xaa\xcc\x2b\x9b\x63\x4e\x62\xd9\xd6\xd1\xb2\x43\x93\xe0\x7c\x1a\xe2\xa9\x72\x3f\x49\x1c\xf0\x00\xcf\x51\xe9\xed\xdb\x7b\xe6\xf1\x16\x20\x54\x42\xea\x92\x71\x39\x8f\xcd\xea\x05\x62\x7a\x36\xe6\xc2\x87\x53\xbc\x14\x21\x92\xfa\x23\x33\xd8\xca\xeb\x06\x87\x69\xdf\x52\x97\x19\x43\x98\x97\x0d\x94\xd7\x2a\x1f\xa9\xad\x6d\x9d\xbb\x5c\xbd\x2c\x0e\xca\x4b\xbb\x43\x99\x53\x63\xe7\x6e\x27\x4e\x93\x89\x9e\xc2\x39\x74\xb3\x0d\x6a\x05\x9d\xba\xe9\x0e\x11\x5b\xae\x65\xc4\x71\x54\xa5\x43\x24\xbe\x17\x4d\xf1\x46\xb6\xa2\x04\x87\xb1\x40\x78\xec\xeb\x29\x53\xae\x9c\x68\x9d\x56\x91\xe5\xc5\x06\x62\xb3\x97\xaf\xf7\xe3\xc4\x08\x83\x27\xa8\x52\xb9\x52\x42\x6d\x2a\x95\x9c\xd3\x8d\x71\xd2\x2c\x54\xc0\xb6\x5e\xf2\x6c\x0e\xf2\x86\xb9\xd8\x11\x73\x47\xd7\xb1\x58\x26\x07\x0d\x0c\x09\xf9\x04\x03\xa8\xec\x9f\x6f\x4d\xb1\x9a\x34\xdb\x7b\x97\xa6\xff\x66\x1c\xaa\x1c\x03\x3f\x7f\x86\x4f\x26\x6e\x7b\xde\xbd\x5e\x65\x6e\x06\x5d\x28\xf8\x74\x7f\xd5\x81\x5a\xb5\x23\x59\xef\x36\x57\x2f\x6e\x90\x2b\xb8\xbc\x81\x8b\x35\xb8\xf4\x41\xe5\x48\x9d\x97\x22\x45\xdc\xad\x54\x27\xfb\x87\x03\x53\xe7\x8d\xf9\xfa\x5a\x94\xaa\xbc\x1a\x3d\x68\x4b\x13\x2a\x66\x54\xcb\x75\x68\x1a\xb3\x2c\xc1\x5a\xb1\x75\xcf\xc5\x50\xe6\x9a\xad\xea\xdd\x56\x5d\x22\xb1\x48\x32\x7d\xdc\xdb\xef\xf9\x9a\x52\xc7\x55\x78\x58\xe5\x7c\xea\xc3\x27\xac\x6f\xcc\x16\xa7\x07\xf5\x83\x26\x90\x4a\x3a\x66\x58\xc1\x7f\x0a\xb5\xb8\x12\x4b\x2a\x2f\x48\x1e\x5b\x71\xad\xbb\xda\xc1\x8f\x08\xfa\xe0\xfd\x60\xe5\x71\x21\x38\x76\x12\x98\x48\x5c\x63\x36\x93\x53\x1f\xfc\x4a\x0d\xf9\xcc\x89\xa7\x26\x4e\xe8\xc3\xcd\xb0\x15\x8e\x19\xd7\x54\x96\x82\xc5\xb8\xa6\x6a\x18\xd7\x5b\xc7\x55\x2a\xf3\x24\x67\xd9\xb0\x85\x44\xd7\xe9\xb6\x55\xd2\x83\xbb\x90\xb1\x9f\x10\x60\xfb\x03\xc6\x2c\xb1\x5f\x1e\x40\x84\xaf\xf3\x24\xa6\x12\x52\xb6\xa2\x1c\xd2\xec\x82\xdc\x64\x3f\x4f\x81\x39\x9f\xc1\x58\x48\x8b\x05\xa7\xb0\x30\xc2\xbb\xdf\x14\xef\x2a\xb8\x02\x3c\xc4\x8c\xe6\x8c\xe3\x97\x44\x06\xab\x63\xd5\x3c\x3f\x7f\xfb\x0a\x96\x62\xce\x63\x17\x52\x2b\x57\x37\x05\x4d\xc5\x55\x5c\x5a\x31\x0d\xd4\x53\x9c\xb7\x9c\xea\xa7\x8b\xd3\x0c\x24\x12\x7c\x3e\xcb\x0f\x6e\x63\x2e\x84\xf4\xd3\x70\x05\x1d\x88\x43\xe4\xf1\x37\x16\xeb\x69\x2b\x07\x97\x62\x59\x87\x5d\xe7\xb0\x3f\x9a\x1b\x99\x22\xe8\x3a\xdc\x67\xd0\xc5\x40\x80\x4b\xdd\xa3\x9b\x38\x1f\x40\x5c\x3a\x07\x39\x98\x62\x14\x6f\xcc\x32\xdd\x22\x67\xfe\x16\xee\xb7\xa8\xac\xc9\xad\xd0\x4a\x60\xf7\x05\xeb\xc5\x7e\x6e\x4d\x30\xf6\x33\x42\x6c\xef\xae\xe0\x61\x99\x3d\x47\x69\x07\x7a\x4f\x5d\x8c\x75\x18\xd6\xf7\x62\x58\x57\x31\x20\xdb\xb5\xe5\x73\x89\x3d\xad\xbf\x71\xa3\x8b\x0a\xc1\x01\xa4\x73\xf7\x87\xf9\xbb\x76\x9f\x88\x98\x90\xe1\xca\xca\x52\x7b\xe0\x66\x2e\x79\xde\xeb\x28\xf7\x0d\xcc\x04\x46\xf2\x3c\x4e\xe0\x07\x11\x85\xa0\xf6\x60\x43\xc2\x97\x64\xad\x4a\x7e\x67\x26\x33\xdc\x28\x16\x8c\x08\x4e\x60\xff\x40\x8e\x03\x40\x29\xa1\x00\xcc\x6b\x71\xfc\xc7\x4f\x65\xcc\x6a\xf8\xe7\x3f\xad\x9f\x18\xfb\x51\xf0\xac\xfc\x76\xb3\xc6\xa3\xf1\xd3\x2e\x3c\x84\xd5\x10\xfa\xd0\xee\x15\xb5\x33\x33\x4e\x8b\xfe\x37\x66\xf8\xa5\xcb\xe7\xcf\xc0\xe0\xac\x14\xf5\xff\xa6\xbd\x1c\xc8\x16\x48\xb0\xce\x08\xce\x8e\x49\x37\xcc\xf5\xa3\x70\x76\xd5\x2d\x18\xff\x57\xd9\x6f\x02\x58\x77\x9d\x04\xb2\x71\xeb\x23\xa7\x25\x9b\x36\x8f\x8d\xfb\x09\xb5\xf3\x25\x6a\xdd\x8a\xda\x92\x0c\xb0\x91\x65\x9c\x7e\x26\x8a\x3c\x0e\xbf\x15\x7c\x3d\x29\x50\xba\xfb\x28\x9b\x93\xfa\x70\x73\x73\xb3\xea\x22\xc5\xc3\x00\x6e\x56\x28\xf5\x1a\x27\xf7\x8d\xe7\x23\x96\x37\x0b\xf6\xc5\x89\xe1\x70\xe8\x48\xd8\x65\xb4\x14\x11\x1b\xaf\x7a\x59\xdc\x07\x1d\xb2\x38\x00\x0c\xdf\xf8\x8c\x7f\xf1\x46\xe6\x8f\x55\xdf\xfc\x9a\xe7\xb5\x79\x5e\x07\xb0\xea\xc3\x2a\x80\x75\x1f\xd6\x81\x39\xa9\x1b\x55\x6d\x20\x3b\x69\x0c\x2b\x57\x7d\x45\x70\xc7\x1c\x8e\xf5\x57\xc4\x59\x74\x77\x6f\xc1\xb3\xe1\x55\x5f\x47\xa3\xad\x1c\xf3\xd0\x20\x1f\x6c\x38\x06\xb9\x52\x5f\x7a\x50\xeb\x4b\x1f\x0a\x45\x78\x39\x97\x15\x0f\x34\x74\x97\x77\x79\x9c\xb0\x41\x1e\x06\x7b\xdf\x88\x15\xd1\xdf\xdc\x82\xb9\xce\x59\xb1\x77\x00\x69\x0b\xca\xf7\x83\xa5\xbc\xf0\x35\x95\x41\x1e\x81\x0a\xd4\x45\x10\x4a\xc3\xd5\xae\xe3\x2e\xc0\xca\x15\x83\xed\x46\x5a\x12\x4b\xd1\x42\x52\x95\x8a\x44\xe5\x64\x7c\xf9\x5a\xa1\x36\x9f\xf5\x47\x72\x24\x07\x60\xf0\x9b\xb7\xec\xc6\xbf\x2a\x4b\xa7\xf1\xd6\x69\x45\xe7\xf8\xb5\xfc\x4a\xcf\x68\x32\x3f\xa8\xf9\x52\x28\xcd\x6b\xd7\x83\x95\xd6\xd7\xe9\xf5\x2b\xa4\x5f\xf2\x4e\xd3\x82\x4a\x57\x7d\x48\xc3\x55\x00\xe9\x1a\x1f\xd6\xc1\xbd\x4d\xa9\x9c\x36\xa4\x05\xb9\x2c\x3e\x6f\x4c\xa5\x98\xa5\xda\xbf\x75\x6d\x34\x6a\x2f\x39\xa3\x10\xbf\x2e\x29\x6e\x3a\x8b\x77\x77\xdd\xe9\x06\x2a\xb3\x6b\xf7\x8d\x90\x93\x15\xee\x93\xed\x7b\x20\x8c\xda\x2f\xf9\xb2\x70\x37\x97\xbc\x0f\x7f\x2a\x81\x52\x3b\xec\xd6\x05\x8a\x19\xd5\x53\x11\x63\x30\x7b\xf3\xfe\x3a\x8b\x64\xee\x3f\x1f\xae\x6d\x40\xc4\x43\x30\xb3\xdf\x19\x76\x3e\xaa\x22\xde\xe1\x19\xb8\x5f\xef\x18\x1d\xe7\x1f\x23\x01\x32\xd0\x37\xbf\x58\x02\x5b\x26\xdd\x85\xeb\xae\x15\xa2\x81\xfb\xb5\x06\xe4\x01\xf3\xc2\xc3\x14\x7e\xd1\xd7\xaa\x04\x16\xaf\xfc\x6d\xb3\xbd\xf2\x3d\x68\x74\x78\x45\x6f\x0e\xfe\x2f\xe9\x98\xcc\xb9\x76\xce\x50\x91\xdd\x57\x8b\xae\x73\xbc\xcd\xce\x6b\x28\x02\xdf\x63\xb1\xd7\xda\x39\x81\xe6\xf2\x7c\x79\x79\x75\x79\x7d\xe9\x1d\x95\xb8\x2d\xe8\xcb\xf4\x8a\xce\x63\xfa\x28\xf6\xe2\xdd\xa9\x7f\xaf\x2d\x5b\x13\x4b\xa3\xf8\x87\x81\xb3\x8e\xfd\xa6\xfa\xe8\xac\x33\xd5\x33\x7e\x7e\xf4\x3f\x03\x00\xc7\xb2\xa3\xe4\x1e\x33\x00\x00")
+
+func indexHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_indexHtml,
+		"index.html",
+	)
+}
+
+func indexHtml() (*asset, error) {
+	bytes, err := indexHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "index.html", size: 13086, mode: os.FileMode(420), modTime: time.Unix(1792378465, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+// Asset loads and returns the asset for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func Asset(name string) ([]byte, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("Asset %s can't read by error: %v", name, err)
+		}
+		return a.bytes, nil
+	}
+	return nil, fmt.Errorf("Asset %s not found", name)
+}
+
+// MustAsset is like Asset but panics when Asset would return an error.
+// It simplifies safe initialization of global variables.
+func MustAsset(name string) []byte {
+	a, err := Asset(name)
+	if err != nil {
+		panic("asset: Asset(" + name + "): " + err.Error())
+	}
+
+	return a
+}
+
+// AssetInfo loads and returns the asset info for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func AssetInfo(name string) (os.FileInfo, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("AssetInfo %s can't read by error: %v", name, err)
+		}
+		return a.info, nil
+	}
+	return nil, fmt.Errorf("AssetInfo %s not found", name)
+}
+
+// AssetNames returns the names of the assets.
+func AssetNames() []string {
+	names := make([]string, 0, len(_bindata))
+	for name := range _bindata {
+		names = append(names, name)
+	}
+	return names
+}
+
+// _bindata is a table, holding each asset generator, mapped to its name.
+var _bindata = map[string]func() (*asset, error){
+	"index.html": indexHtml,
+}
+
+// AssetDir returns the file names below a certain
+// directory embedded in the file by go-bindata.
+// For example if you run go-bindata on data/... and data contains the
+// following hierarchy:
+//     data/
+//       foo.txt
+//       img/
+//         a.png
+//         b.png
+// then AssetDir("data") would return []string{"foo.txt", "img"}
+// AssetDir("data/img") would return []string{"a.png", "b.png"}
+// AssetDir("foo.txt") and AssetDir("notexist") would return an error
+// AssetDir("") will return []string{"data"}.
+func AssetDir(name string) ([]string, error) {
+	node := _bintree
+	if len(name) != 0 {
+		cannonicalName := strings.Replace(name, "\\", "/", -1)
+		pathList := strings.Split(cannonicalName, "/")
+		for _, p := range pathList {
+			node = node.Children[p]
+			if node == nil {
+				return nil, fmt.Errorf("Asset %s not found", name)
+			}
+		}
+	}
+	if node.Func != nil {
+		return nil, fmt.Errorf("Asset %s not found", name)
+	}
+	rv := make([]string, 0, len(node.Children))
+	for childName := range node.Children {
+		rv = append(rv, childName)
+	}
+	return rv, nil
+}
+
+type bintree struct {
+	Func     func() (*asset, error)
+	Children map[string]*bintree
+}
+
+var _bintree = &bintree{nil, map[string]*bintree{
+	"index.html": &bintree{indexHtml, map[string]*bintree{}},
+}}
+
+// RestoreAsset restores an asset under the given directory
+func RestoreAsset(dir, name string) error {
+	data, err := Asset(name)
+	if err != nil {
+		return err
+	}
+	info, err := AssetInfo(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(_filePath(dir, filepath.Dir(name)), os.FileMode(0755))
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(_filePath(dir, name), data, info.Mode())
+	if err != nil {
+		return err
+	}
+	err = os.Chtimes(_filePath(dir, name), info.ModTime(), info.ModTime())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RestoreAssets restores an asset under the given directory recursively
+func RestoreAssets(dir, name string) error {
+	children, err := AssetDir(name)
+	// File
+	if err != nil {
+		return RestoreAsset(dir, name)
+	}
+	// Dir
+	for _, child := range children {
+		err = RestoreAssets(dir, filepath.Join(name, child))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func _filePath(dir, name string) string {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tiles requested with the layer's current revision can never change, so
+// they are cached for a year. Anything else, including JSON, must be
+// revalidated against its ETag, which is cheap and keeps imports visible.
+// Responses for worlds that aren't public are only cached by the client.
+const (
+	immutableCacheControl         = "public, max-age=31536000, immutable"
+	revalidateCacheControl        = "no-cache"
+	privateImmutableCacheControl  = "private, max-age=31536000, immutable"
+	privateRevalidateCacheControl = "private, no-cache"
+)
+
+func revalidate(r *http.Request) string {
+	if restricted(r) || requestTokenFrom(r) != nil {
+		return privateRevalidateCacheControl
+	}
+	return revalidateCacheControl
+}
+
+// findFile returns the path and details of the first file named by elem
+// under any of roots, or an empty path if none has it.
+func findFile(roots []string, elem ...string) (string, os.FileInfo) {
+	for _, root := range roots {
+		p := filepath.Join(append([]string{root}, elem...)...)
+		if stat, err := os.Stat(p); err == nil && !stat.IsDir() {
+			return p, stat
+		}
+	}
+	return "", nil
+}
+
+// tileRevision identifies the current rendering of a layer by the
+// modification time, in milliseconds, of its top level tile. It returns 0
+// when the layer has not been tiled yet.
+func tileRevision(tileRoots []string, t string) int64 {
+	_, stat := findFile(tileRoots, t, "0", "0", "0.png")
+	if stat == nil {
+		return 0
+	}
+	return stat.ModTime().UnixNano() / 1e6
+}
+
+func fileETag(stat os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size())
+}
+
+// contentETag is weak, as the same content is sent gzipped or not
+// depending on the request, and RFC 7232 only lets a strong validator
+// stand for one encoding of it.
+func contentETag(b []byte) string {
+	return fmt.Sprintf(`W/"%x"`, sha1.Sum(b))
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 7232 requires for GET and HEAD.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// serveBytes writes a generated response body with an ETag derived from its
+// content, answering 304 Not Modified when the client already has it.
+func serveBytes(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	etag := contentETag(body)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", revalidate(r))
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func serveJSON(w http.ResponseWriter, r *http.Request, thing interface{}) error {
+	val, err := json.Marshal(thing)
+	if err != nil {
+		return err
+	}
+	serveBytes(w, r, "application/json", val)
+	return nil
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, e := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.Split(e, ";")[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// compressible reports whether a response of the given content type is
+// worth compressing. Tiles are already compressed and event streams are
+// left alone so each event reaches the client as soon as it is flushed.
+func compressible(contentType string) bool {
+	ct := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	switch {
+	case ct == "text/event-stream":
+		return false
+	case strings.HasPrefix(ct, "text/"):
+		return true
+	case ct == "application/json", ct == "application/geo+json", ct == "application/javascript":
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the response body when, at the time the
+// header is written, the content type turns out to be compressible.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func newGzipResponseWriter(w http.ResponseWriter) *gzipResponseWriter {
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &gzipResponseWriter{ResponseWriter: w}
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+
+	h := g.Header()
+	if code != http.StatusNoContent && code != http.StatusNotModified && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		if g.Header().Get("Content-Type") == "" {
+			g.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	return g.ResponseWriter.Write(b)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) Close() error {
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
+// tileCacheControl lets clients keep a tile forever when they asked for it
+// by the layer's current revision, see tileRevision.
+func (s *HTTPServer) tileCacheControl(r *http.Request, t string) string {
+	v := r.URL.Query().Get("v")
+	if v != "" && v == strconv.FormatInt(tileRevision(s.tileRoots, t), 10) {
+		if restricted(r) {
+			return privateImmutableCacheControl
+		}
+		return immutableCacheControl
+	}
+	return revalidate(r)
+}
+
+// worldRevisions returns the tile revision of each of a world's layers.
+func (s *HTTPServer) worldRevisions(worldID int) (map[int]int64, error) {
+	tileRoots, err := s.DB.GetTileRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make(map[int]int64)
+	for _, t := range tileRoots {
+		if t.WorldID == worldID {
+			revisions[t.LayerID] = tileRevision(s.tileRoots, t.TileRoot)
+		}
+	}
+	return revisions, nil
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/ralreegorganon/cddamap/internal/pixel"
+)
+
+// cellWidth and cellHeight are the size of a single overmap terrain in the
+// pixel space that render.GIS writes cell and city geometries in.
+const cellWidth = pixel.CellWidth
+const cellHeight = float64(pixel.CellHeight)
+
+const overmapSize = 180
+
+// groundLayer is the layer index of game z-level 0.
+const groundLayer = 10
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func floorMod(a, b int) int {
+	return a - floorDiv(a, b)*b
+}
+
+func (c GameCoordinate) normalize() GameCoordinate {
+	return GameCoordinate{
+		OMX: c.OMX + floorDiv(c.X, overmapSize),
+		OMY: c.OMY + floorDiv(c.Y, overmapSize),
+		X:   floorMod(c.X, overmapSize),
+		Y:   floorMod(c.Y, overmapSize),
+		Z:   c.Z,
+	}
+}
+
+func (c GameCoordinate) Layer() int {
+	return c.Z + groundLayer
+}
+
+// PixelToGame converts a point in the pixel space of the world's geometries
+// into the game coordinate of the overmap terrain containing it.
+func (wi WorldInfo) PixelToGame(p Point, z int) GameCoordinate {
+	column := int(math.Floor(p.X / cellWidth))
+	row := int(math.Floor(p.Y / cellHeight))
+
+	c := GameCoordinate{
+		OMX: wi.Origin.OMX,
+		OMY: wi.Origin.OMY,
+		X:   column,
+		Y:   row,
+		Z:   z,
+	}
+	return c.normalize()
+}
+
+// GameToPixel converts a game coordinate into the pixel space point at the
+// center of its overmap terrain.
+func (wi WorldInfo) GameToPixel(c GameCoordinate) Point {
+	c = c.normalize()
+	column := (c.OMX-wi.Origin.OMX)*overmapSize + c.X
+	row := (c.OMY-wi.Origin.OMY)*overmapSize + c.Y
+
+	return Point{
+		X: (float64(column) + 0.5) * cellWidth,
+		Y: (float64(row) + 0.5) * cellHeight,
+	}
+}
+
+// GameBoundingBox converts the overmap terrains at two corners into the
+// pixel space box covering both of them and everything between.
+func (wi WorldInfo) GameBoundingBox(a, b GameCoordinate) BoundingBox {
+	pa, pb := wi.GameToPixel(a), wi.GameToPixel(b)
+	return BoundingBox{
+		MinX: math.Min(pa.X, pb.X) - cellWidth/2,
+		MinY: math.Min(pa.Y, pb.Y) - cellHeight/2,
+		MaxX: math.Max(pa.X, pb.X) + cellWidth/2,
+		MaxY: math.Max(pa.Y, pb.Y) + cellHeight/2,
+	}
+}
+
+func (wi WorldInfo) Contains(c GameCoordinate) bool {
+	c = c.normalize()
+	return c.OMX >= wi.Origin.OMX && c.OMX < wi.Origin.OMX+wi.Dimensions.OvermapsWide &&
+		c.OMY >= wi.Origin.OMY && c.OMY < wi.Origin.OMY+wi.Dimensions.OvermapsHigh
+}
+
+func (wi WorldInfo) TerrainLayer(z int) (int, error) {
+	if zl, ok := wi.Z[z+groundLayer]; ok && zl.TerrainLayer.Valid {
+		return int(zl.TerrainLayer.Int64), nil
+	}
+	return 0, NotFound("no terrain layer for z-level %v in world %v", z, wi.ID)
+}
+
+func parseGameCoordinate(query url.Values) (GameCoordinate, error) {
+	c := GameCoordinate{}
+	fields := []struct {
+		name     string
+		value    *int
+		required bool
+	}{
+		{"om_x", &c.OMX, true},
+		{"om_y", &c.OMY, true},
+		{"x", &c.X, true},
+		{"y", &c.Y, true},
+		{"z", &c.Z, false},
+	}
+
+	for _, f := range fields {
+		v := query.Get(f.name)
+		if v == "" {
+			if f.required {
+				return c, BadRequest("%v is required", f.name)
+			}
+			continue
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return c, BadRequest("%v must be an integer: %q", f.name, v)
+		}
+		*f.value = i
+	}
+
+	return c.normalize(), nil
+}
@@ -1,10 +1,14 @@
 package server
 
 import (
+	"database/sql"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/guregu/null"
 	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
 )
 
 type DB struct {
@@ -25,16 +29,100 @@ func (db *DB) GetWorlds() ([]World, error) {
 	err := db.Select(&worlds, `
 		select 
 			world_id, 
-			name
+			name,
+			visibility
 		from 
 			world
 	`)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "worlds")
 	}
 	return worlds, nil
 }
 
+func (db *DB) GetWorld(worldID int) (World, error) {
+	var w World
+	err := db.Get(&w, `
+		select
+			world_id,
+			name,
+			visibility
+		from
+			world
+		where
+			world_id = $1
+	`, worldID)
+	if err != nil {
+		return w, dbError(err, "world %v", worldID)
+	}
+	return w, nil
+}
+
+func (db *DB) GetLayerWorldID(layerID int) (int, error) {
+	var worldID int
+	err := db.QueryRow("select world_id from layer where layer_id = $1", layerID).Scan(&worldID)
+	if err != nil {
+		return 0, dbError(err, "layer %v", layerID)
+	}
+	return worldID, nil
+}
+
+func (db *DB) GetToken(hash []byte) (Token, error) {
+	var t Token
+	err := db.Get(&t, `
+		select
+			api_token_id,
+			name,
+			admin
+		from
+			api_token
+		where
+			token_hash = $1
+	`, hash)
+	if err != nil {
+		return t, dbError(err, "api token")
+	}
+	return t, nil
+}
+
+func (db *DB) HasWorldAccess(worldID, tokenID int) (bool, error) {
+	var ok bool
+	err := db.QueryRow("select exists(select 1 from world_access where world_id = $1 and api_token_id = $2)", worldID, tokenID).Scan(&ok)
+	if err != nil {
+		return false, dbError(err, "world %v access", worldID)
+	}
+	return ok, nil
+}
+
+// CreateToken stores a new API token by the hash of its secret.
+func (db *DB) CreateToken(name string, admin bool, hash []byte) (int, error) {
+	var id int
+	err := db.QueryRow("insert into api_token (name, admin, token_hash) values ($1, $2, $3) returning api_token_id", name, admin, hash).Scan(&id)
+	if err != nil {
+		return 0, dbError(err, "api token %v", name)
+	}
+	return id, nil
+}
+
+func (db *DB) GrantWorldAccess(worldID, tokenID int) error {
+	_, err := db.Exec("insert into world_access (world_id, api_token_id) values ($1, $2) on conflict do nothing", worldID, tokenID)
+	if err != nil {
+		return dbError(err, "world %v access", worldID)
+	}
+	return nil
+}
+
+func (db *DB) SetWorldVisibility(worldID int, visibility string) error {
+	res, err := db.Exec("update world set visibility = $2 where world_id = $1", worldID, visibility)
+	if err != nil {
+		return dbError(err, "world %v", worldID)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return NotFound("world %v not found", worldID)
+	}
+	return nil
+}
+
 func (db *DB) GetWorldInfo(worldID int) (WorldInfo, error) {
 	worldInfo := WorldInfo{
 		Z: make(map[int]*ZLevel),
@@ -45,6 +133,10 @@ func (db *DB) GetWorldInfo(worldID int) (WorldInfo, error) {
 		select
 			w.world_id,
 			w.maxz,
+			w.om_xmin,
+			w.om_ymin,
+			w.om_xsize,
+			w.om_ysize,
 			l.layer_id,
 			l.z,
 			l.type,
@@ -60,16 +152,28 @@ func (db *DB) GetWorldInfo(worldID int) (WorldInfo, error) {
 			w.world_id = $1
 	`, worldID)
 	if err != nil {
-		return worldInfo, err
+		return worldInfo, dbError(err, "world %v", worldID)
 	}
 
 	if len(worldLayerInfos) == 0 {
-		return worldInfo, err
+		return worldInfo, NotFound("world %v not found", worldID)
 	}
 
 	worldInfo.ID = worldLayerInfos[0].WorldID
 	worldInfo.Name = worldLayerInfos[0].WorldName
 	worldInfo.MaxZ = worldLayerInfos[0].MaxZ
+	worldInfo.Origin = OvermapCoordinate{
+		OMX: worldLayerInfos[0].OMXMin,
+		OMY: worldLayerInfos[0].OMYMin,
+	}
+	worldInfo.Dimensions = WorldDimensions{
+		OvermapsWide: worldLayerInfos[0].OMXSize,
+		OvermapsHigh: worldLayerInfos[0].OMYSize,
+		TilesWide:    worldLayerInfos[0].OMXSize * overmapSize,
+		TilesHigh:    worldLayerInfos[0].OMYSize * overmapSize,
+		CellWidth:    cellWidth,
+		CellHeight:   cellHeight,
+	}
 
 	for _, wli := range worldLayerInfos {
 		z, ok := worldInfo.Z[wli.Z]
@@ -105,7 +209,7 @@ func (db *DB) GetCellJson(layerID int, x, y float64) ([]byte, error) {
 			(
 				select
 					'FeatureCollection' as type,
-					array_to_json(array_agg(f)) as features
+					coalesce(array_to_json(array_agg(f)), '[]'::json) as features
 				from
 				(
 					select
@@ -113,7 +217,12 @@ func (db *DB) GetCellJson(layerID int, x, y float64) ([]byte, error) {
 						st_asgeojson(the_geom)::json as geometry,
 						json_build_object(
 							'id', id, 
-							'name', name
+							'name', name,
+							'om_x', om_x,
+							'om_y', om_y,
+							'x', x,
+							'y', y,
+							'z', z - 10
 						) as properties
 					from
 						v_cell
@@ -127,7 +236,7 @@ func (db *DB) GetCellJson(layerID int, x, y float64) ([]byte, error) {
 	var json []byte
 	err := db.QueryRow(sql, layerID).Scan(&json)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, "layer %v", layerID)
 	}
 	return json, nil
 }
@@ -136,7 +245,391 @@ func (db *DB) GetTileRoot(layerID int) (string, error) {
 	var tileRoot string
 	err := db.QueryRow("select tile_root from v_tile where layer_id = $1", layerID).Scan(&tileRoot)
 	if err != nil {
-		return "", err
+		return "", dbError(err, "layer %v", layerID)
 	}
 	return tileRoot, nil
 }
+
+func (db *DB) GetCitiesJson(worldID int, q string, bbox *BoundingBox) ([]byte, error) {
+	where := []string{"world_id = $1"}
+	order := "name"
+	args := []interface{}{worldID}
+
+	if q != "" {
+		args = append(args, escapeLike(q), q)
+		p, s := len(args)-1, len(args)
+		where = append(where, fmt.Sprintf("(name ilike $%[1]d || '%%' or similarity(name, $%[2]d) > 0.3)", p, s))
+		order = fmt.Sprintf("name ilike $%[1]d || '%%' desc, similarity(name, $%[2]d) desc, name", p, s)
+	}
+
+	if bbox != nil {
+		args = append(args, bbox.MinX, bbox.MinY, bbox.MaxX, bbox.MaxY)
+		n := len(args)
+		where = append(where, fmt.Sprintf("the_geom && ST_MakeEnvelope($%d, $%d, $%d, $%d)", n-3, n-2, n-1, n))
+	}
+
+	sql := fmt.Sprintf(`
+		select
+			row_to_json(fc) geojson
+		from
+			(
+				select
+					'FeatureCollection' as type,
+					coalesce(array_to_json(array_agg(f.feature order by f.rank)), '[]'::json) as features
+				from
+				(
+					select
+						json_build_object(
+							'type', 'Feature',
+							'geometry', st_asgeojson(the_geom)::json,
+							'properties', json_build_object(
+								'id', city_id,
+								'name', name,
+								'size', size,
+								'om_x', om_x,
+								'om_y', om_y,
+								'x', x,
+								'y', y
+							)
+						) as feature,
+						row_number() over (order by %s) as rank
+					from
+						city
+					where
+						%s
+				) as f
+			) as fc
+		`, order, strings.Join(where, " and "))
+
+	var json []byte
+	err := db.QueryRow(sql, args...).Scan(&json)
+	if err != nil {
+		return nil, dbError(err, "world %v", worldID)
+	}
+	return json, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (db *DB) SearchTerrainJson(layerID int, terrain string, near *Point, limit int) ([]byte, error) {
+	args := []interface{}{layerID, escapeLike(terrain), limit}
+	distance := "null::float"
+	order := "cell_id"
+
+	if near != nil {
+		args = append(args, near.X, near.Y)
+		distance = "ST_Distance(the_geom, ST_MakePoint($4, $5))"
+		order = "the_geom <-> ST_MakePoint($4, $5)"
+	}
+
+	sql := fmt.Sprintf(`
+		select
+			row_to_json(fc) geojson
+		from
+			(
+				select
+					'FeatureCollection' as type,
+					coalesce(array_to_json(array_agg(f.feature order by f.rank)), '[]'::json) as features
+				from
+				(
+					select
+						json_build_object(
+							'type', 'Feature',
+							'geometry', st_asgeojson(the_geom)::json,
+							'properties', json_build_object(
+								'id', id,
+								'name', name,
+								'om_x', om_x,
+								'om_y', om_y,
+								'x', x,
+								'y', y,
+								'distance', %s
+							)
+						) as feature,
+						row_number() over (order by %[2]s) as rank
+					from
+						cell
+					where
+						layer_id = $1
+						and id like $2 || '%%'
+					order by
+						%[2]s
+					limit $3
+				) as f
+			) as fc
+		`, distance, order)
+
+	var json []byte
+	err := db.QueryRow(sql, args...).Scan(&json)
+	if err != nil {
+		return nil, dbError(err, "layer %v", layerID)
+	}
+	return json, nil
+}
+
+func (db *DB) GetCellAtJson(layerID int, c GameCoordinate) ([]byte, error) {
+	var json []byte
+	err := db.QueryRow(`
+		select
+			row_to_json(fc) geojson
+		from
+			(
+				select
+					'FeatureCollection' as type,
+					coalesce(array_to_json(array_agg(f)), '[]'::json) as features
+				from
+				(
+					select
+						'Feature' as type,
+						st_asgeojson(the_geom)::json as geometry,
+						json_build_object(
+							'id', id,
+							'name', name,
+							'om_x', om_x,
+							'om_y', om_y,
+							'x', x,
+							'y', y,
+							'z', z - 10
+						) as properties
+					from
+						v_cell
+					where
+						layer_id = $1
+						and om_x = $2
+						and om_y = $3
+						and x = $4
+						and y = $5
+				) as f
+			) as fc
+		`, layerID, c.OMX, c.OMY, c.X, c.Y).Scan(&json)
+	if err != nil {
+		return nil, dbError(err, "layer %v", layerID)
+	}
+	return json, nil
+}
+
+func (db *DB) GetTileRoots() ([]TileRoot, error) {
+	tileRoots := []TileRoot{}
+	err := db.Select(&tileRoots, `
+		select
+			t.layer_id,
+			l.world_id,
+			t.tile_root
+		from
+			v_tile t
+			inner join layer l
+				on t.layer_id = l.layer_id
+	`)
+	if err != nil {
+		return nil, dbError(err, "tile roots")
+	}
+	return tileRoots, nil
+}
+
+func (db *DB) GetMVT(layerID, z, x, y int) ([]byte, error) {
+	var worldID, maxz, layerZ int
+	err := db.QueryRow(`
+		select
+			w.world_id,
+			w.maxz,
+			l.z
+		from
+			layer l
+			inner join world w
+				on w.world_id = l.world_id
+		where
+			l.layer_id = $1
+	`, layerID).Scan(&worldID, &maxz, &layerZ)
+	if err != nil {
+		return nil, dbError(err, "layer %v", layerID)
+	}
+
+	b := mvtTileBounds(maxz, z, x, y)
+
+	var mvt []byte
+	err = db.QueryRow(`
+		select
+			coalesce((
+				select
+					ST_AsMVT(c, 'cells', 4096, 'geom')
+				from
+				(
+					select
+						ST_AsMVTGeom(ST_Scale(the_geom, 1, -1), ST_MakeEnvelope($2, $8, $4, $9), 4096, 64, true) as geom,
+						id,
+						name,
+						symbol,
+						color_fg,
+						color_bg,
+						om_x,
+						om_y,
+						x,
+						y
+					from
+						cell
+					where
+						layer_id = $1
+						and the_geom && ST_MakeEnvelope($2, $3, $4, $5)
+				) as c
+			), ''::bytea)
+			||
+			coalesce((
+				select
+					ST_AsMVT(c, 'cities', 4096, 'geom')
+				from
+				(
+					select
+						ST_AsMVTGeom(ST_Scale(the_geom, 1, -1), ST_MakeEnvelope($2, $8, $4, $9), 4096, 64, true) as geom,
+						name,
+						size,
+						om_x,
+						om_y,
+						x,
+						y
+					from
+						city
+					where
+						world_id = $6
+						and $7
+						and the_geom && ST_MakeEnvelope($2, $3, $4, $5)
+				) as c
+			), ''::bytea)
+	`, layerID, b.MinX, b.MinY, b.MaxX, b.MaxY, worldID, layerZ == groundLayer, -b.MaxY, -b.MinY).Scan(&mvt)
+	if err != nil {
+		return nil, dbError(err, "layer %v", layerID)
+	}
+	return mvt, nil
+}
+
+// mvtTileBounds returns the pixel space covered by a tile at zoom z of a
+// pyramid whose native zoom is maxz.
+func mvtTileBounds(maxz, z, x, y int) BoundingBox {
+	size := 256 * math.Pow(2, float64(maxz-z))
+	return BoundingBox{
+		MinX: float64(x) * size,
+		MinY: float64(y) * size,
+		MaxX: float64(x+1) * size,
+		MaxY: float64(y+1) * size,
+	}
+}
+
+func (db *DB) Status() (StoreStatus, error) {
+	if err := db.Ping(); err != nil {
+		log.WithField("err", err).Error("database ping failed")
+		return StoreStatus{}, Unavailable("database unreachable")
+	}
+
+	var s StoreStatus
+	err := db.Get(&s, `
+		select
+			version,
+			dirty
+		from
+			schema_migrations
+		limit 1
+	`)
+	if err == sql.ErrNoRows {
+		return s, Unavailable("no migrations applied")
+	} else if err != nil {
+		return s, dbError(err, "migration status")
+	}
+	return s, nil
+}
+
+const annotationColumns = `
+			annotation_id,
+			world_id,
+			om_x,
+			om_y,
+			x,
+			y,
+			z,
+			ST_X(the_geom) as px,
+			ST_Y(the_geom) as py,
+			text,
+			author,
+			api_token_id,
+			created_at
+`
+
+func (db *DB) GetAnnotations(worldID int, z *int) ([]Annotation, error) {
+	annotations := []Annotation{}
+	err := db.Select(&annotations, `
+		select`+annotationColumns+`
+		from
+			annotation
+		where
+			world_id = $1
+			and ($2::int is null or z = $2)
+		order by
+			annotation_id
+	`, worldID, z)
+	if err != nil {
+		return nil, dbError(err, "annotations for world %v", worldID)
+	}
+	return annotations, nil
+}
+
+func (db *DB) GetAnnotation(worldID, annotationID int) (Annotation, error) {
+	var a Annotation
+	err := db.Get(&a, `
+		select`+annotationColumns+`
+		from
+			annotation
+		where
+			world_id = $1
+			and annotation_id = $2
+	`, worldID, annotationID)
+	if err != nil {
+		return a, dbError(err, "annotation %v", annotationID)
+	}
+	return a, nil
+}
+
+func (db *DB) CreateAnnotation(a Annotation) (Annotation, error) {
+	var created Annotation
+	err := db.Get(&created, `
+		insert into annotation (world_id, om_x, om_y, x, y, z, the_geom, text, author, api_token_id)
+		values ($1, $2, $3, $4, $5, $6, ST_MakePoint($7, $8), $9, $10, $11)
+		returning`+annotationColumns,
+		a.WorldID, a.OMX, a.OMY, a.X, a.Y, a.Z, a.PX, a.PY, a.Text, a.Author, a.TokenID)
+	if err != nil {
+		return created, dbError(err, "annotation")
+	}
+	return created, nil
+}
+
+func (db *DB) UpdateAnnotation(a Annotation) (Annotation, error) {
+	var updated Annotation
+	err := db.Get(&updated, `
+		update annotation set
+			om_x = $3,
+			om_y = $4,
+			x = $5,
+			y = $6,
+			z = $7,
+			the_geom = ST_MakePoint($8, $9),
+			text = $10
+		where
+			world_id = $1
+			and annotation_id = $2
+		returning`+annotationColumns,
+		a.WorldID, a.ID, a.OMX, a.OMY, a.X, a.Y, a.Z, a.PX, a.PY, a.Text)
+	if err != nil {
+		return updated, dbError(err, "annotation %v", a.ID)
+	}
+	return updated, nil
+}
+
+func (db *DB) DeleteAnnotation(worldID, annotationID int) error {
+	res, err := db.Exec("delete from annotation where world_id = $1 and annotation_id = $2", worldID, annotationID)
+	if err != nil {
+		return dbError(err, "annotation %v", annotationID)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return NotFound("annotation %v not found", annotationID)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+type NotFoundError struct {
+	Message string
+}
+
+func (e NotFoundError) Error() string {
+	return e.Message
+}
+
+type BadRequestError struct {
+	Message string
+}
+
+func (e BadRequestError) Error() string {
+	return e.Message
+}
+
+type ConflictError struct {
+	Message string
+}
+
+func (e ConflictError) Error() string {
+	return e.Message
+}
+
+type NotImplementedError struct {
+	Message string
+}
+
+func (e NotImplementedError) Error() string {
+	return e.Message
+}
+
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e UnauthorizedError) Error() string {
+	return e.Message
+}
+
+type ForbiddenError struct {
+	Message string
+}
+
+func (e ForbiddenError) Error() string {
+	return e.Message
+}
+
+type MethodNotAllowedError struct {
+	Message string
+}
+
+func (e MethodNotAllowedError) Error() string {
+	return e.Message
+}
+
+type UnavailableError struct {
+	Message string
+}
+
+func (e UnavailableError) Error() string {
+	return e.Message
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return NotFoundError{Message: fmt.Sprintf(format, args...)}
+}
+
+func BadRequest(format string, args ...interface{}) error {
+	return BadRequestError{Message: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return ConflictError{Message: fmt.Sprintf(format, args...)}
+}
+
+func NotImplemented(format string, args ...interface{}) error {
+	return NotImplementedError{Message: fmt.Sprintf(format, args...)}
+}
+
+func Unauthorized(format string, args ...interface{}) error {
+	return UnauthorizedError{Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(format string, args ...interface{}) error {
+	return ForbiddenError{Message: fmt.Sprintf(format, args...)}
+}
+
+func MethodNotAllowed(format string, args ...interface{}) error {
+	return MethodNotAllowedError{Message: fmt.Sprintf(format, args...)}
+}
+
+func Unavailable(format string, args ...interface{}) error {
+	return UnavailableError{Message: fmt.Sprintf(format, args...)}
+}
+
+func statusCode(err error) int {
+	switch err.(type) {
+	case NotFoundError:
+		return http.StatusNotFound
+	case BadRequestError:
+		return http.StatusBadRequest
+	case ConflictError:
+		return http.StatusConflict
+	case NotImplementedError:
+		return http.StatusNotImplemented
+	case UnauthorizedError:
+		return http.StatusUnauthorized
+	case ForbiddenError:
+		return http.StatusForbidden
+	case MethodNotAllowedError:
+		return http.StatusMethodNotAllowed
+	case UnavailableError:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// dbError translates database errors with an obvious HTTP meaning into typed
+// errors, describing the missing or conflicting thing with what.
+func dbError(err error, what string, args ...interface{}) error {
+	if err == sql.ErrNoRows {
+		return NotFound(what+" not found", args...)
+	}
+
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Class() {
+		case "23":
+			return Conflict(what+" conflicts with existing data: %v", append(args, pqErr.Message)...)
+		case "22":
+			return BadRequest(what+" is invalid: %v", append(args, pqErr.Message)...)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ralreegorganon/cddamap/internal/notify"
+	log "github.com/sirupsen/logrus"
+)
+
+// WorldUpdate tells subscribers which layers of a world were imported again.
+type WorldUpdate = notify.WorldUpdate
+
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan WorldUpdate]bool
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[int]map[chan WorldUpdate]bool),
+		done:        make(chan struct{}),
+	}
+}
+
+// Done is closed when the broker shuts down, telling long lived event
+// streams to end so the server can drain.
+func (b *Broker) Done() <-chan struct{} {
+	return b.done
+}
+
+func (b *Broker) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+}
+
+func (b *Broker) Subscribe(worldID int) chan WorldUpdate {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := make(chan WorldUpdate, 8)
+	if _, ok := b.subscribers[worldID]; !ok {
+		b.subscribers[worldID] = make(map[chan WorldUpdate]bool)
+	}
+	b.subscribers[worldID][c] = true
+	return c
+}
+
+func (b *Broker) Unsubscribe(worldID int, c chan WorldUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers[worldID], c)
+	if len(b.subscribers[worldID]) == 0 {
+		delete(b.subscribers, worldID)
+	}
+}
+
+func (b *Broker) Publish(u WorldUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	log.WithField("worldID", u.WorldID).WithField("layerIDs", u.LayerIDs).Info("world updated")
+
+	for c := range b.subscribers[u.WorldID] {
+		select {
+		case c <- u:
+		default:
+			log.WithField("worldID", u.WorldID).Warn("dropping world update for slow subscriber")
+		}
+	}
+}
+
+// ListenForWorldUpdates relays import notifications from Postgres to the
+// broker until the listener is closed.
+func ListenForWorldUpdates(connectionString string, b *Broker) (*pq.Listener, error) {
+	l := pq.NewListener(connectionString, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.WithField("err", err).Error("world update listener error")
+		}
+	})
+
+	if err := l.Listen(notify.WorldUpdatedChannel); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	go func() {
+		for n := range l.Notify {
+			if n == nil {
+				continue
+			}
+			var u WorldUpdate
+			if err := json.Unmarshal([]byte(n.Extra), &u); err != nil {
+				log.WithField("err", err).WithField("payload", n.Extra).Error("bad world update notification")
+				continue
+			}
+			b.Publish(u)
+		}
+	}()
+
+	return l, nil
+}
+
+// WatchTiles polls the top level tile of every layer's pyramid and publishes
+// an update for layers whose tiles were regenerated since the last poll.
+func WatchTiles(db Store, tileRoots []string, interval time.Duration, b *Broker) {
+	seen := make(map[int]time.Time)
+	for {
+		layers, err := db.GetTileRoots()
+		if err != nil {
+			log.WithField("err", err).Error("tile watch error")
+		}
+
+		updates := make(map[int][]int)
+		for _, t := range layers {
+			_, stat := findFile(tileRoots, t.TileRoot, "0", "0", "0.png")
+			if stat == nil {
+				continue
+			}
+
+			last, ok := seen[t.LayerID]
+			seen[t.LayerID] = stat.ModTime()
+			if ok && stat.ModTime().After(last) {
+				updates[t.WorldID] = append(updates[t.WorldID], t.LayerID)
+			}
+		}
+
+		for worldID, layerIDs := range updates {
+			b.Publish(WorldUpdate{
+				WorldID:  worldID,
+				LayerIDs: layerIDs,
+			})
+		}
+
+		time.Sleep(interval)
+	}
+}
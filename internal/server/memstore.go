@@ -0,0 +1,628 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+	"github.com/ralreegorganon/cddamap/internal/pixel"
+)
+
+// MemStore is a Store held entirely in memory, loaded straight from a built
+// world instead of a PostGIS import. Vector tiles are not supported.
+type MemStore struct {
+	mu          sync.RWMutex
+	worlds      map[int]*memWorld
+	layers      map[int]*memLayer
+	nextWorldID int
+	nextLayerID int
+
+	annotations      map[int]*Annotation
+	nextAnnotationID int
+}
+
+type memWorld struct {
+	info   WorldInfo
+	cities []memCity
+}
+
+type memLayer struct {
+	id        int
+	worldID   int
+	z         int
+	layerType string
+	character string
+	tileRoot  string
+	cells     map[[2]int]*memCell
+}
+
+type memCell struct {
+	id         string
+	name       string
+	symbol     string
+	colorFG    string
+	colorBG    string
+	column     int
+	row        int
+	coordinate GameCoordinate
+}
+
+type memCity struct {
+	id         int
+	name       string
+	size       int
+	point      Point
+	coordinate GameCoordinate
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Geometry   geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		worlds:      make(map[int]*memWorld),
+		layers:      make(map[int]*memLayer),
+		nextWorldID: 1,
+		nextLayerID: 1,
+
+		annotations:      make(map[int]*Annotation),
+		nextAnnotationID: 1,
+	}
+}
+
+// Load adds a world the same way render.GIS imports one, replacing any
+// previously loaded world with the same name.
+func (m *MemStore) Load(w world.World, includeLayers []int, terrain, seen, seenSolid, skipEmpty, cities bool) error {
+	if len(includeLayers) == 0 {
+		return fmt.Errorf("no layers to load")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	maxz := pixel.WorldMaxZoom(w.Columns(), w.Rows())
+
+	worldID := m.nextWorldID
+	for id, mw := range m.worlds {
+		if mw.info.Name == w.Name {
+			worldID = id
+			m.removeWorld(id)
+		}
+	}
+	if worldID == m.nextWorldID {
+		m.nextWorldID++
+	}
+
+	mw := &memWorld{
+		info: WorldInfo{
+			ID:   worldID,
+			Name: w.Name,
+			MaxZ: maxz,
+			Z:    make(map[int]*ZLevel),
+			Origin: OvermapCoordinate{
+				OMX: w.Extent.XMin,
+				OMY: w.Extent.YMin,
+			},
+			Dimensions: WorldDimensions{
+				OvermapsWide: w.Extent.XSize,
+				OvermapsHigh: w.Extent.YSize,
+				TilesWide:    w.Extent.XSize * overmapSize,
+				TilesHigh:    w.Extent.YSize * overmapSize,
+				CellWidth:    cellWidth,
+				CellHeight:   cellHeight,
+			},
+		},
+	}
+	m.worlds[worldID] = mw
+
+	for _, i := range includeLayers {
+		if seen || seenSolid {
+			for _, name := range w.Characters() {
+				if w.SeenLayers[name].Empty[i] && skipEmpty {
+					continue
+				}
+				if seen {
+					m.addLayer(mw, i, "seen", name, fmt.Sprintf("%v/%v_visible_%v_tiles", w.Name, name, i))
+				}
+				if seenSolid {
+					m.addLayer(mw, i, "seen_solid", name, fmt.Sprintf("%v/%v_visible_solid_%v_tiles", w.Name, name, i))
+				}
+			}
+		}
+
+		if terrain {
+			if w.TerrainLayers.Empty[i] && skipEmpty {
+				continue
+			}
+
+			ml := m.addLayer(mw, i, "overmap", "", fmt.Sprintf("%v/o_%v_tiles", w.Name, i))
+			for p, chunk := range w.TerrainLayers.Chunks {
+				om := w.Extent.Rect(p)
+				for ci, k := range chunk[i] {
+					if world.IsEmptyTerrain(k) {
+						continue
+					}
+					column, row := om.Min.X+ci%180, om.Min.Y+ci/180
+
+					c := w.Palette.Cells[k]
+					ml.cells[[2]int{column, row}] = &memCell{
+						id:         c.ID,
+						name:       c.Name,
+						symbol:     c.Symbol,
+						colorFG:    pixel.HexColor(c.ColorFG),
+						colorBG:    pixel.HexColor(c.ColorBG),
+						column:     column,
+						row:        row,
+						coordinate: mw.info.PixelToGame(Point{X: (float64(column) + 0.5) * cellWidth, Y: (float64(row) + 0.5) * cellHeight}, i-groundLayer),
+					}
+				}
+			}
+		}
+	}
+
+	if cities {
+		m.addLayer(mw, groundLayer, "city", "", fmt.Sprintf("%v/cities_tiles", w.Name))
+		for i, c := range w.CityLayer.Cities {
+			x, y := pixel.CellCenter(c.X, c.Y)
+			p := Point{X: x, Y: y}
+			mw.cities = append(mw.cities, memCity{
+				id:         i + 1,
+				name:       c.Name,
+				size:       c.Size,
+				point:      p,
+				coordinate: mw.info.PixelToGame(p, 0),
+			})
+		}
+	}
+
+	return nil
+}
+
+func (m *MemStore) addLayer(mw *memWorld, z int, layerType, character, tileRoot string) *memLayer {
+	l := &memLayer{
+		id:        m.nextLayerID,
+		worldID:   mw.info.ID,
+		z:         z,
+		layerType: layerType,
+		character: character,
+		tileRoot:  tileRoot,
+		cells:     make(map[[2]int]*memCell),
+	}
+	m.nextLayerID++
+	m.layers[l.id] = l
+
+	zl, ok := mw.info.Z[z]
+	if !ok {
+		zl = &ZLevel{
+			SeenLayer:      make(map[string]int),
+			SeenSolidLayer: make(map[string]int),
+		}
+		mw.info.Z[z] = zl
+	}
+
+	switch layerType {
+	case "overmap":
+		zl.TerrainLayer.Int64 = int64(l.id)
+		zl.TerrainLayer.Valid = true
+	case "seen":
+		zl.SeenLayer[character] = l.id
+	case "seen_solid":
+		zl.SeenSolidLayer[character] = l.id
+	}
+
+	return l
+}
+
+func (m *MemStore) removeWorld(worldID int) {
+	delete(m.worlds, worldID)
+	for id, a := range m.annotations {
+		if a.WorldID == worldID {
+			delete(m.annotations, id)
+		}
+	}
+	for id, l := range m.layers {
+		if l.worldID == worldID {
+			delete(m.layers, id)
+		}
+	}
+}
+
+func (m *MemStore) layer(layerID int) (*memLayer, error) {
+	l, ok := m.layers[layerID]
+	if !ok {
+		return nil, NotFound("layer %v not found", layerID)
+	}
+	return l, nil
+}
+
+func (m *MemStore) GetWorlds() ([]World, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	worlds := []World{}
+	for _, mw := range m.worlds {
+		worlds = append(worlds, World{ID: mw.info.ID, Name: mw.info.Name, Visibility: VisibilityPublic})
+	}
+	sort.Slice(worlds, func(i, j int) bool { return worlds[i].ID < worlds[j].ID })
+	return worlds, nil
+}
+
+// GetWorld reports every world as public; the in-memory store has no
+// tokens to grant access to anything else.
+func (m *MemStore) GetWorld(worldID int) (World, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mw, ok := m.worlds[worldID]
+	if !ok {
+		return World{}, NotFound("world %v not found", worldID)
+	}
+	return World{ID: mw.info.ID, Name: mw.info.Name, Visibility: VisibilityPublic}, nil
+}
+
+func (m *MemStore) GetLayerWorldID(layerID int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, err := m.layer(layerID)
+	if err != nil {
+		return 0, err
+	}
+	return l.worldID, nil
+}
+
+func (m *MemStore) GetToken(hash []byte) (Token, error) {
+	return Token{}, NotFound("api token not found")
+}
+
+func (m *MemStore) HasWorldAccess(worldID, tokenID int) (bool, error) {
+	return false, nil
+}
+
+func (m *MemStore) GetWorldInfo(worldID int) (WorldInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mw, ok := m.worlds[worldID]
+	if !ok {
+		return WorldInfo{}, NotFound("world %v not found", worldID)
+	}
+	return mw.info, nil
+}
+
+func (m *MemStore) GetCellJson(layerID int, x, y float64) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, err := m.layer(layerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cellJson(l, Point{X: x, Y: y})
+}
+
+func (m *MemStore) GetCellAtJson(layerID int, c GameCoordinate) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, err := m.layer(layerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cellJson(l, m.worlds[l.worldID].info.GameToPixel(c))
+}
+
+func cellJson(l *memLayer, p Point) ([]byte, error) {
+	features := []feature{}
+	if c, ok := l.cells[[2]int{int(math.Floor(p.X / cellWidth)), int(math.Floor(p.Y / cellHeight))}]; ok {
+		features = append(features, c.feature(nil))
+	}
+	return json.Marshal(featureCollection{Type: "FeatureCollection", Features: features})
+}
+
+func (m *MemStore) GetCitiesJson(worldID int, q string, bbox *BoundingBox) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mw, ok := m.worlds[worldID]
+	if !ok {
+		return json.Marshal(featureCollection{Type: "FeatureCollection", Features: []feature{}})
+	}
+
+	type match struct {
+		city       memCity
+		prefix     bool
+		similarity float64
+	}
+
+	matches := make([]match, 0)
+	for _, c := range mw.cities {
+		if bbox != nil && (c.point.X < bbox.MinX || c.point.X > bbox.MaxX || c.point.Y < bbox.MinY || c.point.Y > bbox.MaxY) {
+			continue
+		}
+
+		mt := match{city: c}
+		if q != "" {
+			mt.prefix = strings.HasPrefix(strings.ToLower(c.name), strings.ToLower(q))
+			mt.similarity = trigramSimilarity(c.name, q)
+			if !mt.prefix && mt.similarity <= 0.3 {
+				continue
+			}
+		}
+		matches = append(matches, mt)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.prefix != b.prefix {
+			return a.prefix
+		}
+		if a.similarity != b.similarity {
+			return a.similarity > b.similarity
+		}
+		return a.city.name < b.city.name
+	})
+
+	features := make([]feature, 0, len(matches))
+	for _, mt := range matches {
+		c := mt.city
+		features = append(features, feature{
+			Type: "Feature",
+			Geometry: geometry{
+				Type:        "Point",
+				Coordinates: []float64{c.point.X, c.point.Y},
+			},
+			Properties: map[string]interface{}{
+				"id":   c.id,
+				"name": c.name,
+				"size": c.size,
+				"om_x": c.coordinate.OMX,
+				"om_y": c.coordinate.OMY,
+				"x":    c.coordinate.X,
+				"y":    c.coordinate.Y,
+			},
+		})
+	}
+	return json.Marshal(featureCollection{Type: "FeatureCollection", Features: features})
+}
+
+func (m *MemStore) SearchTerrainJson(layerID int, terrain string, near *Point, limit int) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, err := m.layer(layerID)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		cell     *memCell
+		distance float64
+	}
+
+	matches := make([]match, 0)
+	for _, c := range l.cells {
+		if !strings.HasPrefix(c.id, terrain) {
+			continue
+		}
+		mt := match{cell: c}
+		if near != nil {
+			mt.distance = c.distance(*near)
+		}
+		matches = append(matches, mt)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if near != nil && a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.cell.row != b.cell.row {
+			return a.cell.row < b.cell.row
+		}
+		return a.cell.column < b.cell.column
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	features := make([]feature, 0, len(matches))
+	for _, mt := range matches {
+		var distance interface{}
+		if near != nil {
+			distance = mt.distance
+		}
+		features = append(features, mt.cell.feature(map[string]interface{}{"distance": distance}))
+	}
+	return json.Marshal(featureCollection{Type: "FeatureCollection", Features: features})
+}
+
+func (m *MemStore) GetMVT(layerID, z, x, y int) ([]byte, error) {
+	return nil, NotImplemented("vector tiles require a PostGIS store")
+}
+
+func (m *MemStore) GetTileRoot(layerID int) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, err := m.layer(layerID)
+	if err != nil {
+		return "", err
+	}
+	return l.tileRoot, nil
+}
+
+func (m *MemStore) GetTileRoots() ([]TileRoot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tileRoots := []TileRoot{}
+	for _, l := range m.layers {
+		tileRoots = append(tileRoots, TileRoot{
+			LayerID:  l.id,
+			WorldID:  l.worldID,
+			TileRoot: l.tileRoot,
+		})
+	}
+	return tileRoots, nil
+}
+
+func (c *memCell) feature(extra map[string]interface{}) feature {
+	x := float64(c.column) * cellWidth
+	y := float64(c.row) * cellHeight
+	x2 := x + cellWidth
+	y2 := y + cellHeight
+
+	properties := map[string]interface{}{
+		"id":   c.id,
+		"name": c.name,
+		"om_x": c.coordinate.OMX,
+		"om_y": c.coordinate.OMY,
+		"x":    c.coordinate.X,
+		"y":    c.coordinate.Y,
+		"z":    c.coordinate.Z,
+	}
+	for k, v := range extra {
+		properties[k] = v
+	}
+
+	return feature{
+		Type: "Feature",
+		Geometry: geometry{
+			Type:        "Polygon",
+			Coordinates: [][][]float64{{{x, y}, {x2, y}, {x2, y2}, {x, y2}, {x, y}}},
+		},
+		Properties: properties,
+	}
+}
+
+func (c *memCell) distance(p Point) float64 {
+	minX := float64(c.column) * cellWidth
+	minY := float64(c.row) * cellHeight
+	dx := math.Max(0, math.Max(minX-p.X, p.X-(minX+cellWidth)))
+	dy := math.Max(0, math.Max(minY-p.Y, p.Y-(minY+cellHeight)))
+	return math.Hypot(dx, dy)
+}
+
+// trigramSimilarity approximates pg_trgm's similarity so MemStore city search
+// ranks results the same way the PostGIS store does.
+func trigramSimilarity(a, b string) float64 {
+	ta := trigrams(a)
+	tb := trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+	t := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			t[string(padded[i:i+3])] = true
+		}
+	}
+	return t
+}
+
+func (m *MemStore) Status() (StoreStatus, error) {
+	return StoreStatus{}, nil
+}
+
+func (m *MemStore) GetAnnotations(worldID int, z *int) ([]Annotation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	annotations := []Annotation{}
+	for _, a := range m.annotations {
+		if a.WorldID == worldID && (z == nil || a.Z == *z) {
+			annotations = append(annotations, *a)
+		}
+	}
+	sort.Slice(annotations, func(i, j int) bool { return annotations[i].ID < annotations[j].ID })
+	return annotations, nil
+}
+
+func (m *MemStore) GetAnnotation(worldID, annotationID int) (Annotation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, ok := m.annotations[annotationID]
+	if !ok || a.WorldID != worldID {
+		return Annotation{}, NotFound("annotation %v not found", annotationID)
+	}
+	return *a, nil
+}
+
+func (m *MemStore) CreateAnnotation(a Annotation) (Annotation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.worlds[a.WorldID]; !ok {
+		return a, NotFound("world %v not found", a.WorldID)
+	}
+
+	a.ID = m.nextAnnotationID
+	a.CreatedAt = time.Now()
+	m.nextAnnotationID++
+	m.annotations[a.ID] = &a
+	return a, nil
+}
+
+func (m *MemStore) UpdateAnnotation(a Annotation) (Annotation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.annotations[a.ID]
+	if !ok || existing.WorldID != a.WorldID {
+		return a, NotFound("annotation %v not found", a.ID)
+	}
+
+	existing.setGame(a.Game())
+	existing.PX, existing.PY = a.PX, a.PY
+	existing.Text = a.Text
+	return *existing, nil
+}
+
+func (m *MemStore) DeleteAnnotation(worldID, annotationID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.annotations[annotationID]
+	if !ok || a.WorldID != worldID {
+		return NotFound("annotation %v not found", annotationID)
+	}
+	delete(m.annotations, annotationID)
+	return nil
+}
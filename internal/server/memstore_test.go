@@ -0,0 +1,318 @@
+package server
+
+import (
+	"encoding/json"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ralreegorganon/cddamap/internal/gen/world"
+)
+
+func testWorld() world.World {
+	cell := func(id string) world.TerrainCell {
+		return world.TerrainCell{
+			ID:      id,
+			Name:    id,
+			Symbol:  ".",
+			ColorFG: color.RGBA{0, 110, 0, 255},
+			ColorBG: color.RGBA{0, 0, 0, 255},
+		}
+	}
+
+	palette := world.NewPalette(cell(""), cell("empty_rock"), cell("open_air"))
+	keys := make(map[string]uint16)
+	for _, id := range []string{"field", "hospital_north", "empty_rock"} {
+		i, err := palette.Add(cell(id))
+		if err != nil {
+			panic(err)
+		}
+		keys[id] = i
+	}
+
+	grid := [][]string{
+		{"field", "field", "field", "hospital_north"},
+		{"field", "hospital_north", "field", "field"},
+		{"empty_rock", "field", "field", "field"},
+	}
+
+	chunk := &world.TerrainChunk{}
+	for li := range chunk {
+		for i := range chunk[li] {
+			chunk[li][i] = keys["empty_rock"]
+		}
+	}
+	for ri, r := range grid {
+		for ci, id := range r {
+			chunk[groundLayer][ri*180+ci] = keys[id]
+		}
+	}
+
+	layers := world.TerrainLayers{
+		Chunks: map[world.Point]*world.TerrainChunk{{X: -1, Y: 2}: chunk},
+	}
+	for i := range layers.Empty {
+		layers.Empty[i] = i != groundLayer
+	}
+
+	return world.World{
+		Name:          "Spenard",
+		TerrainLayers: layers,
+		Palette:       palette,
+		CityLayer: world.CityLayer{
+			Cities: []world.City{
+				{Name: "Spenard", X: 1, Y: 1, Size: 8},
+				{Name: "Anchorage", X: 3, Y: 2, Size: 16},
+			},
+		},
+		Extent: world.Extent{XMin: -1, YMin: 2, XSize: 1, YSize: 1},
+	}
+}
+
+func testMemStore(t *testing.T) *MemStore {
+	m := NewMemStore()
+	layers := make([]int, 21)
+	for i := range layers {
+		layers[i] = i
+	}
+	if err := m.Load(testWorld(), layers, true, true, true, true, true); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func getFeatures(t *testing.T, store Store, url string) []feature {
+	w := serve(t, store, "", url)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%v: expected 200, got %v: %s", url, w.Code, w.Body.String())
+	}
+
+	var fc featureCollection
+	if err := json.Unmarshal(w.Body.Bytes(), &fc); err != nil {
+		t.Fatal(err)
+	}
+	return fc.Features
+}
+
+func TestMemStoreWorldInfo(t *testing.T) {
+	w := serve(t, testMemStore(t), "", "/api/worlds/1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v", w.Code)
+	}
+
+	var wi WorldInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &wi); err != nil {
+		t.Fatal(err)
+	}
+
+	if wi.Origin.OMX != -1 || wi.Origin.OMY != 2 {
+		t.Errorf("unexpected origin: %+v", wi.Origin)
+	}
+	if len(wi.Z) != 1 {
+		t.Errorf("expected empty layers to be skipped, got %v z-levels", len(wi.Z))
+	}
+	if !wi.Z[groundLayer].TerrainLayer.Valid {
+		t.Errorf("expected a terrain layer at ground level")
+	}
+}
+
+func TestMemStoreCells(t *testing.T) {
+	store := testMemStore(t)
+
+	features := getFeatures(t, store, "/api/worlds/1/layers/1/cells/30/30")
+	if len(features) != 1 {
+		t.Fatalf("expected one cell, got %v", len(features))
+	}
+	p := features[0].Properties
+	if p["id"] != "hospital_north" || p["om_x"] != -1.0 || p["om_y"] != 2.0 || p["x"] != 1.0 || p["y"] != 1.0 || p["z"] != 0.0 {
+		t.Errorf("unexpected cell: %+v", p)
+	}
+
+	features = getFeatures(t, store, "/api/worlds/1/cells?om_x=-1&om_y=2&x=3&y=0")
+	if len(features) != 1 || features[0].Properties["id"] != "hospital_north" {
+		t.Errorf("unexpected cell at game coordinate: %+v", features)
+	}
+
+	features = getFeatures(t, store, "/api/worlds/1/layers/1/cells/1/50")
+	if len(features) != 0 {
+		t.Errorf("expected empty rock to be skipped, got %+v", features)
+	}
+}
+
+func TestMemStoreSearchTerrain(t *testing.T) {
+	features := getFeatures(t, testMemStore(t), "/api/worlds/1/layers/1/search?terrain=hospital_east&near=-1,2,0,0&limit=5")
+	if len(features) != 2 {
+		t.Fatalf("expected two hospitals, got %v", len(features))
+	}
+
+	nearest := features[0].Properties
+	if nearest["x"] != 1.0 || nearest["y"] != 1.0 {
+		t.Errorf("expected the nearest hospital first, got %+v", nearest)
+	}
+	if features[0].Properties["distance"].(float64) > features[1].Properties["distance"].(float64) {
+		t.Errorf("results are not ordered by distance")
+	}
+}
+
+func TestMemStoreCities(t *testing.T) {
+	store := testMemStore(t)
+
+	features := getFeatures(t, store, "/api/worlds/1/cities?q=anchorag")
+	if len(features) != 1 || features[0].Properties["name"] != "Anchorage" {
+		t.Errorf("unexpected prefix search result: %+v", features)
+	}
+
+	features = getFeatures(t, store, "/api/worlds/1/cities?q=spenrad")
+	if len(features) != 1 || features[0].Properties["name"] != "Spenard" {
+		t.Errorf("unexpected fuzzy search result: %+v", features)
+	}
+
+	features = getFeatures(t, store, "/api/worlds/1/cities?bbox=0,0,48,48")
+	if len(features) != 1 || features[0].Properties["name"] != "Spenard" {
+		t.Errorf("unexpected bbox result: %+v", features)
+	}
+}
+
+func TestMemStoreCityPoints(t *testing.T) {
+	store := testMemStore(t)
+
+	mw := store.worlds[1]
+	if len(mw.cities) != 2 {
+		t.Fatalf("expected 2 cities, got %v", len(mw.cities))
+	}
+	for _, c := range mw.cities {
+		if want := mw.info.GameToPixel(c.coordinate); c.point != want {
+			t.Errorf("%v: expected the center of %+v at %+v, got %+v", c.name, c.coordinate, want, c.point)
+		}
+	}
+}
+
+func TestMemStoreMVTNotImplemented(t *testing.T) {
+	w := serve(t, testMemStore(t), "", "/api/worlds/1/layers/1/mvt/0/0/0.pbf")
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %v", w.Code)
+	}
+	decodeError(t, w)
+}
+
+func TestMemStoreAnnotations(t *testing.T) {
+	store := testMemStore(t)
+
+	w := serveRequest(t, store, "", httptest.NewRequest("POST", "/api/worlds/1/annotations", strings.NewReader(`{"text":"stash","author":"Bruce","om_x":-1,"om_y":2,"x":1,"y":1}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %v: %s", w.Code, w.Body.String())
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/worlds/1/annotations/1" {
+		t.Errorf("unexpected location %q", loc)
+	}
+
+	features := getFeatures(t, store, "/api/worlds/1/annotations?z=0")
+	if len(features) != 1 || features[0].Properties["text"] != "stash" || features[0].Properties["author"] != "Bruce" {
+		t.Fatalf("unexpected annotations: %+v", features)
+	}
+	if c := features[0].Geometry.Coordinates.([]interface{}); math.Abs(c[0].(float64)-1.5*cellWidth) > 1e-9 || c[1] != 1.5*cellHeight {
+		t.Errorf("unexpected point: %v", c)
+	}
+	if features := getFeatures(t, store, "/api/worlds/1/annotations?z=1"); len(features) != 0 {
+		t.Errorf("expected no annotations at z 1, got %+v", features)
+	}
+
+	w = serveRequest(t, store, "", httptest.NewRequest("PUT", "/api/worlds/1/annotations/1", strings.NewReader(`{"text":"danger"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %s", w.Code, w.Body.String())
+	}
+	if features := getFeatures(t, store, "/api/worlds/1/annotations"); features[0].Properties["text"] != "danger" || features[0].Properties["x"] != 1.0 {
+		t.Errorf("unexpected update: %+v", features)
+	}
+
+	bad := []string{
+		`{"om_x":-1,"om_y":2,"x":1,"y":1}`,
+		`{"text":"stash","om_x":5,"om_y":2,"x":1,"y":1}`,
+		`{"text":"stash","om_x":-1,"om_y":2,"x":1}`,
+		`not json`,
+	}
+	for _, body := range bad {
+		w := serveRequest(t, store, "", httptest.NewRequest("POST", "/api/worlds/1/annotations", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %v", body, w.Code)
+		}
+	}
+
+	w = serveRequest(t, store, "", httptest.NewRequest("DELETE", "/api/worlds/1/annotations/1", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %v", w.Code)
+	}
+	if w := serve(t, store, "", "/api/worlds/1/annotations/1"); w.Code != http.StatusNotFound {
+		t.Errorf("expected deleted annotation to be gone, got %v", w.Code)
+	}
+}
+
+func TestReadOnlyRejectsWrites(t *testing.T) {
+	s := NewHTTPServer(testMemStore(t), nil)
+	s.ReadOnly = true
+	router, err := CreateRouter(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/worlds/1/annotations", strings.NewReader(`{"text":"stash","om_x":-1,"om_y":2,"x":1,"y":1}`)))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %v", w.Code)
+	}
+}
+
+func TestExportStatic(t *testing.T) {
+	tileRoot := testTileRoot(t)
+	defer os.RemoveAll(tileRoot)
+
+	site, err := ioutil.TempDir("", "cddamap-site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(site)
+
+	if err := ExportStatic(testMemStore(t), []string{tileRoot}, site); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []string{
+		"api/worlds.json",
+		"api/worlds/1.json",
+		"api/worlds/1/cities.json",
+		"api/worlds/1/layers/1/tiles/0/0/0.png",
+	} {
+		if _, err := os.Stat(filepath.Join(site, f)); err != nil {
+			t.Errorf("expected %v to be exported: %v", f, err)
+		}
+	}
+
+	viewer, err := ioutil.ReadFile(filepath.Join(site, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(viewer), "static: true") {
+		t.Errorf("expected the viewer to be configured for static files")
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(site, "api/worlds/1/layers/1/cells/-1_2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shard cellShard
+	if err := json.Unmarshal(b, &shard); err != nil {
+		t.Fatal(err)
+	}
+	if i := shard.Cells[1*overmapSize+1]; i < 0 || shard.Terrain[i].ID != "hospital_north" {
+		t.Errorf("expected hospital_north at 1,1, got %v", i)
+	}
+	if i := shard.Cells[2*overmapSize+0]; i != -1 {
+		t.Errorf("expected empty rock to be left out, got %v", i)
+	}
+}
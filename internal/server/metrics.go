@@ -0,0 +1,181 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram buckets.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type routeKey struct {
+	method string
+	route  string
+}
+
+type requestKey struct {
+	routeKey
+	code int
+}
+
+type histogram struct {
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range latencyBuckets {
+		if v <= b {
+			h.buckets[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Metrics collects request counters and latencies and renders them in the
+// Prometheus text exposition format.
+type Metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[routeKey]*histogram
+	tiles     map[string]uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[routeKey]*histogram),
+		tiles:     make(map[string]uint64),
+	}
+}
+
+func (m *Metrics) Observe(method, route string, code int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rk := routeKey{method: method, route: route}
+	m.requests[requestKey{routeKey: rk, code: code}]++
+
+	h, ok := m.latencies[rk]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets))}
+		m.latencies[rk] = h
+	}
+	h.observe(d.Seconds())
+
+	if strings.Contains(route, "/tiles/") {
+		switch code {
+		case http.StatusNotModified:
+			m.tiles["hit"]++
+		case http.StatusOK, http.StatusPartialContent:
+			m.tiles["miss"]++
+		}
+	}
+}
+
+// WriteTo writes every metric, plus the connection pool statistics of db
+// when there is one.
+func (m *Metrics) WriteTo(w io.Writer, db *sql.DBStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP cddamap_http_requests_total HTTP requests by route and status code.")
+	fmt.Fprintln(w, "# TYPE cddamap_http_requests_total counter")
+	requests := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		requests = append(requests, k)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].routeKey != requests[j].routeKey {
+			return requests[i].routeKey.less(requests[j].routeKey)
+		}
+		return requests[i].code < requests[j].code
+	})
+	for _, k := range requests {
+		fmt.Fprintf(w, "cddamap_http_requests_total{method=%q,route=%q,code=\"%d\"} %d\n", k.method, k.route, k.code, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP cddamap_http_request_duration_seconds HTTP request latency by route.")
+	fmt.Fprintln(w, "# TYPE cddamap_http_request_duration_seconds histogram")
+	routes := make([]routeKey, 0, len(m.latencies))
+	for k := range m.latencies {
+		routes = append(routes, k)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].less(routes[j]) })
+	for _, k := range routes {
+		h := m.latencies[k]
+		for i, b := range latencyBuckets {
+			fmt.Fprintf(w, "cddamap_http_request_duration_seconds_bucket{method=%q,route=%q,le=\"%g\"} %d\n", k.method, k.route, b, h.buckets[i])
+		}
+		fmt.Fprintf(w, "cddamap_http_request_duration_seconds_bucket{method=%q,route=%q,le=\"+Inf\"} %d\n", k.method, k.route, h.count)
+		fmt.Fprintf(w, "cddamap_http_request_duration_seconds_sum{method=%q,route=%q} %g\n", k.method, k.route, h.sum)
+		fmt.Fprintf(w, "cddamap_http_request_duration_seconds_count{method=%q,route=%q} %d\n", k.method, k.route, h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP cddamap_tile_requests_total Tile requests answered from the client's cache (hit) or by sending the tile (miss).")
+	fmt.Fprintln(w, "# TYPE cddamap_tile_requests_total counter")
+	for _, result := range []string{"hit", "miss"} {
+		fmt.Fprintf(w, "cddamap_tile_requests_total{result=%q} %d\n", result, m.tiles[result])
+	}
+
+	if db == nil {
+		return
+	}
+
+	gauge := func(name, help string, v interface{}) {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v gauge\n%v %v\n", name, help, name, name, v)
+	}
+	counter := func(name, help string, v interface{}) {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v counter\n%v %v\n", name, help, name, name, v)
+	}
+	gauge("cddamap_db_max_open_connections", "Maximum number of open database connections.", db.MaxOpenConnections)
+	gauge("cddamap_db_open_connections", "Open database connections.", db.OpenConnections)
+	gauge("cddamap_db_in_use_connections", "Database connections in use.", db.InUse)
+	gauge("cddamap_db_idle_connections", "Idle database connections.", db.Idle)
+	counter("cddamap_db_wait_total", "Connections waited for.", db.WaitCount)
+	counter("cddamap_db_wait_duration_seconds_total", "Time spent waiting for connections.", db.WaitDuration.Seconds())
+	counter("cddamap_db_max_idle_closed_total", "Connections closed due to the idle limit.", db.MaxIdleClosed)
+	counter("cddamap_db_max_lifetime_closed_total", "Connections closed due to the lifetime limit.", db.MaxLifetimeClosed)
+}
+
+func (k routeKey) less(o routeKey) bool {
+	if k.route != o.route {
+		return k.route < o.route
+	}
+	return k.method < o.method
+}
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1 @@
+drop index city_name_trgm;
\ No newline at end of file
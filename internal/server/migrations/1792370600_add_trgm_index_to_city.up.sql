@@ -0,0 +1,3 @@
+create extension if not exists pg_trgm;
+
+create index city_name_trgm on city using gin (name gin_trgm_ops);
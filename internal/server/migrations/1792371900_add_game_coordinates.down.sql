@@ -0,0 +1,28 @@
+drop view v_cell;
+
+create view v_cell as
+select 
+	w.world_id, l.layer_id, c.cell_id, l.z, c.id, c.name, c.the_geom
+from 
+	cell c 
+	inner join layer l 
+		on c.layer_id = l.layer_id
+	inner join world w
+		on w.world_id = l.world_id;
+
+alter table city drop column y;
+alter table city drop column x;
+alter table city drop column om_y;
+alter table city drop column om_x;
+
+drop index cell_layer_id_game_coordinates;
+
+alter table cell drop column y;
+alter table cell drop column x;
+alter table cell drop column om_y;
+alter table cell drop column om_x;
+
+alter table world drop column om_ysize;
+alter table world drop column om_xsize;
+alter table world drop column om_ymin;
+alter table world drop column om_xmin;
@@ -0,0 +1,26 @@
+alter table world add column om_xmin int not null default 0;
+alter table world add column om_ymin int not null default 0;
+alter table world add column om_xsize int not null default 0;
+alter table world add column om_ysize int not null default 0;
+
+alter table cell add column om_x int null;
+alter table cell add column om_y int null;
+alter table cell add column x int null;
+alter table cell add column y int null;
+
+create index cell_layer_id_game_coordinates on cell (layer_id, om_x, om_y, x, y);
+
+alter table city add column om_x int null;
+alter table city add column om_y int null;
+alter table city add column x int null;
+alter table city add column y int null;
+
+create or replace view v_cell as
+select 
+	w.world_id, l.layer_id, c.cell_id, l.z, c.id, c.name, c.the_geom, c.om_x, c.om_y, c.x, c.y
+from 
+	cell c 
+	inner join layer l 
+		on c.layer_id = l.layer_id
+	inner join world w
+		on w.world_id = l.world_id;
@@ -0,0 +1,3 @@
+alter table cell drop column color_bg;
+alter table cell drop column color_fg;
+alter table cell drop column symbol;
@@ -0,0 +1,3 @@
+alter table cell add column symbol character varying null;
+alter table cell add column color_fg character varying null;
+alter table cell add column color_bg character varying null;
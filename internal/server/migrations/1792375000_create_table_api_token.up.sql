@@ -0,0 +1,9 @@
+create table api_token
+(
+    api_token_id serial not null,
+    name character varying not null,
+    token_hash bytea not null unique,
+    admin boolean not null default false,
+    created_at timestamp with time zone not null default now(),
+    constraint api_token_pkey primary key (api_token_id)
+);
@@ -0,0 +1,2 @@
+alter table world drop constraint world_visibility_check;
+alter table world drop column visibility;
@@ -0,0 +1,2 @@
+alter table world add column visibility character varying not null default 'public';
+alter table world add constraint world_visibility_check check (visibility in ('public', 'unlisted', 'private'));
@@ -0,0 +1 @@
+drop table world_access;
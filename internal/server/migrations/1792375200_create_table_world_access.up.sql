@@ -0,0 +1,10 @@
+create table world_access
+(
+    world_id int not null,
+    api_token_id int not null,
+    created_at timestamp with time zone not null default now(),
+    constraint world_access_pkey primary key (world_id, api_token_id)
+);
+
+alter table world_access add constraint fk_world_access_world foreign key(world_id) references world(world_id) on delete cascade;
+alter table world_access add constraint fk_world_access_api_token foreign key(api_token_id) references api_token(api_token_id) on delete cascade;
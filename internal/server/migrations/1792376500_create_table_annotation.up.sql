@@ -0,0 +1,22 @@
+create table annotation
+(
+    annotation_id serial not null,
+    world_id int not null,
+    z int not null,
+    om_x int not null,
+    om_y int not null,
+    x int not null,
+    y int not null,
+    the_geom geometry(POINT) not null,
+    text character varying not null,
+    author character varying not null,
+    api_token_id int null,
+    created_at timestamp with time zone not null default now(),
+    constraint annotation_pkey primary key (annotation_id)
+);
+
+alter table annotation add constraint fk_annotation_world foreign key(world_id) references world(world_id) on delete cascade;
+alter table annotation add constraint fk_annotation_api_token foreign key(api_token_id) references api_token(api_token_id) on delete set null;
+
+create index annotation_gix on annotation using gist (the_geom);
+create index annotation_world_id_z on annotation (world_id, z);
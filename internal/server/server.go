@@ -1,14 +1,19 @@
 package server
 
 import (
+	"database/sql"
 	"encoding/json"
-	"io"
+	"fmt"
+	"math"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/guregu/null"
+	"github.com/ralreegorganon/cddamap/internal/terrain"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -16,13 +21,33 @@ func CreateRouter(server *HTTPServer) (*mux.Router, error) {
 	r := mux.NewRouter()
 	m := map[string]map[string]HttpApiFunc{
 		"GET": {
-			"/api/worlds":                                                                                     server.GetWorlds,
-			"/api/worlds/{worldID:[0-9]+}":                                                                    server.GetWorldLayerInfo,
+			"/":                                   server.GetViewer,
+			"/healthz":                            server.GetHealth,
+			"/readyz":                             server.GetReady,
+			"/metrics":                            server.GetMetrics,
+			"/api/worlds":                         server.GetWorlds,
+			"/api/worlds/{worldID:[0-9]+}":        server.GetWorldLayerInfo,
+			"/api/worlds/{worldID:[0-9]+}/cities": server.GetCities,
+			"/api/worlds/{worldID:[0-9]+}/cells":  server.GetCellAt,
+			"/api/worlds/{worldID:[0-9]+}/coordinates":                                                        server.GetCoordinates,
+			"/api/worlds/{worldID:[0-9]+}/events":                                                             server.GetEvents,
+			"/api/worlds/{worldID:[0-9]+}/annotations":                                                        server.GetAnnotations,
+			"/api/worlds/{worldID:[0-9]+}/annotations/{annotationID:[0-9]+}":                                  server.GetAnnotation,
+			"/api/worlds/{worldID:[0-9]+}/layers/{layerID:[0-9]+}/search":                                     server.SearchTerrain,
+			"/api/worlds/{worldID:[0-9]+}/layers/{layerID:[0-9]+}/cells":                                      server.GetCells,
 			"/api/worlds/{worldID:[0-9]+}/layers/{layerID:[0-9]+}/cells/{x}/{y}":                              server.GetCells,
 			"/api/worlds/{worldID:[0-9]+}/layers/{layerID:[0-9]+}/tiles/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.png": server.GetTile,
+			"/api/worlds/{worldID:[0-9]+}/layers/{layerID:[0-9]+}/mvt/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.pbf":   server.GetMVT,
+		},
+		"POST": {
+			"/api/worlds/{worldID:[0-9]+}/annotations": server.CreateAnnotation,
+		},
+		"PUT": {
+			"/api/worlds/{worldID:[0-9]+}/annotations/{annotationID:[0-9]+}": server.UpdateAnnotation,
+		},
+		"DELETE": {
+			"/api/worlds/{worldID:[0-9]+}/annotations/{annotationID:[0-9]+}": server.DeleteAnnotation,
 		},
-		"POST": {},
-		"PUT":  {},
 		"OPTIONS": {
 			"": options,
 		},
@@ -33,7 +58,7 @@ func CreateRouter(server *HTTPServer) (*mux.Router, error) {
 			localRoute := route
 			localHandler := handler
 			localMethod := method
-			f := makeHttpHandler(localMethod, localRoute, localHandler)
+			f := makeHttpHandler(server, localMethod, localRoute, localHandler)
 
 			if localRoute == "" {
 				r.Methods(localMethod).HandlerFunc(f)
@@ -46,32 +71,107 @@ func CreateRouter(server *HTTPServer) (*mux.Router, error) {
 	return r, nil
 }
 
-func makeHttpHandler(localMethod string, localRoute string, handlerFunc HttpApiFunc) http.HandlerFunc {
+func makeHttpHandler(server *HTTPServer, localMethod string, localRoute string, handlerFunc HttpApiFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		writeCorsHeaders(w, r)
-		if err := handlerFunc(w, r, mux.Vars(r)); err != nil {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		w = rec
+		defer func() {
+			server.Metrics.Observe(localMethod, localRoute, rec.code, time.Since(start))
+		}()
+
+		server.writeCorsHeaders(w, r)
+		if acceptsGzip(r) {
+			gw := newGzipResponseWriter(w)
+			defer gw.Close()
+			w = gw
+		}
+		if server.ReadOnly && localMethod != "GET" && localMethod != "OPTIONS" {
+			httpError(w, MethodNotAllowed("server is read-only"))
+			return
+		}
+
+		vars := mux.Vars(r)
+		r, err := server.authenticate(w, r)
+		if err == nil {
+			r, err = server.authorize(r, vars)
+		}
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+
+		if err := handlerFunc(w, r, vars); err != nil {
 			httpError(w, err)
 		}
 	}
 }
 
-func writeCorsHeaders(w http.ResponseWriter, r *http.Request) {
-	w.Header().Add("Access-Control-Allow-Origin", "*")
-	w.Header().Add("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept")
-	w.Header().Add("Access-Control-Allow-Methods", "GET, POST, DELETE, PUT, OPTIONS")
+func (s *HTTPServer) writeCorsHeaders(w http.ResponseWriter, r *http.Request) {
+	origin := s.allowedOrigin(r.Header.Get("Origin"))
+	if origin == "" {
+		return
+	}
+	if origin != "*" {
+		w.Header().Add("Vary", "Origin")
+	}
+
+	methods := "GET, POST, DELETE, PUT, OPTIONS"
+	if s.ReadOnly {
+		methods = "GET, OPTIONS"
+	}
+
+	w.Header().Add("Access-Control-Allow-Origin", origin)
+	w.Header().Add("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, Authorization")
+	w.Header().Add("Access-Control-Allow-Methods", methods)
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request
+// from origin, or "" if that origin may not use the API.
+func (s *HTTPServer) allowedOrigin(origin string) string {
+	for _, o := range s.AllowedOrigins {
+		if o == "*" {
+			return "*"
+		}
+		if origin != "" && o == origin {
+			return origin
+		}
+	}
+	return ""
 }
 
 type HttpApiFunc func(w http.ResponseWriter, r *http.Request, vars map[string]string) error
 
 type HTTPServer struct {
-	DB       *DB
-	tileRoot string
+	DB      Store
+	Updates *Broker
+	Metrics *Metrics
+
+	// AllowedOrigins lists the origins allowed to make cross origin requests,
+	// "*" allowing any.
+	AllowedOrigins []string
+
+	// ReadOnly rejects anything but GET and OPTIONS requests.
+	ReadOnly bool
+
+	// LatestMigration is the newest migration the server was deployed with.
+	// The store isn't ready until it has reached it. Zero skips the check,
+	// as for stores without migrations.
+	LatestMigration uint
+
+	// PublicMetrics serves /metrics to anyone rather than only admin tokens.
+	PublicMetrics bool
+
+	tileRoots []string
 }
 
-func NewHTTPServer(db *DB, tileRoot string) *HTTPServer {
+func NewHTTPServer(db Store, tileRoots []string) *HTTPServer {
 	s := &HTTPServer{
-		DB:       db,
-		tileRoot: tileRoot,
+		DB:             db,
+		Updates:        NewBroker(),
+		Metrics:        NewMetrics(),
+		AllowedOrigins: []string{"*"},
+		tileRoots:      tileRoots,
 	}
 
 	return s
@@ -85,19 +185,43 @@ func writeJSON(w http.ResponseWriter, code int, thing interface{}) error {
 	return err
 }
 
-func writeJSONDirect(w http.ResponseWriter, code int, thing []byte) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(thing)
+type errorResponse struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
 }
 
 func httpError(w http.ResponseWriter, err error) {
-	statusCode := http.StatusInternalServerError
+	if err == nil {
+		return
+	}
 
-	if err != nil {
+	code := statusCode(err)
+	message := err.Error()
+	if code == http.StatusInternalServerError {
 		log.WithField("err", err).Error("http error")
-		http.Error(w, err.Error(), statusCode)
+		message = http.StatusText(code)
+	}
+
+	writeJSON(w, code, errorResponse{
+		Status: code,
+		Error:  message,
+	})
+}
+
+func intVar(vars map[string]string, name string) (int, error) {
+	i, err := strconv.Atoi(vars[name])
+	if err != nil {
+		return 0, BadRequest("%v must be an integer: %q", name, vars[name])
 	}
+	return i, nil
+}
+
+func floatVar(vars map[string]string, name string) (float64, error) {
+	f, err := strconv.ParseFloat(vars[name], 64)
+	if err != nil {
+		return 0, BadRequest("%v must be a number: %q", name, vars[name])
+	}
+	return f, nil
 }
 
 func options(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -105,6 +229,67 @@ func options(w http.ResponseWriter, r *http.Request, vars map[string]string) err
 	return nil
 }
 
+func (s *HTTPServer) GetViewer(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	viewer, err := Asset("index.html")
+	if err != nil {
+		return err
+	}
+
+	serveBytes(w, r, "text/html; charset=utf-8", viewer)
+	return nil
+}
+
+func (s *HTTPServer) GetHealth(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	w.Header().Set("Cache-Control", "no-store")
+	return writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *HTTPServer) GetReady(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	status, err := s.DB.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return Unavailable("migration %v is dirty", status.Migration)
+	}
+	if status.Migration < s.LatestMigration {
+		return Unavailable("migration %v is behind the latest, %v", status.Migration, s.LatestMigration)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	return writeJSON(w, http.StatusOK, struct {
+		Status string `json:"status"`
+		StoreStatus
+	}{"ready", status})
+}
+
+// GetMetrics serves Prometheus metrics to admin tokens, or to anyone when
+// they're public.
+func (s *HTTPServer) GetMetrics(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if !s.PublicMetrics {
+		t := requestTokenFrom(r)
+		if t == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cddamap"`)
+			return Unauthorized("metrics need an admin api token")
+		}
+		if !t.Admin {
+			return Forbidden("metrics need an admin api token")
+		}
+	}
+
+	var stats *sql.DBStats
+	if p, ok := s.DB.(interface{ Stats() sql.DBStats }); ok {
+		st := p.Stats()
+		stats = &st
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	s.Metrics.WriteTo(w, stats)
+	return nil
+}
+
 func (s *HTTPServer) GetWorlds(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	worlds, err := s.DB.GetWorlds()
 
@@ -112,13 +297,22 @@ func (s *HTTPServer) GetWorlds(w http.ResponseWriter, r *http.Request, vars map[
 		return err
 	}
 
-	writeJSON(w, http.StatusOK, worlds)
+	visible := []World{}
+	for _, world := range worlds {
+		ok, err := s.canView(world, requestTokenFrom(r), true)
+		if err != nil {
+			return err
+		}
+		if ok {
+			visible = append(visible, world)
+		}
+	}
 
-	return nil
+	return serveJSON(w, r, visible)
 }
 
 func (s *HTTPServer) GetWorldLayerInfo(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	worldID, err := strconv.Atoi(vars["worldID"])
+	worldID, err := intVar(vars, "worldID")
 	if err != nil {
 		return err
 	}
@@ -129,85 +323,638 @@ func (s *HTTPServer) GetWorldLayerInfo(w http.ResponseWriter, r *http.Request, v
 		return err
 	}
 
-	writeJSON(w, http.StatusOK, worldInfo)
+	worldInfo.Revisions, err = s.worldRevisions(worldID)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return serveJSON(w, r, worldInfo)
 }
 
+// GetCells returns the cell of a layer at a pixel space x/y in the path, or
+// at the game om_x, om_y, x and y in the query.
 func (s *HTTPServer) GetCells(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	layerID, err := strconv.Atoi(vars["layerID"])
+	layerID, err := intVar(vars, "layerID")
+	if err != nil {
+		return err
+	}
+
+	var p Point
+	if _, ok := vars["x"]; ok {
+		p.X, err = floatVar(vars, "x")
+		if err != nil {
+			return err
+		}
+
+		p.Y, err = floatVar(vars, "y")
+		if err != nil {
+			return err
+		}
+	} else {
+		c, err := parseGameCoordinate(r.URL.Query())
+		if err != nil {
+			return err
+		}
+
+		worldInfo, err := s.worldInfo(vars["worldID"])
+		if err != nil {
+			return err
+		}
+		p = worldInfo.GameToPixel(c)
+	}
+
+	json, err := s.DB.GetCellJson(layerID, p.X, p.Y)
+	if err != nil {
+		return err
+	}
+	serveBytes(w, r, "application/json", json)
+	return nil
+}
+
+func (s *HTTPServer) GetCities(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
 	if err != nil {
 		return err
 	}
 
-	x, err := strconv.ParseFloat(vars["x"], 64)
+	var bbox *BoundingBox
+	if b := r.URL.Query().Get("bbox"); b != "" {
+		bbox, err = s.parseBoundingBox(vars["worldID"], b)
+		if err != nil {
+			return err
+		}
+	}
+
+	json, err := s.DB.GetCitiesJson(worldID, r.URL.Query().Get("q"), bbox)
 	if err != nil {
 		return err
 	}
+	serveBytes(w, r, "application/json", json)
+	return nil
+}
+
+const defaultSearchLimit = 10
+const maxSearchLimit = 1000
 
-	y, err := strconv.ParseFloat(vars["y"], 64)
+func (s *HTTPServer) SearchTerrain(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	layerID, err := intVar(vars, "layerID")
 	if err != nil {
 		return err
 	}
 
-	json, err := s.DB.GetCellJson(layerID, x, y)
+	query := r.URL.Query()
+
+	id := terrain.BaseID(strings.TrimSpace(query.Get("terrain")))
+	if id == "" {
+		return BadRequest("terrain is required")
+	}
+
+	var near *Point
+	if n := query.Get("near"); n != "" {
+		near, err = s.parseNear(vars["worldID"], n)
+		if err != nil {
+			return err
+		}
+	}
+
+	limit := defaultSearchLimit
+	if l := query.Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return BadRequest("limit must be between 1 and %v", maxSearchLimit)
+		}
+	}
+
+	json, err := s.DB.SearchTerrainJson(layerID, id, near, limit)
 	if err != nil {
 		return err
 	}
-	writeJSONDirect(w, http.StatusOK, json)
+	serveBytes(w, r, "application/json", json)
+	return nil
+}
+
+// parseNear accepts either a pixel space x,y or a game om_x,om_y,x,y.
+func (s *HTTPServer) parseNear(world, near string) (*Point, error) {
+	parts := strings.Split(near, ",")
+	if len(parts) != 4 {
+		return parsePoint(near)
+	}
+
+	values, err := parseInts(parts)
+	if err != nil {
+		return nil, BadRequest("near must be x,y or om_x,om_y,x,y: %v", near)
+	}
+
+	worldInfo, err := s.worldInfo(world)
+	if err != nil {
+		return nil, err
+	}
+
+	p := worldInfo.GameToPixel(GameCoordinate{OMX: values[0], OMY: values[1], X: values[2], Y: values[3]})
+	return &p, nil
+}
+
+// worldInfo looks up the world named by a worldID route variable.
+func (s *HTTPServer) worldInfo(world string) (WorldInfo, error) {
+	worldID, err := strconv.Atoi(world)
+	if err != nil {
+		return WorldInfo{}, BadRequest("worldID must be an integer: %q", world)
+	}
+	return s.DB.GetWorldInfo(worldID)
+}
+
+func parseInts(parts []string) ([]int, error) {
+	values := make([]int, len(parts))
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func parsePoint(s string) (*Point, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return nil, BadRequest("point must be x,y: %v", s)
+	}
+
+	x, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return nil, BadRequest("point must be x,y: %v", s)
+	}
+
+	y, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return nil, BadRequest("point must be x,y: %v", s)
+	}
+
+	return &Point{X: x, Y: y}, nil
+}
+
+// parseBoundingBox accepts either a pixel space minx,miny,maxx,maxy or the
+// game om_x,om_y,x,y of two opposite corners.
+func (s *HTTPServer) parseBoundingBox(world, bbox string) (*BoundingBox, error) {
+	parts := strings.Split(bbox, ",")
+	if len(parts) != 8 {
+		return parseBoundingBox(bbox)
+	}
+
+	v, err := parseInts(parts)
+	if err != nil {
+		return nil, BadRequest("bbox must be minx,miny,maxx,maxy or om_x,om_y,x,y,om_x,om_y,x,y: %v", bbox)
+	}
+
+	worldInfo, err := s.worldInfo(world)
+	if err != nil {
+		return nil, err
+	}
+
+	b := worldInfo.GameBoundingBox(
+		GameCoordinate{OMX: v[0], OMY: v[1], X: v[2], Y: v[3]},
+		GameCoordinate{OMX: v[4], OMY: v[5], X: v[6], Y: v[7]},
+	)
+	return &b, nil
+}
+
+func parseBoundingBox(s string) (*BoundingBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, BadRequest("bbox must be minx,miny,maxx,maxy: %v", s)
+	}
+
+	v := make([]float64, 4)
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, BadRequest("bbox must be minx,miny,maxx,maxy: %v", s)
+		}
+		v[i] = f
+	}
+
+	return &BoundingBox{
+		MinX: math.Min(v[0], v[2]),
+		MinY: math.Min(v[1], v[3]),
+		MaxX: math.Max(v[0], v[2]),
+		MaxY: math.Max(v[1], v[3]),
+	}, nil
+}
+
+func (s *HTTPServer) GetCellAt(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return err
+	}
+
+	c, err := parseGameCoordinate(r.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	worldInfo, err := s.DB.GetWorldInfo(worldID)
+	if err != nil {
+		return err
+	}
+
+	layerID, err := worldInfo.TerrainLayer(c.Z)
+	if err != nil {
+		return err
+	}
+
+	json, err := s.DB.GetCellAtJson(layerID, c)
+	if err != nil {
+		return err
+	}
+	serveBytes(w, r, "application/json", json)
+	return nil
+}
+
+func (s *HTTPServer) GetCoordinates(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return err
+	}
+
+	worldInfo, err := s.DB.GetWorldInfo(worldID)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+
+	var c Coordinates
+	if query.Get("px") != "" || query.Get("py") != "" {
+		p, err := parsePoint(query.Get("px") + "," + query.Get("py"))
+		if err != nil {
+			return err
+		}
+		z := 0
+		if zs := query.Get("z"); zs != "" {
+			z, err = strconv.Atoi(zs)
+			if err != nil {
+				return BadRequest("z must be an integer: %q", zs)
+			}
+		}
+		c.Game = worldInfo.PixelToGame(*p, z)
+	} else {
+		c.Game, err = parseGameCoordinate(query)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !worldInfo.Contains(c.Game) {
+		return BadRequest("coordinate %+v is outside world %v", c.Game, worldID)
+	}
+	c.Pixel = worldInfo.GameToPixel(c.Game)
+
+	return serveJSON(w, r, c)
+}
+
+func (s *HTTPServer) GetEvents(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return err
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming unsupported")
+	}
+
+	updates := s.Updates.Subscribe(worldID)
+	defer s.Updates.Unsubscribe(worldID, updates)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-s.Updates.Done():
+			return nil
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case u := <-updates:
+			b, err := json.Marshal(u)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "event: update\ndata: %s\n\n", b)
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *HTTPServer) GetMVT(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	layerID, err := intVar(vars, "layerID")
+	if err != nil {
+		return err
+	}
+
+	x, err := intVar(vars, "x")
+	if err != nil {
+		return err
+	}
+
+	y, err := intVar(vars, "y")
+	if err != nil {
+		return err
+	}
+
+	z, err := intVar(vars, "z")
+	if err != nil {
+		return err
+	}
+
+	mvt, err := s.DB.GetMVT(layerID, z, x, y)
+	if err != nil {
+		return err
+	}
+
+	serveBytes(w, r, "application/vnd.mapbox-vector-tile", mvt)
 	return nil
 }
 
 func (s *HTTPServer) GetTile(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	layerID, err := strconv.Atoi(vars["layerID"])
+	layerID, err := intVar(vars, "layerID")
 	if err != nil {
 		return err
 	}
 
-	x, err := strconv.Atoi(vars["x"])
+	x, err := intVar(vars, "x")
 	if err != nil {
 		return err
 	}
 
-	y, err := strconv.Atoi(vars["y"])
+	y, err := intVar(vars, "y")
 	if err != nil {
 		return err
 	}
 
-	z, err := strconv.Atoi(vars["z"])
+	z, err := intVar(vars, "z")
 	if err != nil {
 		return err
 	}
 
 	t, err := s.DB.GetTileRoot(layerID)
 	if err != nil {
-		log.WithField("err", err).Error("db error")
-		w.WriteHeader(http.StatusNotFound)
-		return nil
+		return err
 	}
 
-	tile := filepath.Join(s.tileRoot, t, strconv.Itoa(z), strconv.Itoa(x), strconv.Itoa(y)+".png")
+	tile, stat := findFile(s.tileRoots, t, strconv.Itoa(z), strconv.Itoa(x), strconv.Itoa(y)+".png")
+	if tile == "" {
+		return NotFound("tile %v/%v/%v not found for layer %v", z, x, y, layerID)
+	}
 
 	f, err := os.Open(tile)
-	defer f.Close()
 	if err != nil {
-		log.WithField("err", err).Error("file error")
-		w.WriteHeader(http.StatusNotFound)
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("ETag", fileETag(stat))
+	w.Header().Set("Cache-Control", s.tileCacheControl(r, t))
+	http.ServeContent(w, r, tile, stat.ModTime(), f)
+
+	return nil
+}
+
+// maxAnnotationLength bounds annotation text, which is meant for a label
+// and not a diary.
+const maxAnnotationLength = 500
+
+// annotationRequest is the body of annotation create and update requests.
+// Fields left out of an update keep their current value.
+type annotationRequest struct {
+	Text   *string `json:"text"`
+	Author string  `json:"author"`
+	OMX    *int    `json:"om_x"`
+	OMY    *int    `json:"om_y"`
+	X      *int    `json:"x"`
+	Y      *int    `json:"y"`
+	Z      *int    `json:"z"`
+}
+
+func decodeAnnotationRequest(r *http.Request) (annotationRequest, error) {
+	var req annotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, BadRequest("invalid annotation: %v", err)
+	}
+	return req, nil
+}
+
+// apply copies the request onto a, checking the result still lies in the
+// world.
+func (req annotationRequest) apply(a *Annotation, worldInfo WorldInfo) error {
+	if req.Text != nil {
+		a.Text = strings.TrimSpace(*req.Text)
+	}
+	if a.Text == "" {
+		return BadRequest("text is required")
+	}
+	if len(a.Text) > maxAnnotationLength {
+		return BadRequest("text must be at most %v characters", maxAnnotationLength)
+	}
+
+	// New annotations need a position, z defaulting to ground level.
+	c := a.Game()
+	for _, f := range []struct {
+		name string
+		src  *int
+		dst  *int
+	}{
+		{"om_x", req.OMX, &c.OMX},
+		{"om_y", req.OMY, &c.OMY},
+		{"x", req.X, &c.X},
+		{"y", req.Y, &c.Y},
+		{"z", req.Z, &c.Z},
+	} {
+		if f.src != nil {
+			*f.dst = *f.src
+		} else if a.ID == 0 && f.name != "z" {
+			return BadRequest("%v is required", f.name)
+		}
+	}
+
+	c = c.normalize()
+	if !worldInfo.Contains(c) {
+		return BadRequest("%v,%v is outside world %v", c.OMX, c.OMY, worldInfo.ID)
+	}
+	if l := c.Layer(); l < 0 || l > 2*groundLayer {
+		return BadRequest("z must be between %v and %v", -groundLayer, groundLayer)
+	}
+
+	a.setGame(c)
+	p := worldInfo.GameToPixel(c)
+	a.PX, a.PY = p.X, p.Y
+	return nil
+}
+
+// canEdit allows anyone who can see the world to change annotations placed
+// without a token, and otherwise only the token that placed it or an admin.
+func canEdit(a Annotation, t *Token) error {
+	if !a.TokenID.Valid || (t != nil && (t.Admin || int64(t.ID) == a.TokenID.Int64)) {
 		return nil
 	}
+	return Forbidden("annotation %v belongs to %v", a.ID, a.Author)
+}
 
-	h := make([]byte, 512)
-	f.Read(h)
-	ct := http.DetectContentType(h)
+func (s *HTTPServer) GetAnnotations(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return err
+	}
 
-	stat, _ := f.Stat()
-	fs := strconv.FormatInt(stat.Size(), 10)
+	var z *int
+	if v := r.URL.Query().Get("z"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return BadRequest("z must be an integer: %q", v)
+		}
+		z = &i
+	}
 
-	w.Header().Set("Content-Type", ct)
-	w.Header().Set("Content-Length", fs)
+	annotations, err := s.DB.GetAnnotations(worldID, z)
+	if err != nil {
+		return err
+	}
+
+	fc := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+	for _, a := range annotations {
+		fc.Features = append(fc.Features, a.feature())
+	}
+	return serveJSON(w, r, fc)
+}
+
+func (s *HTTPServer) GetAnnotation(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return err
+	}
+
+	annotationID, err := intVar(vars, "annotationID")
+	if err != nil {
+		return err
+	}
+
+	a, err := s.DB.GetAnnotation(worldID, annotationID)
+	if err != nil {
+		return err
+	}
+	return serveJSON(w, r, a.feature())
+}
+
+func (s *HTTPServer) CreateAnnotation(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return err
+	}
+
+	req, err := decodeAnnotationRequest(r)
+	if err != nil {
+		return err
+	}
+
+	worldInfo, err := s.DB.GetWorldInfo(worldID)
+	if err != nil {
+		return err
+	}
+
+	a := Annotation{
+		WorldID: worldID,
+		Author:  strings.TrimSpace(req.Author),
+	}
+	if t := requestTokenFrom(r); t != nil {
+		a.Author = t.Name
+		a.TokenID = null.IntFrom(int64(t.ID))
+	}
+	if a.Author == "" {
+		a.Author = "anonymous"
+	}
+	if err := req.apply(&a, worldInfo); err != nil {
+		return err
+	}
+
+	a, err = s.DB.CreateAnnotation(a)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/worlds/%v/annotations/%v", worldID, a.ID))
+	return writeJSON(w, http.StatusCreated, a.feature())
+}
+
+func (s *HTTPServer) UpdateAnnotation(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return err
+	}
+
+	annotationID, err := intVar(vars, "annotationID")
+	if err != nil {
+		return err
+	}
+
+	req, err := decodeAnnotationRequest(r)
+	if err != nil {
+		return err
+	}
+
+	a, err := s.DB.GetAnnotation(worldID, annotationID)
+	if err != nil {
+		return err
+	}
+	if err := canEdit(a, requestTokenFrom(r)); err != nil {
+		return err
+	}
+
+	worldInfo, err := s.DB.GetWorldInfo(worldID)
+	if err != nil {
+		return err
+	}
+	if err := req.apply(&a, worldInfo); err != nil {
+		return err
+	}
+
+	a, err = s.DB.UpdateAnnotation(a)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, a.feature())
+}
+
+func (s *HTTPServer) DeleteAnnotation(w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	worldID, err := intVar(vars, "worldID")
+	if err != nil {
+		return err
+	}
 
-	f.Seek(0, 0)
-	io.Copy(w, f)
+	annotationID, err := intVar(vars, "annotationID")
+	if err != nil {
+		return err
+	}
 
+	a, err := s.DB.GetAnnotation(worldID, annotationID)
+	if err != nil {
+		return err
+	}
+	if err := canEdit(a, requestTokenFrom(r)); err != nil {
+		return err
+	}
+
+	if err := s.DB.DeleteAnnotation(worldID, annotationID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
@@ -0,0 +1,594 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStore satisfies Store by embedding it, so each test only implements
+// the queries it exercises; anything else panics on the nil interface.
+type fakeStore struct {
+	Store
+	worlds    []World
+	worldInfo map[int]WorldInfo
+	tileRoots map[int]string
+	status    StoreStatus
+	err       error
+
+	// layerWorlds maps layers to worlds, any layer not in it belongs to
+	// world 1.
+	layerWorlds map[int]int
+	tokens      map[string]Token
+	access      map[int][]int
+}
+
+// testWorlds is world 1, which most tests query.
+var testWorlds = []World{{ID: 1, Name: "Spenard", Visibility: VisibilityPublic}}
+
+func (f *fakeStore) GetWorld(worldID int) (World, error) {
+	if f.err != nil {
+		return World{}, f.err
+	}
+	for _, w := range f.worlds {
+		if w.ID == worldID {
+			return w, nil
+		}
+	}
+	return World{}, NotFound("world %v not found", worldID)
+}
+
+func (f *fakeStore) GetLayerWorldID(layerID int) (int, error) {
+	if worldID, ok := f.layerWorlds[layerID]; ok {
+		return worldID, nil
+	}
+	return 1, nil
+}
+
+func (f *fakeStore) GetToken(hash []byte) (Token, error) {
+	for raw, t := range f.tokens {
+		if string(HashToken(raw)) == string(hash) {
+			return t, nil
+		}
+	}
+	return Token{}, NotFound("api token not found")
+}
+
+func (f *fakeStore) HasWorldAccess(worldID, tokenID int) (bool, error) {
+	for _, id := range f.access[worldID] {
+		if id == tokenID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStore) GetWorlds() ([]World, error) {
+	return f.worlds, f.err
+}
+
+func (f *fakeStore) GetWorldInfo(worldID int) (WorldInfo, error) {
+	if f.err != nil {
+		return WorldInfo{}, f.err
+	}
+	wi, ok := f.worldInfo[worldID]
+	if !ok {
+		return wi, NotFound("world %v not found", worldID)
+	}
+	return wi, nil
+}
+
+func (f *fakeStore) GetTileRoot(layerID int) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	t, ok := f.tileRoots[layerID]
+	if !ok {
+		return "", NotFound("layer %v not found", layerID)
+	}
+	return t, nil
+}
+
+func (f *fakeStore) Status() (StoreStatus, error) {
+	return f.status, f.err
+}
+
+func (f *fakeStore) GetTileRoots() ([]TileRoot, error) {
+	tileRoots := []TileRoot{}
+	for layerID, t := range f.tileRoots {
+		worldID, _ := f.GetLayerWorldID(layerID)
+		tileRoots = append(tileRoots, TileRoot{LayerID: layerID, WorldID: worldID, TileRoot: t})
+	}
+	return tileRoots, nil
+}
+
+func serve(t *testing.T, store Store, tileRoot, url string) *httptest.ResponseRecorder {
+	return serveRequest(t, store, tileRoot, httptest.NewRequest("GET", url, nil))
+}
+
+func serveRequest(t *testing.T, store Store, tileRoot string, r *http.Request) *httptest.ResponseRecorder {
+	router, err := CreateRouter(NewHTTPServer(store, []string{tileRoot}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorResponse {
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected a JSON error body, got content type %q", ct)
+	}
+
+	var e errorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != w.Code {
+		t.Errorf("body status %v does not match response status %v", e.Status, w.Code)
+	}
+	return e
+}
+
+func TestGetWorlds(t *testing.T) {
+	store := &fakeStore{worlds: testWorlds}
+
+	w := serve(t, store, "", "/api/worlds")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v", w.Code)
+	}
+
+	var worlds []World
+	if err := json.Unmarshal(w.Body.Bytes(), &worlds); err != nil {
+		t.Fatal(err)
+	}
+	if len(worlds) != 1 || worlds[0].Name != "Spenard" {
+		t.Errorf("unexpected worlds: %+v", worlds)
+	}
+}
+
+func TestGetWorldLayerInfoNotFound(t *testing.T) {
+	w := serve(t, &fakeStore{}, "", "/api/worlds/42")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %v", w.Code)
+	}
+
+	e := decodeError(t, w)
+	if e.Error != "world 42 not found" {
+		t.Errorf("unexpected error message: %q", e.Error)
+	}
+}
+
+func TestGetCellsBadRequest(t *testing.T) {
+	w := serve(t, &fakeStore{worlds: testWorlds}, "", "/api/worlds/1/layers/1/cells/abc/12")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %v", w.Code)
+	}
+	decodeError(t, w)
+}
+
+// pointStore records where cells and cities were asked for.
+type pointStore struct {
+	fakeStore
+	cell Point
+	bbox *BoundingBox
+}
+
+func (p *pointStore) GetCellJson(layerID int, x, y float64) ([]byte, error) {
+	p.cell = Point{X: x, Y: y}
+	return []byte("{}"), nil
+}
+
+func (p *pointStore) GetCitiesJson(worldID int, q string, bbox *BoundingBox) ([]byte, error) {
+	p.bbox = bbox
+	return []byte("{}"), nil
+}
+
+func TestGetCellsGameCoordinates(t *testing.T) {
+	store := &pointStore{fakeStore: fakeStore{
+		worlds:    testWorlds,
+		worldInfo: map[int]WorldInfo{1: {ID: 1, Origin: OvermapCoordinate{OMX: -1, OMY: 2}}},
+	}}
+
+	w := serve(t, store, "", "/api/worlds/1/layers/1/cells?om_x=0&om_y=2&x=3&y=4")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v", w.Code)
+	}
+	want := Point{X: 183.5 * cellWidth, Y: 4.5 * cellHeight}
+	if store.cell != want {
+		t.Errorf("expected cell at %v, got %v", want, store.cell)
+	}
+
+	w = serve(t, store, "", "/api/worlds/1/layers/1/cells?om_x=0&x=3&y=4")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without om_y, got %v", w.Code)
+	}
+}
+
+func TestGetCitiesBoundingBox(t *testing.T) {
+	store := &pointStore{fakeStore: fakeStore{
+		worlds:    testWorlds,
+		worldInfo: map[int]WorldInfo{1: {ID: 1, Origin: OvermapCoordinate{OMX: -1, OMY: 2}}},
+	}}
+
+	tests := []struct {
+		bbox string
+		want BoundingBox
+	}{
+		{"30,40,10,20", BoundingBox{MinX: 10, MinY: 20, MaxX: 30, MaxY: 40}},
+		{"-1,2,0,0,0,2,1,1", BoundingBox{MinX: 0, MinY: 0, MaxX: 182 * cellWidth, MaxY: 2 * cellHeight}},
+	}
+
+	for _, tt := range tests {
+		w := serve(t, store, "", "/api/worlds/1/cities?bbox="+tt.bbox)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%v: expected 200, got %v", tt.bbox, w.Code)
+		}
+		if store.bbox == nil || *store.bbox != tt.want {
+			t.Errorf("%v: expected %+v, got %+v", tt.bbox, tt.want, store.bbox)
+		}
+	}
+}
+
+func TestSearchTerrainRequiresTerrain(t *testing.T) {
+	w := serve(t, &fakeStore{worlds: testWorlds}, "", "/api/worlds/1/layers/1/search?near=1,2")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %v", w.Code)
+	}
+	decodeError(t, w)
+}
+
+func TestInternalErrorsAreNotLeaked(t *testing.T) {
+	store := &fakeStore{err: errors.New("pq: password authentication failed")}
+
+	w := serve(t, store, "", "/api/worlds")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %v", w.Code)
+	}
+
+	e := decodeError(t, w)
+	if e.Error != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("internal error leaked: %q", e.Error)
+	}
+}
+
+func testTileRoot(t *testing.T) string {
+	tileRoot, err := ioutil.TempDir("", "cddamap")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(tileRoot, "Spenard", "o_10_tiles", "0", "0")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	png := []byte("\x89PNG\r\n\x1a\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "0.png"), png, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return tileRoot
+}
+
+func TestGetTile(t *testing.T) {
+	tileRoot := testTileRoot(t)
+	defer os.RemoveAll(tileRoot)
+
+	store := &fakeStore{worlds: testWorlds, tileRoots: map[int]string{1: "Spenard/o_10_tiles"}}
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{"/api/worlds/1/layers/1/tiles/0/0/0.png", http.StatusOK},
+		{"/api/worlds/1/layers/1/tiles/0/0/1.png", http.StatusNotFound},
+		{"/api/worlds/1/layers/2/tiles/0/0/0.png", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := serve(t, store, tileRoot, tt.url)
+		if w.Code != tt.code {
+			t.Errorf("%v: expected %v, got %v", tt.url, tt.code, w.Code)
+			continue
+		}
+		if tt.code == http.StatusOK {
+			if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+				t.Errorf("%v: expected image/png, got %q", tt.url, ct)
+			}
+		} else {
+			decodeError(t, w)
+		}
+	}
+}
+
+func TestGetTileConditional(t *testing.T) {
+	tileRoot := testTileRoot(t)
+	defer os.RemoveAll(tileRoot)
+
+	store := &fakeStore{worlds: testWorlds, tileRoots: map[int]string{1: "Spenard/o_10_tiles"}}
+	url := "/api/worlds/1/layers/1/tiles/0/0/0.png"
+
+	w := serve(t, store, tileRoot, url)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != revalidateCacheControl {
+		t.Errorf("expected tiles without a revision to be revalidated, got %q", cc)
+	}
+
+	r := httptest.NewRequest("GET", url, nil)
+	r.Header.Set("If-None-Match", etag)
+	if w := serveRequest(t, store, tileRoot, r); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: expected 304, got %v", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", url, nil)
+	r.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if w := serveRequest(t, store, tileRoot, r); w.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: expected 304, got %v", w.Code)
+	}
+
+	revision := tileRevision([]string{tileRoot}, "Spenard/o_10_tiles")
+	w = serve(t, store, tileRoot, fmt.Sprintf("%v?v=%v", url, revision))
+	if cc := w.Header().Get("Cache-Control"); cc != immutableCacheControl {
+		t.Errorf("expected the current revision to be immutable, got %q", cc)
+	}
+}
+
+func TestJSONCaching(t *testing.T) {
+	store := &fakeStore{worlds: testWorlds}
+
+	r := httptest.NewRequest("GET", "/api/worlds", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := serveRequest(t, store, "", r)
+	if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("expected gzip, got %q", ce)
+	}
+	if v := w.Header().Get("Vary"); v != "Accept-Encoding" {
+		t.Errorf("expected to vary by encoding, got %q", v)
+	}
+	if etag := w.Header().Get("ETag"); !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("expected a weak ETag shared by each encoding, got %q", etag)
+	}
+
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var worlds []World
+	if err := json.NewDecoder(gz).Decode(&worlds); err != nil {
+		t.Fatal(err)
+	}
+	if len(worlds) != 1 {
+		t.Errorf("unexpected worlds: %+v", worlds)
+	}
+
+	r = httptest.NewRequest("GET", "/api/worlds", nil)
+	r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	if w := serveRequest(t, store, "", r); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304, got %v", w.Code)
+	}
+
+	plain := serve(t, store, "", "/api/worlds")
+	if ce := plain.Header().Get("Content-Encoding"); ce != "" {
+		t.Errorf("expected no encoding, got %q", ce)
+	}
+	if plain.Header().Get("ETag") != w.Header().Get("ETag") {
+		t.Errorf("expected the same weak ETag for each encoding, got %q and %q", plain.Header().Get("ETag"), w.Header().Get("ETag"))
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		store *fakeStore
+		code  int
+	}{
+		{&fakeStore{status: StoreStatus{Migration: 1792373400}}, http.StatusOK},
+		{&fakeStore{status: StoreStatus{Migration: 1792373400, Dirty: true}}, http.StatusServiceUnavailable},
+		{&fakeStore{status: StoreStatus{Migration: 1792370600}}, http.StatusServiceUnavailable},
+		{&fakeStore{err: Unavailable("database unreachable")}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		s := NewHTTPServer(tt.store, nil)
+		s.LatestMigration = 1792373400
+		router, err := CreateRouter(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		if w.Code != tt.code {
+			t.Errorf("%+v: expected %v, got %v", tt.store, tt.code, w.Code)
+		}
+	}
+
+	if w := serve(t, &fakeStore{err: Unavailable("down")}, "", "/healthz"); w.Code != http.StatusOK {
+		t.Errorf("expected liveness to ignore the store, got %v", w.Code)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	store := &fakeStore{
+		worlds: testWorlds,
+		tokens: map[string]Token{
+			"admin-secret": {ID: 1, Name: "admin", Admin: true},
+			"alice-secret": {ID: 2, Name: "alice"},
+		},
+	}
+	s := NewHTTPServer(store, nil)
+	router, err := CreateRouter(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/worlds", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/worlds/7", nil))
+
+	for url, code := range map[string]int{
+		"/metrics":                    http.StatusUnauthorized,
+		"/metrics?token=alice-secret": http.StatusForbidden,
+	} {
+		if w := serveRequest(t, store, "", httptest.NewRequest("GET", url, nil)); w.Code != code {
+			t.Errorf("%v: expected %v, got %v", url, code, w.Code)
+		}
+	}
+
+	s.PublicMetrics = true
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected public metrics, got %v", w.Code)
+	}
+
+	s.PublicMetrics = false
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics?token=admin-secret", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`cddamap_http_requests_total{method="GET",route="/api/worlds",code="200"} 1`,
+		`cddamap_http_requests_total{method="GET",route="/api/worlds/{worldID:[0-9]+}",code="404"} 1`,
+		`cddamap_http_request_duration_seconds_count{method="GET",route="/api/worlds"} 1`,
+		`cddamap_tile_requests_total{result="hit"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestGetTileSearchesTileRoots(t *testing.T) {
+	tileRoot := testTileRoot(t)
+	defer os.RemoveAll(tileRoot)
+
+	store := &fakeStore{worlds: testWorlds, tileRoots: map[int]string{1: "Spenard/o_10_tiles"}}
+	router, err := CreateRouter(NewHTTPServer(store, []string{filepath.Join(tileRoot, "missing"), tileRoot}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/worlds/1/layers/1/tiles/0/0/0.png", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the tile from the second root, got %v", w.Code)
+	}
+}
+
+func TestCorsAllowedOrigins(t *testing.T) {
+	s := NewHTTPServer(&fakeStore{worlds: []World{}}, nil)
+	s.AllowedOrigins = []string{"https://map.example.com"}
+	router, err := CreateRouter(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for origin, want := range map[string]string{
+		"https://map.example.com":  "https://map.example.com",
+		"https://evil.example.com": "",
+	} {
+		r := httptest.NewRequest("GET", "/api/worlds", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("%v: expected %q, got %q", origin, want, got)
+		}
+	}
+}
+
+func TestWorldVisibility(t *testing.T) {
+	store := &fakeStore{
+		worlds: []World{
+			{ID: 1, Name: "Spenard", Visibility: VisibilityPublic},
+			{ID: 2, Name: "Muldoon", Visibility: VisibilityUnlisted},
+			{ID: 3, Name: "Eagle River", Visibility: VisibilityPrivate},
+		},
+		worldInfo:   map[int]WorldInfo{1: {ID: 1}, 2: {ID: 2}, 3: {ID: 3}},
+		layerWorlds: map[int]int{5: 2},
+		tokens: map[string]Token{
+			"player": {ID: 7, Name: "player"},
+			"admin":  {ID: 8, Name: "admin", Admin: true},
+		},
+		access: map[int][]int{3: {7}},
+	}
+
+	listed := func(token string) []int {
+		r := httptest.NewRequest("GET", "/api/worlds", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := serveRequest(t, store, "", r)
+		var worlds []World
+		if err := json.Unmarshal(w.Body.Bytes(), &worlds); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, world := range worlds {
+			ids = append(ids, world.ID)
+		}
+		return ids
+	}
+
+	for token, want := range map[string][]int{"": {1}, "player": {1, 3}, "admin": {1, 2, 3}} {
+		if got := listed(token); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("token %q: expected worlds %v, got %v", token, want, got)
+		}
+	}
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{"/api/worlds/2", http.StatusOK},
+		{"/api/worlds/3", http.StatusNotFound},
+		{"/api/worlds/3?token=player", http.StatusOK},
+		{"/api/worlds/3?token=admin", http.StatusOK},
+		{"/api/worlds/1?token=wrong", http.StatusUnauthorized},
+		{"/api/worlds/1/layers/5/tiles/0/0/0.png", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := serve(t, store, "", tt.url)
+		if w.Code != tt.code {
+			t.Errorf("%v: expected %v, got %v", tt.url, tt.code, w.Code)
+		}
+	}
+
+	if w := serve(t, store, "", "/api/worlds/3?token=player"); w.Header().Get("Cache-Control") != privateRevalidateCacheControl {
+		t.Errorf("expected private worlds to stay out of shared caches, got %q", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{NotFound("world %v not found", 1), http.StatusNotFound},
+		{BadRequest("bad"), http.StatusBadRequest},
+		{Conflict("taken"), http.StatusConflict},
+		{Unavailable("down"), http.StatusServiceUnavailable},
+		{MethodNotAllowed("read-only"), http.StatusMethodNotAllowed},
+		{Unauthorized("who are you"), http.StatusUnauthorized},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if code := statusCode(tt.err); code != tt.code {
+			t.Errorf("%v: expected %v, got %v", tt.err, tt.code, code)
+		}
+	}
+}
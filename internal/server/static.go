@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// staticConfigMarker is replaced in the viewer with the configuration that
+// points it at exported files instead of the API.
+const staticConfigMarker = "<!-- cddamap config -->"
+
+// cellShard holds the terrain of one overmap on one layer. Terrain lists
+// each distinct terrain once and Cells indexes into it for every overmap
+// terrain position, row by row, with -1 where nothing was imported.
+type cellShard struct {
+	OMX     int            `json:"om_x"`
+	OMY     int            `json:"om_y"`
+	Z       int            `json:"z"`
+	Terrain []shardTerrain `json:"terrain"`
+	Cells   []int          `json:"cells"`
+}
+
+type shardTerrain struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Symbol  string `json:"symbol"`
+	ColorFG string `json:"color_fg"`
+	ColorBG string `json:"color_bg"`
+}
+
+// ExportStatic writes every world in the store as a static site under
+// outputRoot: the viewer, JSON files standing in for the API and the tile
+// pyramids found under tileRoots. The result can be served by any static
+// file host.
+func ExportStatic(m *MemStore, tileRoots []string, outputRoot string) error {
+	api := filepath.Join(outputRoot, "api")
+
+	worlds, err := m.GetWorlds()
+	if err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(api, "worlds.json"), worlds); err != nil {
+		return err
+	}
+
+	for _, w := range worlds {
+		if err := m.exportWorld(w.ID, tileRoots, filepath.Join(api, "worlds")); err != nil {
+			return err
+		}
+	}
+
+	viewer, err := Asset("index.html")
+	if err != nil {
+		return err
+	}
+	config := `<script>window.cddamapConfig = { api: 'api', static: true };</script>`
+	viewer = bytes.Replace(viewer, []byte(staticConfigMarker), []byte(config), 1)
+	return ioutil.WriteFile(filepath.Join(outputRoot, "index.html"), viewer, 0644)
+}
+
+func (m *MemStore) exportWorld(worldID int, tileRoots []string, worldsRoot string) error {
+	info, err := m.GetWorldInfo(worldID)
+	if err != nil {
+		return err
+	}
+
+	tileRootsByLayer, err := m.GetTileRoots()
+	if err != nil {
+		return err
+	}
+
+	info.Revisions = make(map[int]int64)
+	for _, t := range tileRootsByLayer {
+		if t.WorldID != worldID {
+			continue
+		}
+		info.Revisions[t.LayerID] = tileRevision(tileRoots, t.TileRoot)
+
+		dst := filepath.Join(worldsRoot, fmt.Sprint(worldID), "layers", fmt.Sprint(t.LayerID), "tiles")
+		src, _ := findFile(tileRoots, t.TileRoot, "0", "0", "0.png")
+		if src == "" {
+			log.WithField("tileRoot", t.TileRoot).Warn("no tiles to export for layer")
+			continue
+		}
+		if err := copyTree(filepath.Dir(filepath.Dir(filepath.Dir(src))), dst); err != nil {
+			return err
+		}
+	}
+
+	root := filepath.Join(worldsRoot, fmt.Sprint(worldID))
+	if err := writeJSONFile(root+".json", info); err != nil {
+		return err
+	}
+
+	cities, err := m.GetCitiesJson(worldID, "", nil)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(root, "cities.json"), cities); err != nil {
+		return err
+	}
+
+	for layerID, shards := range m.cellShards(worldID) {
+		for _, s := range shards {
+			name := fmt.Sprintf("%v_%v.json", s.OMX, s.OMY)
+			if err := writeJSONFile(filepath.Join(root, "layers", fmt.Sprint(layerID), "cells", name), s); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cellShards splits each terrain layer of a world into one shard per
+// overmap, so the viewer only downloads the overmaps it's looking at.
+func (m *MemStore) cellShards(worldID int) map[int][]*cellShard {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	shards := make(map[int][]*cellShard)
+	for _, l := range m.layers {
+		if l.worldID != worldID || l.layerType != "overmap" {
+			continue
+		}
+
+		byOvermap := make(map[OvermapCoordinate]*cellShard)
+		palettes := make(map[OvermapCoordinate]map[string]int)
+		for _, c := range l.cells {
+			om := OvermapCoordinate{OMX: c.coordinate.OMX, OMY: c.coordinate.OMY}
+			s, ok := byOvermap[om]
+			if !ok {
+				s = &cellShard{OMX: om.OMX, OMY: om.OMY, Z: l.z - groundLayer, Terrain: []shardTerrain{}, Cells: make([]int, overmapSize*overmapSize)}
+				for i := range s.Cells {
+					s.Cells[i] = -1
+				}
+				byOvermap[om] = s
+				palettes[om] = make(map[string]int)
+			}
+
+			i, ok := palettes[om][c.id]
+			if !ok {
+				i = len(s.Terrain)
+				palettes[om][c.id] = i
+				s.Terrain = append(s.Terrain, shardTerrain{ID: c.id, Name: c.name, Symbol: c.symbol, ColorFG: c.colorFG, ColorBG: c.colorBG})
+			}
+			s.Cells[c.coordinate.Y*overmapSize+c.coordinate.X] = i
+		}
+
+		for _, s := range byOvermap {
+			shards[l.id] = append(shards[l.id], s)
+		}
+		sort.Slice(shards[l.id], func(i, j int) bool {
+			a, b := shards[l.id][i], shards[l.id][j]
+			return a.OMY < b.OMY || (a.OMY == b.OMY && a.OMX < b.OMX)
+		})
+	}
+	return shards
+}
+
+func writeJSONFile(filename string, thing interface{}) error {
+	b, err := json.Marshal(thing)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, b)
+}
+
+func writeFile(filename string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0644)
+}
+
+// copyTree copies the files under src to dst, hard linking them where the
+// file system allows since tile pyramids get large.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+
+		os.Remove(target)
+		if err := os.Link(path, target); err == nil {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
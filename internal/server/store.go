@@ -0,0 +1,31 @@
+package server
+
+// Store is everything the HTTP handlers need from the map database.
+type Store interface {
+	GetWorlds() ([]World, error)
+	GetWorld(worldID int) (World, error)
+	GetLayerWorldID(layerID int) (int, error)
+	GetToken(hash []byte) (Token, error)
+	HasWorldAccess(worldID, tokenID int) (bool, error)
+	GetWorldInfo(worldID int) (WorldInfo, error)
+	GetCellJson(layerID int, x, y float64) ([]byte, error)
+	GetCellAtJson(layerID int, c GameCoordinate) ([]byte, error)
+	GetCitiesJson(worldID int, q string, bbox *BoundingBox) ([]byte, error)
+	SearchTerrainJson(layerID int, terrain string, near *Point, limit int) ([]byte, error)
+	GetMVT(layerID, z, x, y int) ([]byte, error)
+	GetTileRoot(layerID int) (string, error)
+	GetTileRoots() ([]TileRoot, error)
+	GetAnnotations(worldID int, z *int) ([]Annotation, error)
+	GetAnnotation(worldID, annotationID int) (Annotation, error)
+	CreateAnnotation(a Annotation) (Annotation, error)
+	UpdateAnnotation(a Annotation) (Annotation, error)
+	DeleteAnnotation(worldID, annotationID int) error
+	Status() (StoreStatus, error)
+}
+
+// StoreStatus describes the schema a store is serving from. Stores without
+// migrations report the zero value.
+type StoreStatus struct {
+	Migration uint `json:"migration" db:"version"`
+	Dirty     bool `json:"dirty" db:"dirty"`
+}
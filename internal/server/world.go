@@ -1,18 +1,25 @@
 package server
 
 import (
+	"time"
+
 	"github.com/guregu/null"
 )
 
 type World struct {
-	ID   int    `json:"id" db:"world_id"`
-	Name string `json:"name" db:"name"`
+	ID         int    `json:"id" db:"world_id"`
+	Name       string `json:"name" db:"name"`
+	Visibility string `json:"visibility" db:"visibility"`
 }
 
 type WorldLayerInfo struct {
 	WorldID       int         `json:"worldId" db:"world_id"`
 	LayerID       int         `json:"layerId" db:"layer_id"`
 	MaxZ          int         `json:"maxz" db:"maxz"`
+	OMXMin        int         `json:"omXMin" db:"om_xmin"`
+	OMYMin        int         `json:"omYMin" db:"om_ymin"`
+	OMXSize       int         `json:"omXSize" db:"om_xsize"`
+	OMYSize       int         `json:"omYSize" db:"om_ysize"`
 	Z             int         `json:"z" db:"z"`
 	Type          string      `json:"type" db:"type"`
 	WorldName     string      `json:"worldName" db:"world_name"`
@@ -26,8 +33,103 @@ type ZLevel struct {
 }
 
 type WorldInfo struct {
-	ID   int             `json:"id"`
-	Name string          `json:"name"`
-	MaxZ int             `json:"maxz"`
-	Z    map[int]*ZLevel `json:"z"`
+	ID         int               `json:"id"`
+	Name       string            `json:"name"`
+	MaxZ       int               `json:"maxz"`
+	Z          map[int]*ZLevel   `json:"z"`
+	Origin     OvermapCoordinate `json:"origin"`
+	Dimensions WorldDimensions   `json:"dimensions"`
+	Revisions  map[int]int64     `json:"revisions"`
+}
+
+type OvermapCoordinate struct {
+	OMX int `json:"om_x"`
+	OMY int `json:"om_y"`
+}
+
+type WorldDimensions struct {
+	OvermapsWide int     `json:"overmapsWide"`
+	OvermapsHigh int     `json:"overmapsHigh"`
+	TilesWide    int     `json:"tilesWide"`
+	TilesHigh    int     `json:"tilesHigh"`
+	CellWidth    float64 `json:"cellWidth"`
+	CellHeight   float64 `json:"cellHeight"`
+}
+
+// Annotation is a player placed marker. Its pixel position is fixed when
+// it's placed, its game coordinate is authoritative.
+type Annotation struct {
+	ID        int       `db:"annotation_id"`
+	WorldID   int       `db:"world_id"`
+	OMX       int       `db:"om_x"`
+	OMY       int       `db:"om_y"`
+	X         int       `db:"x"`
+	Y         int       `db:"y"`
+	Z         int       `db:"z"`
+	PX        float64   `db:"px"`
+	PY        float64   `db:"py"`
+	Text      string    `db:"text"`
+	Author    string    `db:"author"`
+	TokenID   null.Int  `db:"api_token_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (a Annotation) Game() GameCoordinate {
+	return GameCoordinate{OMX: a.OMX, OMY: a.OMY, X: a.X, Y: a.Y, Z: a.Z}
+}
+
+func (a *Annotation) setGame(c GameCoordinate) {
+	a.OMX, a.OMY, a.X, a.Y, a.Z = c.OMX, c.OMY, c.X, c.Y, c.Z
+}
+
+func (a Annotation) feature() feature {
+	return feature{
+		Type: "Feature",
+		Geometry: geometry{
+			Type:        "Point",
+			Coordinates: []float64{a.PX, a.PY},
+		},
+		Properties: map[string]interface{}{
+			"id":         a.ID,
+			"text":       a.Text,
+			"author":     a.Author,
+			"created_at": a.CreatedAt,
+			"om_x":       a.OMX,
+			"om_y":       a.OMY,
+			"x":          a.X,
+			"y":          a.Y,
+			"z":          a.Z,
+		},
+	}
+}
+
+type BoundingBox struct {
+	MinX float64
+	MinY float64
+	MaxX float64
+	MaxY float64
+}
+
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type GameCoordinate struct {
+	OMX int `json:"om_x"`
+	OMY int `json:"om_y"`
+	X   int `json:"x"`
+	Y   int `json:"y"`
+	Z   int `json:"z"`
+}
+
+type Coordinates struct {
+	Game  GameCoordinate `json:"game"`
+	Pixel Point          `json:"pixel"`
+}
+
+type TileRoot struct {
+	LayerID  int    `db:"layer_id"`
+	WorldID  int    `db:"world_id"`
+	TileRoot string `db:"tile_root"`
 }
@@ -0,0 +1,47 @@
+// Package terrain holds what the generator and the server both need to know
+// about overmap terrain ids.
+package terrain
+
+import "strings"
+
+// LinearSuffixes are added to the id of linear terrain, such as roads and
+// rivers, for each way it can connect to its neighbours.
+var LinearSuffixes = []string{
+	"_isolated",
+	"_end_south",
+	"_end_west",
+	"_ne",
+	"_end_north",
+	"_ns",
+	"_es",
+	"_nes",
+	"_end_east",
+	"_wn",
+	"_ew",
+	"_new",
+	"_sw",
+	"_nsw",
+	"_esw",
+	"_nesw"}
+
+// RotationSuffixes are added to the id of rotatable terrain for each way it
+// can face.
+var RotationSuffixes = []string{
+	"_north",
+	"_east",
+	"_south",
+	"_west"}
+
+// BaseID strips any rotation or linear suffix from an overmap terrain id, so
+// "road_nesw" becomes "road" and "hospital_north" becomes "hospital".
+func BaseID(id string) string {
+	longest := ""
+	for _, suffixes := range [][]string{RotationSuffixes, LinearSuffixes} {
+		for _, suffix := range suffixes {
+			if len(suffix) > len(longest) && strings.HasSuffix(id, suffix) {
+				longest = suffix
+			}
+		}
+	}
+	return strings.TrimSuffix(id, longest)
+}
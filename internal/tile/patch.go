@@ -0,0 +1,150 @@
+package tile
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/ralreegorganon/cddamap/internal/pixel"
+)
+
+// Pyramid draws the tiles of imgfile's pyramid a piece of the full image at
+// a time, so the full image never has to be held or read whole. Native zoom
+// tiles are drawn over as each piece arrives, and once every piece is in,
+// Close rebuilds the tiles above them from the four beneath.
+type Pyramid struct {
+	layerFolder string
+	bounds      image.Rectangle
+	zCount      int
+	drawn       map[image.Point]bool
+}
+
+// NewPyramid starts drawing into the pyramid of imgfile, a full image of
+// size pixels, which sets how deep the pyramid is. Tiles already cut are
+// drawn over.
+func NewPyramid(imgfile string, size image.Point) *Pyramid {
+	return &Pyramid{
+		layerFolder: strings.TrimSuffix(imgfile, filepath.Ext(imgfile)) + "_tiles",
+		bounds:      image.Rectangle{Max: size},
+		zCount:      pixel.MaxZoom(size.X, size.Y),
+		drawn:       make(map[image.Point]bool),
+	}
+}
+
+// Draw draws the pixels of the full image inside img.Bounds() over the
+// native zoom tiles they cover.
+func (p *Pyramid) Draw(img image.Image) error {
+	r := img.Bounds().Intersect(p.bounds)
+	if r.Empty() {
+		return nil
+	}
+
+	for x := r.Min.X / tileSize; x <= (r.Max.X-1)/tileSize; x++ {
+		for y := r.Min.Y / tileSize; y <= (r.Max.Y-1)/tileSize; y++ {
+			filename := tileFile(p.layerFolder, p.zCount, x, y)
+			tile, err := readTile(filename)
+			if err != nil {
+				return err
+			}
+
+			origin := image.Pt(x*tileSize, y*tileSize)
+			covered := r.Intersect(image.Rectangle{origin, origin.Add(image.Pt(tileSize, tileSize))})
+			draw.Draw(tile, covered.Sub(origin), img, covered.Min, draw.Src)
+
+			if err := writeTile(filename, tile); err != nil {
+				return err
+			}
+			p.drawn[image.Pt(x, y)] = true
+		}
+	}
+	return nil
+}
+
+// Close rebuilds every tile above the native zoom tiles drawn, each once
+// however many of its children were.
+func (p *Pyramid) Close() error {
+	canvas := image.NewRGBA(image.Rect(0, 0, tileSize*2, tileSize*2))
+	children := p.drawn
+	for z := p.zCount - 1; z >= 0; z-- {
+		parents := make(map[image.Point]bool)
+		for c := range children {
+			parents[image.Pt(c.X/2, c.Y/2)] = true
+		}
+
+		for t := range parents {
+			draw.Draw(canvas, canvas.Bounds(), image.Transparent, image.ZP, draw.Src)
+			for dx := 0; dx < 2; dx++ {
+				for dy := 0; dy < 2; dy++ {
+					child, err := readTile(tileFile(p.layerFolder, z+1, t.X*2+dx, t.Y*2+dy))
+					if err != nil {
+						return err
+					}
+					draw.Draw(canvas, image.Rect(dx*tileSize, dy*tileSize, (dx+1)*tileSize, (dy+1)*tileSize), child, image.ZP, draw.Src)
+				}
+			}
+
+			resizedTile := imaging.Resize(canvas, tileSize, tileSize, imaging.Lanczos)
+			if err := writeTile(tileFile(p.layerFolder, z, t.X, t.Y), resizedTile); err != nil {
+				return err
+			}
+		}
+		children = parents
+	}
+
+	p.drawn = make(map[image.Point]bool)
+	return nil
+}
+
+func tileFile(layerFolder string, z, x, y int) string {
+	return filepath.Join(layerFolder, strconv.Itoa(z), strconv.Itoa(x), fmt.Sprintf("%v.png", y))
+}
+
+// readTile returns the tile in filename, or a transparent one if it hasn't
+// been cut yet.
+func readTile(filename string) (*image.RGBA, error) {
+	tile := image.NewRGBA(image.Rect(0, 0, tileSize, tileSize))
+
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return tile, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	draw.Draw(tile, tile.Bounds(), img, img.Bounds().Min, draw.Src)
+	return tile, nil
+}
+
+func writeTile(filename string, tile image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	outFile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	b := bufio.NewWriter(outFile)
+	if err := png.Encode(b, tile); err != nil {
+		outFile.Close()
+		return err
+	}
+	if err := b.Flush(); err != nil {
+		outFile.Close()
+		return err
+	}
+	return outFile.Close()
+}
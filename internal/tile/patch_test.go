@@ -0,0 +1,110 @@
+package tile
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readTiles(t *testing.T, layerFolder string) map[string][]byte {
+	tiles := make(map[string][]byte)
+	err := filepath.Walk(layerFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(layerFolder, path)
+		tiles[filepath.ToSlash(rel)] = b
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tiles
+}
+
+func filled(r image.Rectangle, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(r)
+	draw.Draw(img, r, image.NewUniform(c), image.ZP, draw.Src)
+	return img
+}
+
+func TestPyramid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cddamap-tile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	imgfile := filepath.Join(dir, "o_10.png")
+	layerFolder := filepath.Join(dir, "o_10_tiles")
+	size := image.Pt(1000, 500)
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	// Drawn in two pieces, the whole image makes a pyramid of 4 x 2 native
+	// tiles, 2 x 1 above them and one at the top.
+	p := NewPyramid(imgfile, size)
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 600, 500), image.Rect(600, 0, 1000, 500)} {
+		if err := p.Draw(filled(r, red)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	before := readTiles(t, layerFolder)
+	if len(before) != 8+2+1 {
+		t.Fatalf("expected 11 tiles, got %v", len(before))
+	}
+
+	p = NewPyramid(imgfile, size)
+	if err := p.Draw(filled(image.Rect(300, 10, 400, 100), blue)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	after := readTiles(t, layerFolder)
+	changed := map[string]bool{"2/1/0.png": true, "1/0/0.png": true, "0/0/0.png": true}
+	for name, b := range before {
+		if !bytes.Equal(b, after[name]) != changed[name] {
+			t.Errorf("%v: expected changed to be %v", name, changed[name])
+		}
+	}
+	if len(after) != len(before) {
+		t.Errorf("expected no new tiles, got %v", len(after))
+	}
+
+	tile, err := readTile(tileFile(layerFolder, 2, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := tile.RGBAAt(300-256, 10); c != blue {
+		t.Errorf("expected the patched pixel to be blue, got %v", c)
+	}
+	if c := tile.RGBAAt(400-256, 10); c != red {
+		t.Errorf("expected the pixel beside the patch to stay red, got %v", c)
+	}
+
+	edge, err := readTile(tileFile(layerFolder, 2, 3, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := edge.RGBAAt(1000-768-1, 500-256-1); c != red {
+		t.Errorf("expected the image's last pixel to be red, got %v", c)
+	}
+	if c := edge.RGBAAt(1000-768, 500-256); c.A != 0 {
+		t.Errorf("expected the tile past the image to be transparent, got %v", c)
+	}
+}
@@ -13,9 +13,10 @@ import (
 	"strings"
 
 	"github.com/disintegration/imaging"
+	"github.com/ralreegorganon/cddamap/internal/pixel"
 )
 
-var tileSize = 256
+var tileSize = pixel.TileSize
 
 func nativeZoom(xCount, yCount int) int {
 	return int(math.Max(math.Ceil(math.Log2(float64(xCount))), math.Ceil(math.Log2(float64(yCount)))))